@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Jguer/aur"
+	alpm "github.com/Jguer/go-alpm/v2"
+	gosrc "github.com/Morganamilo/go-srcinfo"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/dep"
+	"github.com/Jguer/yay/v11/pkg/pin"
+	"github.com/Jguer/yay/v11/pkg/settings/exe"
+	"github.com/Jguer/yay/v11/pkg/settings/parser"
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+// aurPinRequest is the historical revision requested with --aur-version or --aur-commit.
+type aurPinRequest struct {
+	target  string
+	version string
+	commit  string
+}
+
+// pinRequestFromArgs extracts the pin request from the command line arguments.
+// The pin options are removed from cmdArgs so they are never passed to pacman.
+func pinRequestFromArgs(cmdArgs *parser.Arguments) (*aurPinRequest, error) {
+	version, _, hasVersion := cmdArgs.GetArg("aur-version")
+	commit, _, hasCommit := cmdArgs.GetArg("aur-commit")
+
+	cmdArgs.DelArg("aur-version", "aur-commit")
+
+	if !hasVersion && !hasCommit {
+		return nil, nil
+	}
+
+	if hasVersion && hasCommit {
+		return nil, errors.New(gotext.Get("only one of --aur-version and --aur-commit can be used"))
+	}
+
+	if len(cmdArgs.Targets) != 1 {
+		return nil, errors.New(gotext.Get("--aur-version and --aur-commit require exactly one target"))
+	}
+
+	_, target := text.SplitDBFromName(cmdArgs.Targets[0])
+
+	return &aurPinRequest{target: target, version: version, commit: commit}, nil
+}
+
+// findPinBase returns the AUR base containing the pin request target.
+func (r *aurPinRequest) findPinBase(bases []dep.Base) (dep.Base, error) {
+	for _, base := range bases {
+		for _, pkg := range base {
+			if pkg.Name == r.target {
+				return base, nil
+			}
+		}
+	}
+
+	return nil, errors.New(gotext.Get("%s is not an AUR package, it can not be pinned", text.Cyan(r.target)))
+}
+
+func gitShowSrcinfo(ctx context.Context, cmdBuilder exe.GitCmdBuilder, dir, commit string) (*gosrc.Srcinfo, error) {
+	stdout, stderr, err := cmdBuilder.Capture(
+		cmdBuilder.BuildGitCmd(ctx, dir, "show", commit+":.SRCINFO"))
+	if err != nil {
+		return nil, fmt.Errorf("%s %s", stderr, err)
+	}
+
+	return gosrc.Parse(stdout)
+}
+
+// findPinCommit locates the commit of the PKGBUILD repository in dir matching
+// the pin request by walking the .SRCINFO history from newest to oldest.
+// It returns the commit and the .SRCINFO it builds.
+func findPinCommit(ctx context.Context, cmdBuilder exe.GitCmdBuilder,
+	dir string, request *aurPinRequest) (string, *gosrc.Srcinfo, error) {
+	if request.commit != "" {
+		stdout, stderr, err := cmdBuilder.Capture(
+			cmdBuilder.BuildGitCmd(ctx, dir, "rev-parse", "--verify", "--quiet", request.commit+"^{commit}"))
+		if err != nil {
+			return "", nil, errors.New(gotext.Get("commit %s not found in %s: %s",
+				request.commit, filepath.Base(dir), stderr))
+		}
+
+		commit := strings.TrimSpace(stdout)
+
+		srcinfo, err := gitShowSrcinfo(ctx, cmdBuilder, dir, commit)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return commit, srcinfo, nil
+	}
+
+	stdout, stderr, err := cmdBuilder.Capture(
+		cmdBuilder.BuildGitCmd(ctx, dir, "log", "--format=%H", "--branches", "--remotes", "--", ".SRCINFO"))
+	if err != nil {
+		return "", nil, fmt.Errorf("%s %s", stderr, err)
+	}
+
+	for _, hash := range strings.Fields(stdout) {
+		srcinfo, errShow := gitShowSrcinfo(ctx, cmdBuilder, dir, hash)
+		if errShow != nil {
+			// very old history may predate a parsable .SRCINFO
+			continue
+		}
+
+		if alpm.VerCmp(srcinfo.Version(), request.version) == 0 {
+			return hash, srcinfo, nil
+		}
+	}
+
+	return "", nil, errors.New(gotext.Get("version %s not found in the history of %s",
+		request.version, filepath.Base(dir)))
+}
+
+// checkoutPin checks out commit as a detached HEAD in dir.
+// The returned function restores the branch, or the detached commit, that was
+// checked out before.
+func checkoutPin(ctx context.Context, cmdBuilder exe.GitCmdBuilder, dir, commit string) (func(), error) {
+	stdout, _, err := cmdBuilder.Capture(
+		cmdBuilder.BuildGitCmd(ctx, dir, "symbolic-ref", "--quiet", "--short", "HEAD"))
+	previous := []string{strings.TrimSpace(stdout)}
+
+	if err != nil {
+		// HEAD is already detached, left over from an interrupted pinned build
+		stdout, stderr, errRev := cmdBuilder.Capture(
+			cmdBuilder.BuildGitCmd(ctx, dir, "rev-parse", "--verify", "HEAD"))
+		if errRev != nil {
+			return nil, fmt.Errorf("%s %s", stderr, errRev)
+		}
+
+		previous = []string{"--detach", strings.TrimSpace(stdout)}
+	}
+
+	_, stderr, err := cmdBuilder.Capture(
+		cmdBuilder.BuildGitCmd(ctx, dir, "checkout", "--quiet", "--force", "--detach", commit))
+	if err != nil {
+		return nil, errors.New(gotext.Get("error checking out %s: %s", commit, stderr))
+	}
+
+	restore := func() {
+		_, stderrRestore, errRestore := cmdBuilder.Capture(cmdBuilder.BuildGitCmd(context.Background(), dir,
+			append([]string{"checkout", "--quiet", "--force"}, previous...)...))
+		if errRestore != nil {
+			text.Errorln(gotext.Get("error restoring %s to %s: %s",
+				filepath.Base(dir), previous[len(previous)-1], stderrRestore))
+		}
+	}
+
+	return restore, nil
+}
+
+// resolvePinRequest downloads the PKGBUILD repository of the pin request target
+// and locates the requested revision. The .SRCINFO of that revision is returned
+// so dependencies are resolved from it rather than from the current AUR data.
+func resolvePinRequest(ctx context.Context, aurClient aur.ClientInterface,
+	request *aurPinRequest) (pin.Pin, *gosrc.Srcinfo, error) {
+	pkgs, err := aurClient.Info(ctx, []string{request.target})
+	if err != nil {
+		return pin.Pin{}, nil, err
+	}
+
+	pkgbase := ""
+
+	for i := range pkgs {
+		if pkgs[i].Name == request.target {
+			pkgbase = pkgs[i].PackageBase
+		}
+	}
+
+	if pkgbase == "" {
+		return pin.Pin{}, nil, errors.New(gotext.Get("%s is not an AUR package, it can not be pinned", text.Cyan(request.target)))
+	}
+
+	if _, err = downloadPKGBUILDRepos(ctx, []string{pkgbase}, config.BuildDir, false); err != nil {
+		return pin.Pin{}, nil, err
+	}
+
+	commit, srcinfo, err := findPinCommit(ctx, config.Runtime.CmdBuilder, filepath.Join(config.BuildDir, pkgbase), request)
+	if err != nil {
+		return pin.Pin{}, nil, err
+	}
+
+	return pin.Pin{Pkgbase: pkgbase, Version: srcinfo.Version(), Commit: commit}, srcinfo, nil
+}
+
+// applyPinRequest checks out the revision a pin request resolved to in the
+// base of bases containing its target. It returns the names of the packages to
+// pin once installed and the function restoring the repository.
+func applyPinRequest(ctx context.Context, cmdBuilder exe.GitCmdBuilder, buildDir string,
+	bases []dep.Base, request *aurPinRequest, pinned pin.Pin) ([]string, func(), error) {
+	base, err := request.findPinBase(bases)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	restore, err := checkoutPin(ctx, cmdBuilder, filepath.Join(buildDir, base.Pkgbase()), pinned.Commit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	text.OperationInfoln(gotext.Get("Building %s at %s (%s)",
+		text.Cyan(base.Pkgbase()), text.Bold(pinned.Version), pinned.Commit))
+
+	names := make([]string, 0, len(base))
+	for _, pkg := range base {
+		names = append(names, pkg.Name)
+	}
+
+	return names, restore, nil
+}
+
+// updatePins records the pin of a historical build, or releases the pins of
+// packages explicitly installed without one.
+func updatePins(request *aurPinRequest, pinned pin.Pin, pinnedNames, explicitTargets []string) {
+	if request != nil {
+		if err := config.Runtime.PinStore.Set(pinnedNames, pinned); err != nil {
+			text.Errorln(err)
+
+			return
+		}
+
+		text.OperationInfoln(gotext.Get("%s pinned to %s, reinstall it without --aur-version to release the pin",
+			text.Cyan(pinned.Pkgbase), text.Bold(pinned.Version)))
+
+		return
+	}
+
+	names := make([]string, 0, len(explicitTargets))
+	for _, target := range explicitTargets {
+		_, name := text.SplitDBFromName(target)
+		names = append(names, name)
+	}
+
+	released, err := config.Runtime.PinStore.Release(names)
+	if err != nil {
+		text.Errorln(err)
+	}
+
+	if len(released) > 0 {
+		text.OperationInfoln(gotext.Get("Released pin: %s", text.Cyan(strings.Join(released, ", "))))
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v11/pkg/settings/exe"
+	"github.com/Jguer/yay/v11/pkg/settings/parser"
+)
+
+const pinSrcinfo = `pkgbase = foo
+	pkgver = %s
+	pkgrel = 1
+	arch = any
+
+pkgname = foo
+`
+
+// localGitBuilder runs git as the current user, without de-elevation.
+type localGitBuilder struct {
+	exe.OSRunner
+}
+
+func (g *localGitBuilder) BuildGitCmd(ctx context.Context, dir string, extraArgs ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, "git", append([]string{"-C", dir}, extraArgs...)...)
+}
+
+func gitTestRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	args = append([]string{"-c", "user.name=yay", "-c", "user.email=yay@localhost"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s %s", args, err, out)
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// newPinTestRepo creates a PKGBUILD repository with one commit per version.
+func newPinTestRepo(t *testing.T, versions ...string) (dir string, commits []string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir = t.TempDir()
+	gitTestRun(t, dir, "init", "--quiet", "--initial-branch=master")
+
+	for _, version := range versions {
+		srcinfo := strings.Replace(pinSrcinfo, "%s", version, 1)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, ".SRCINFO"), []byte(srcinfo), 0o644))
+		gitTestRun(t, dir, "add", ".SRCINFO")
+		gitTestRun(t, dir, "commit", "--quiet", "-m", version)
+		commits = append(commits, gitTestRun(t, dir, "rev-parse", "HEAD"))
+	}
+
+	return dir, commits
+}
+
+func TestFindPinCommit(t *testing.T) {
+	t.Parallel()
+
+	dir, commits := newPinTestRepo(t, "1.0.0", "1.1.0", "1.2.0")
+	cmdBuilder := &localGitBuilder{}
+
+	type testCase struct {
+		desc        string
+		request     *aurPinRequest
+		wantCommit  string
+		wantVersion string
+		wantErr     bool
+	}
+
+	testCases := []testCase{
+		{
+			desc:        "full version",
+			request:     &aurPinRequest{target: "foo", version: "1.1.0-1"},
+			wantCommit:  commits[1],
+			wantVersion: "1.1.0-1",
+		},
+		{
+			desc:        "version without pkgrel",
+			request:     &aurPinRequest{target: "foo", version: "1.0.0"},
+			wantCommit:  commits[0],
+			wantVersion: "1.0.0-1",
+		},
+		{
+			desc:    "missing version",
+			request: &aurPinRequest{target: "foo", version: "0.9.0-1"},
+			wantErr: true,
+		},
+		{
+			desc:        "abbreviated commit",
+			request:     &aurPinRequest{target: "foo", commit: commits[1][:8]},
+			wantCommit:  commits[1],
+			wantVersion: "1.1.0-1",
+		},
+		{
+			desc:    "missing commit",
+			request: &aurPinRequest{target: "foo", commit: "deadbeef"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			commit, srcinfo, err := findPinCommit(context.Background(), cmdBuilder, dir, tc.request)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantCommit, commit)
+			assert.Equal(t, tc.wantVersion, srcinfo.Version())
+		})
+	}
+}
+
+func TestCheckoutPin(t *testing.T) {
+	t.Parallel()
+
+	dir, commits := newPinTestRepo(t, "1.0.0", "1.1.0")
+	cmdBuilder := &localGitBuilder{}
+
+	restore, err := checkoutPin(context.Background(), cmdBuilder, dir, commits[0])
+	assert.NoError(t, err)
+	assert.Equal(t, commits[0], gitTestRun(t, dir, "rev-parse", "HEAD"))
+
+	restore()
+	assert.Equal(t, "master", gitTestRun(t, dir, "symbolic-ref", "--short", "HEAD"))
+	assert.Equal(t, commits[1], gitTestRun(t, dir, "rev-parse", "HEAD"))
+}
+
+func TestCheckoutPinDetached(t *testing.T) {
+	t.Parallel()
+
+	dir, commits := newPinTestRepo(t, "1.0.0", "1.1.0", "1.2.0")
+	cmdBuilder := &localGitBuilder{}
+
+	gitTestRun(t, dir, "checkout", "--quiet", "--detach", commits[1])
+
+	restore, err := checkoutPin(context.Background(), cmdBuilder, dir, commits[0])
+	assert.NoError(t, err)
+	assert.Equal(t, commits[0], gitTestRun(t, dir, "rev-parse", "HEAD"))
+
+	restore()
+	assert.Equal(t, commits[1], gitTestRun(t, dir, "rev-parse", "HEAD"))
+}
+
+func TestPinRequestFromArgs(t *testing.T) {
+	t.Parallel()
+
+	cmdArgs := parser.MakeArguments()
+	cmdArgs.Op = "S"
+	cmdArgs.AddTarget("aur/foo")
+	cmdArgs.CreateOrAppendOption("aur-version", "1.0.0-1")
+
+	request, err := pinRequestFromArgs(cmdArgs)
+	assert.NoError(t, err)
+	assert.Equal(t, &aurPinRequest{target: "foo", version: "1.0.0-1"}, request)
+	assert.False(t, cmdArgs.ExistsArg("aur-version"))
+
+	cmdArgs.AddTarget("bar")
+	cmdArgs.CreateOrAppendOption("aur-commit", "deadbeef")
+
+	_, err = pinRequestFromArgs(cmdArgs)
+	assert.Error(t, err)
+
+	request, err = pinRequestFromArgs(cmdArgs)
+	assert.NoError(t, err)
+	assert.Nil(t, request)
+}
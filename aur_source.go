@@ -3,15 +3,17 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
 	"sync"
 
+	gosrc "github.com/Morganamilo/go-srcinfo"
 	"github.com/leonelquinteros/gotext"
 
 	"github.com/Jguer/yay/v11/pkg/dep"
-	"github.com/Jguer/yay/v11/pkg/multierror"
 	"github.com/Jguer/yay/v11/pkg/settings/exe"
+	"github.com/Jguer/yay/v11/pkg/srccache"
 	"github.com/Jguer/yay/v11/pkg/stringset"
 	"github.com/Jguer/yay/v11/pkg/text"
 )
@@ -31,56 +33,155 @@ func (e *ErrDownloadSource) Unwrap() error {
 	return e.inner
 }
 
-func downloadPKGBUILDSource(ctx context.Context, cmdBuilder exe.ICmdBuilder, dest,
-	base string, incompatible stringset.StringSet) (err error) {
+// cachedSources returns the sources of base that can be shared through the
+// source cache, along with the directory makepkg downloads them to.
+func cachedSources(base, dir string) (entries []srccache.Entry, srcdest string, err error) {
+	srcinfo, err := gosrc.ParseFile(filepath.Join(dir, ".SRCINFO"))
+	if err != nil {
+		return nil, "", err
+	}
+
+	conf := config.MakepkgConfFor(base)
+	if conf == nil {
+		return srccache.Entries(srcinfo, ""), dir, nil
+	}
+
+	return srccache.Entries(srcinfo, conf.CArch), conf.SourceDir(dir), nil
+}
+
+// downloadPKGBUILDSource downloads and verifies the sources of base. While
+// progress is live, the output of makepkg is only shown on failure.
+func downloadPKGBUILDSource(ctx context.Context, cmdBuilder exe.ICmdBuilder, opts sourceDownload,
+	progress *text.Progress, dest, base string, incompatible stringset.StringSet) (err error) {
 	dir := filepath.Join(dest, base)
+	if local, ok := opts.localDirs[base]; ok {
+		dir = local
+	}
+
+	cache := opts.cache
+
 	args := []string{"--verifysource", "-Ccf"}
 
 	if incompatible.Get(base) {
 		args = append(args, "--ignorearch")
 	}
 
-	err = cmdBuilder.Show(
-		cmdBuilder.BuildMakepkgCmd(ctx, dir, args...))
+	var (
+		entries  []srccache.Entry
+		srcdest  string
+		restored []string
+	)
+
+	if cache != nil {
+		var errCache error
+
+		// without a readable .SRCINFO, let makepkg handle the sources alone
+		entries, srcdest, errCache = cachedSources(base, dir)
+		if errCache != nil {
+			cache = nil
+		} else {
+			// sources shared with a base downloading at the same time are
+			// restored once it stored them
+			defer cache.Lock(entries)()
+
+			if restored, errCache = cache.Restore(entries, srcdest); errCache != nil {
+				progress.Warnln(gotext.Get("unable to restore cached sources of %s: %s", text.Cyan(base), errCache))
+			} else if len(restored) > 0 {
+				progress.OperationInfoln(gotext.Get("Reusing %d cached sources: %s", len(restored), text.Cyan(base)))
+			}
+		}
+	}
+
+	run := func() (string, error) {
+		cmd := cmdBuilder.BuildMakepkgBaseCmd(ctx, base, dir, args...)
+		if !progress.Live() {
+			return "", cmdBuilder.Show(cmd)
+		}
+
+		_, stderr, errCapture := cmdBuilder.Capture(cmd)
+
+		return stderr, errCapture
+	}
+
+	errOut, err := run()
+	if err != nil && len(restored) > 0 {
+		// the cache follows .SRCINFO, which may be older than the PKGBUILD
+		for _, path := range restored {
+			_ = os.Remove(path)
+		}
+
+		errOut, err = run()
+	}
+
 	if err != nil {
-		return ErrDownloadSource{inner: err, pkgName: base, errOut: ""}
+		return ErrDownloadSource{inner: err, pkgName: base, errOut: errOut}
+	}
+
+	if cache != nil {
+		if _, errStore := cache.Store(entries, srcdest); errStore != nil {
+			progress.Warnln(gotext.Get("unable to cache sources of %s: %s", text.Cyan(base), errStore))
+		}
 	}
 
 	return nil
 }
 
+// sourceDownload holds how the sources of PKGBUILDs are downloaded.
+type sourceDownload struct {
+	cache *srccache.Cache
+	// jobs is the number of PKGBUILDs downloaded at once, one per CPU when 0.
+	jobs int
+	live bool
+	// localDirs maps the bases built with yay -B to their directory.
+	localDirs map[string]string
+}
+
 func downloadPKGBUILDSourceWorker(ctx context.Context, wg *sync.WaitGroup, dest string,
-	cBase <-chan string, valOut chan<- string, errOut chan<- error,
-	cmdBuilder exe.ICmdBuilder, incompatible stringset.StringSet) {
+	cBase <-chan string, progress *text.Progress,
+	cmdBuilder exe.ICmdBuilder, opts sourceDownload, incompatible stringset.StringSet) {
 	for base := range cBase {
-		err := downloadPKGBUILDSource(ctx, cmdBuilder, dest, base, incompatible)
+		progress.Set(base, text.FetchingSources)
+
+		err := downloadPKGBUILDSource(ctx, cmdBuilder, opts, progress, dest, base, incompatible)
 		if err != nil {
-			errOut <- ErrDownloadSource{inner: err, pkgName: base, errOut: ""}
+			progress.Fail(base, err)
 		} else {
-			valOut <- base
+			progress.Done(base, gotext.Get("Downloaded sources"))
 		}
 	}
 
 	wg.Done()
 }
 
-func downloadPKGBUILDSourceFanout(ctx context.Context, cmdBuilder exe.ICmdBuilder, dest string,
+func downloadPKGBUILDSourceFanout(ctx context.Context, cmdBuilder exe.ICmdBuilder, opts sourceDownload, dest string,
 	bases []dep.Base, incompatible stringset.StringSet) error {
+	if opts.cache != nil {
+		defer evictSources(opts.cache)
+	}
+
 	if len(bases) == 1 {
-		return downloadPKGBUILDSource(ctx, cmdBuilder, dest, bases[0].Pkgbase(), incompatible)
+		return downloadPKGBUILDSource(ctx, cmdBuilder, opts, nil, dest, bases[0].Pkgbase(), incompatible)
+	}
+
+	numOfWorkers := opts.jobs
+	if numOfWorkers <= 0 {
+		numOfWorkers = runtime.NumCPU()
+	}
+
+	names := make([]string, 0, len(bases))
+	for _, base := range bases {
+		names = append(names, base.Pkgbase())
 	}
 
 	var (
-		numOfWorkers    = runtime.NumCPU()
-		wg              = &sync.WaitGroup{}
-		c               = make(chan string)
-		fanInChanValues = make(chan string)
-		fanInChanErrors = make(chan error)
+		wg       = &sync.WaitGroup{}
+		c        = make(chan string)
+		progress = text.NewProgress(os.Stdout, gotext.Get("Downloading sources"), opts.live, names)
 	)
 
 	go func() {
-		for _, base := range bases {
-			c <- base.Pkgbase()
+		for _, name := range names {
+			c <- name
 		}
 
 		close(c)
@@ -90,32 +191,17 @@ func downloadPKGBUILDSourceFanout(ctx context.Context, cmdBuilder exe.ICmdBuilde
 	wg.Add(numOfWorkers)
 
 	for s := 0; s < numOfWorkers; s++ {
-		go downloadPKGBUILDSourceWorker(ctx, wg, dest, c,
-			fanInChanValues, fanInChanErrors, cmdBuilder, incompatible)
+		go downloadPKGBUILDSourceWorker(ctx, wg, dest, c, progress, cmdBuilder, opts, incompatible)
 	}
 
-	go func() {
-		wg.Wait()
-		close(fanInChanValues)
-		close(fanInChanErrors)
-	}()
+	wg.Wait()
 
-	returnErr := multierror.MultiError{}
+	return progress.Close()
+}
 
-receiver:
-	for {
-		select {
-		case _, ok := <-fanInChanValues:
-			if !ok {
-				break receiver
-			}
-		case err, ok := <-fanInChanErrors:
-			if !ok {
-				break receiver
-			}
-			returnErr.Add(err)
-		}
+// evictSources trims the source cache down to its size limit.
+func evictSources(cache *srccache.Cache) {
+	if _, err := cache.Evict(); err != nil {
+		text.Warnln(gotext.Get("unable to trim the source cache: %s", err))
 	}
-
-	return returnErr.Return()
 }
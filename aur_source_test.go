@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"os"
 	"os/exec"
 	"sync/atomic"
 	"testing"
@@ -27,7 +28,14 @@ type TestMakepkgBuilder struct {
 }
 
 func (z *TestMakepkgBuilder) BuildMakepkgCmd(ctx context.Context, dir string, extraArgs ...string) *exec.Cmd {
-	cmd := z.parentBuilder.BuildMakepkgCmd(ctx, dir, extraArgs...)
+	return z.check(z.parentBuilder.BuildMakepkgCmd(ctx, dir, extraArgs...))
+}
+
+func (z *TestMakepkgBuilder) BuildMakepkgBaseCmd(ctx context.Context, pkgbase, dir string, extraArgs ...string) *exec.Cmd {
+	return z.check(z.parentBuilder.BuildMakepkgBaseCmd(ctx, pkgbase, dir, extraArgs...))
+}
+
+func (z *TestMakepkgBuilder) check(cmd *exec.Cmd) *exec.Cmd {
 	if z.want != "" {
 		assert.Contains(z.test, cmd.String(), z.want)
 	}
@@ -55,7 +63,7 @@ func Test_downloadPKGBUILDSource(t *testing.T) {
 		want:          "makepkg --nocheck --config /etc/not.conf --verifysource -Ccf",
 		wantDir:       "/tmp/yay-bin",
 	}
-	err := downloadPKGBUILDSource(context.TODO(), cmdBuilder, "/tmp", "yay-bin", stringset.Make())
+	err := downloadPKGBUILDSource(context.TODO(), cmdBuilder, sourceDownload{}, nil, "/tmp", "yay-bin", stringset.Make())
 	assert.NoError(t, err)
 	assert.Equal(t, 1, int(cmdBuilder.passes))
 }
@@ -72,11 +80,42 @@ func Test_downloadPKGBUILDSourceError(t *testing.T) {
 		wantDir:       "/tmp/yay-bin",
 		showError:     &exec.ExitError{},
 	}
-	err := downloadPKGBUILDSource(context.TODO(), cmdBuilder, "/tmp", "yay-bin", stringset.Make())
+	err := downloadPKGBUILDSource(context.TODO(), cmdBuilder, sourceDownload{}, nil, "/tmp", "yay-bin", stringset.Make())
 	assert.Error(t, err)
 	assert.EqualError(t, err, "error downloading sources: \x1b[36myay-bin\x1b[0m \n\t context: <nil> \n\t \n")
 }
 
+// GIVEN 1 package matching makepkg overrides
+// WHEN downloadPKGBUILDSource is called
+// THEN makepkg should be called with the merged overrides
+func Test_downloadPKGBUILDSourceOverrides(t *testing.T) {
+	t.Parallel()
+	cmdBuilder := &TestMakepkgBuilder{
+		parentBuilder: &exe.CmdBuilder{
+			MakepkgConfPath: "/etc/not.conf", MakepkgFlags: []string{"--nocheck"}, MakepkgBin: "makepkg",
+			MakepkgOverrides: []exe.MakepkgOverride{
+				{Pattern: "yay-*", Flags: []string{"--skipinteg"}, Env: map[string]string{"MAKEFLAGS": "-j2"}},
+				{Pattern: "yay-bin", SkipPGPCheck: true, MakepkgConf: "/etc/clang.conf", Env: map[string]string{"MAKEFLAGS": "-j4"}},
+				{Pattern: "yay-git", IgnoreArch: true},
+			},
+		},
+		test:    t,
+		want:    "--nocheck --config /etc/clang.conf --skipinteg --skippgpcheck --verifysource -Ccf",
+		wantDir: "/tmp/yay-bin",
+	}
+	err := downloadPKGBUILDSource(context.TODO(), cmdBuilder, sourceDownload{}, nil, "/tmp", "yay-bin", stringset.Make())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, int(cmdBuilder.passes))
+
+	// systemd-run is given the environment when running as root
+	cmd := cmdBuilder.parentBuilder.BuildMakepkgBaseCmd(context.TODO(), "yay-bin", "/tmp/yay-bin")
+	if os.Geteuid() == 0 {
+		assert.Contains(t, cmd.String(), "-E MAKEFLAGS=-j4")
+	} else {
+		assert.Contains(t, cmd.Env, "MAKEFLAGS=-j4")
+	}
+}
+
 // GIVEN 5 packages
 // WHEN downloadPKGBUILDSourceFanout is called
 // THEN 5 calls should be made to makepkg
@@ -98,7 +137,7 @@ func Test_downloadPKGBUILDSourceFanout(t *testing.T) {
 		{&aur.Pkg{PackageBase: "yay-v12"}},
 	}
 
-	err := downloadPKGBUILDSourceFanout(context.TODO(), cmdBuilder, "/tmp", bases, stringset.Make())
+	err := downloadPKGBUILDSourceFanout(context.TODO(), cmdBuilder, sourceDownload{}, "/tmp", bases, stringset.Make())
 	assert.NoError(t, err)
 	assert.Equal(t, 5, int(cmdBuilder.passes))
 }
@@ -120,7 +159,7 @@ func Test_downloadPKGBUILDSourceFanoutNoCC(t *testing.T) {
 		{&aur.Pkg{PackageBase: "yay"}},
 	}
 
-	err := downloadPKGBUILDSourceFanout(context.TODO(), cmdBuilder, "/tmp", bases, stringset.Make())
+	err := downloadPKGBUILDSourceFanout(context.TODO(), cmdBuilder, sourceDownload{}, "/tmp", bases, stringset.Make())
 	assert.NoError(t, err)
 	assert.Equal(t, 1, int(cmdBuilder.passes))
 }
@@ -147,7 +186,7 @@ func Test_downloadPKGBUILDSourceFanoutError(t *testing.T) {
 		{&aur.Pkg{PackageBase: "yay-v12"}},
 	}
 
-	err := downloadPKGBUILDSourceFanout(context.TODO(), cmdBuilder, "/tmp", bases, stringset.Make())
+	err := downloadPKGBUILDSourceFanout(context.TODO(), cmdBuilder, sourceDownload{}, "/tmp", bases, stringset.Make())
 	assert.Error(t, err)
 	assert.Equal(t, 5, int(cmdBuilder.passes))
 	assert.Len(t, err.(*multierror.MultiError).Errors, 5)
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+
+	gosrc "github.com/Morganamilo/go-srcinfo"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/db"
+	"github.com/Jguer/yay/v11/pkg/dep"
+	"github.com/Jguer/yay/v11/pkg/pin"
+	"github.com/Jguer/yay/v11/pkg/settings/parser"
+	"github.com/Jguer/yay/v11/pkg/sources"
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+// pkgbuildDir returns the directory pkgbase is built in.
+func pkgbuildDir(pkgbase string) string {
+	if dir, ok := config.Runtime.LocalBuildDirs[pkgbase]; ok {
+		return dir
+	}
+
+	return filepath.Join(config.BuildDir, pkgbase)
+}
+
+// remoteBases returns the bases that are not built from a local directory.
+func remoteBases(bases []dep.Base) []dep.Base {
+	remote := make([]dep.Base, 0, len(bases))
+
+	for _, base := range bases {
+		if _, ok := config.Runtime.LocalBuildDirs[base.Pkgbase()]; !ok {
+			remote = append(remote, base)
+		}
+	}
+
+	return remote
+}
+
+// yay -B.
+func handleBuild(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Executor) error {
+	if len(cmdArgs.Targets) == 0 {
+		return errors.New(gotext.Get("no target directories specified"))
+	}
+
+	srcinfos := make([]*gosrc.Srcinfo, 0, len(cmdArgs.Targets))
+	targets := make([]string, 0, len(cmdArgs.Targets))
+
+	for _, target := range cmdArgs.Targets {
+		dir, err := filepath.Abs(target)
+		if err != nil {
+			return err
+		}
+
+		srcinfo, err := sources.ReadSrcinfo(ctx, config.Runtime.CmdBuilder, dir)
+		if err != nil {
+			return err
+		}
+
+		if other, ok := config.Runtime.LocalBuildDirs[srcinfo.Pkgbase]; ok && other != dir {
+			return errors.New(gotext.Get("%s is provided by both %s and %s", srcinfo.Pkgbase, other, dir))
+		}
+
+		config.Runtime.LocalBuildDirs[srcinfo.Pkgbase] = dir
+		srcinfos = append(srcinfos, srcinfo)
+
+		for _, pkg := range srcinfo.SplitPackages() {
+			targets = append(targets, "aur/"+pkg.Pkgname)
+		}
+	}
+
+	config.Runtime.QueryClient = sources.NewLocal(config.Runtime.QueryClient, srcinfos)
+
+	cmdArgs.Op = "S"
+	cmdArgs.ClearTargets()
+	cmdArgs.AddTarget(targets...)
+
+	return install(ctx, cmdArgs, dbExecutor, false)
+}
+
+// updateOrigins records the directory of the bases built with yay -B, and
+// forgets the origin of packages built from the AUR again.
+func updateOrigins(bases []dep.Base) {
+	for _, base := range bases {
+		names := make([]string, 0, len(base))
+		for _, pkg := range base {
+			names = append(names, pkg.Name)
+		}
+
+		dir, ok := config.Runtime.LocalBuildDirs[base.Pkgbase()]
+		if !ok {
+			if _, err := config.Runtime.OriginStore.Release(names); err != nil {
+				text.Errorln(err)
+			}
+
+			continue
+		}
+
+		o := pin.Pin{Pkgbase: base.Pkgbase(), Version: base.Version(), Dir: dir}
+		if err := config.Runtime.OriginStore.Set(names, o); err != nil {
+			text.Errorln(err)
+		}
+	}
+}
+
+// withoutLocalOrigins removes the packages built with yay -B from names so
+// they are not compared against the AUR.
+func withoutLocalOrigins(names []string) []string {
+	result := make([]string, 0, len(names))
+
+	for _, name := range names {
+		if _, ok := config.Runtime.OriginStore.Get(name); !ok {
+			result = append(result, name)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/download"
+	"github.com/Jguer/yay/v11/pkg/multierror"
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+const (
+	recoverStash   = "stash"
+	recoverReset   = "reset"
+	recoverReclone = "reclone"
+	recoverAbort   = "abort"
+)
+
+// parseRecoverAnswer maps an answer to the checkout recovery prompt to one of
+// the recover actions, aborting on anything unknown.
+func parseRecoverAnswer(answer string) string {
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "s", recoverStash:
+		return recoverStash
+	case "r", recoverReset:
+		return recoverReset
+	case "c", "clone", recoverReclone:
+		return recoverReclone
+	default:
+		return recoverAbort
+	}
+}
+
+// flattenErrors returns the errors held by nested multierrors.
+func flattenErrors(err error) []error {
+	var multi *multierror.MultiError
+	if !errors.As(err, &multi) {
+		return []error{err}
+	}
+
+	errs := make([]error, 0, len(multi.Errors))
+	for _, e := range multi.Errors {
+		errs = append(errs, flattenErrors(e)...)
+	}
+
+	return errs
+}
+
+// recoverCheckouts offers to recover the checkouts that failed to update in
+// err, recording the recovered ones in cloned. The other errors are returned.
+func recoverCheckouts(ctx context.Context, cloned map[string]bool, dest string, err error) error {
+	var errs multierror.MultiError
+
+	for _, e := range flattenErrors(err) {
+		var errCheckout download.ErrPKGBUILDRepoCheckout
+		if !errors.As(e, &errCheckout) {
+			errs.Add(e)
+			continue
+		}
+
+		newClone, errR := recoverCheckout(ctx, errCheckout, dest)
+		if errR != nil {
+			errs.Add(errR)
+			continue
+		}
+
+		cloned[errCheckout.Pkgbase] = newClone
+	}
+
+	return errs.Return()
+}
+
+func recoverCheckout(ctx context.Context, errCheckout download.ErrPKGBUILDRepoCheckout, dest string) (bool, error) {
+	dir := filepath.Join(dest, errCheckout.Pkgbase)
+
+	text.Warnln(gotext.Get("%s: %s", text.Cyan(errCheckout.Pkgbase), errCheckout.State))
+	text.Infoln(gotext.Get("[S]tash and reapply local edits, [R]eset to upstream, Re-[C]lone or [A]bort"))
+
+	answer := config.RecoverCheckout
+	if answer == "ask" {
+		answer = ""
+	}
+
+	input, err := getInput(answer)
+	if err != nil {
+		return false, err
+	}
+
+	switch parseRecoverAnswer(input) {
+	case recoverStash:
+		if errReset := download.ResetCheckout(ctx, config.Runtime.CmdBuilder, dir, true); errReset != nil {
+			return false, errReset
+		}
+
+		config.Runtime.KeptEdits.Set(errCheckout.Pkgbase)
+
+		return false, nil
+	case recoverReset:
+		return false, download.ResetCheckout(ctx, config.Runtime.CmdBuilder, dir, false)
+	case recoverReclone:
+		cloned, errClone := fetchPKGBUILDRepos(ctx, []string{errCheckout.Pkgbase}, dest, true)
+
+		return cloned[errCheckout.Pkgbase], errClone
+	default:
+		return false, errCheckout
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v11/pkg/multierror"
+)
+
+func TestParseRecoverAnswer(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		answer string
+		want   string
+	}{
+		{answer: "S", want: recoverStash},
+		{answer: "stash", want: recoverStash},
+		{answer: "r", want: recoverReset},
+		{answer: " Reset ", want: recoverReset},
+		{answer: "c", want: recoverReclone},
+		{answer: "reclone", want: recoverReclone},
+		{answer: "", want: recoverAbort},
+		{answer: "y", want: recoverAbort},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.answer, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, parseRecoverAnswer(tc.answer))
+		})
+	}
+}
+
+func TestFlattenErrors(t *testing.T) {
+	t.Parallel()
+
+	errA, errB, errC := errors.New("a"), errors.New("b"), errors.New("c")
+
+	var inner, outer multierror.MultiError
+
+	inner.Add(errB)
+	inner.Add(errC)
+	outer.Add(errA)
+	outer.Add(inner.Return())
+
+	assert.Equal(t, []error{errA, errB, errC}, flattenErrors(outer.Return()))
+	assert.Equal(t, []error{errA}, flattenErrors(errA))
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -18,6 +19,7 @@ import (
 	"github.com/Jguer/yay/v11/pkg/news"
 	"github.com/Jguer/yay/v11/pkg/query"
 	"github.com/Jguer/yay/v11/pkg/settings"
+	"github.com/Jguer/yay/v11/pkg/settings/makepkgconf"
 	"github.com/Jguer/yay/v11/pkg/settings/parser"
 	"github.com/Jguer/yay/v11/pkg/text"
 	"github.com/Jguer/yay/v11/pkg/upgrade"
@@ -45,6 +47,7 @@ New operations:
     yay {-Y --yay}         [options] [package(s)]
     yay {-P --show}        [options]
     yay {-G --getpkgbuild} [options] [package(s)]
+    yay {-B --build}       [options] <dir(s)>
 
 If no arguments are provided 'yay -Syu' will be performed
 If no operation is provided -Y will be assumed
@@ -59,6 +62,13 @@ Permanent configuration options:
 
     --aururl      <url>   Set an alternative AUR URL
     --builddir    <dir>   Directory used to download and run PKGBUILDS
+    --patchdir    <dir>   Directory holding local patches per package base
+    --sourcecachedir <dir> Directory sharing downloaded sources between packages
+    --sourcecachesize <n> Size limit of the source cache in MiB, 0 disables it
+    --downloadjobs <n>    Number of PKGBUILDs downloaded at once, 0 for default
+    --downloadprogress <auto|live|plain> How download progress is shown
+    --pgphome     <dir>   GNUPGHOME of the keyring dedicated to yay
+    --pgpkeyservers <urls> Keyservers to import PGP keys from, in order
     --editor      <file>  Editor to use when editing PKGBUILDs
     --editorflags <flags> Pass arguments to editor
     --makepkg     <file>  makepkg command to use
@@ -73,7 +83,13 @@ Permanent configuration options:
     --nomakepkgconf       Use the default makepkg.conf
 
     --requestsplitn <n>   Max amount of packages to query per AUR request
+    --gitretries <n>      Times to retry a clone or pull after a network failure
+    --gitretrybackoff <n> Seconds to wait before the first retry
     --completioninterval  <n> Time in days to refresh completion cache
+    --metadatainterval    <n> Time in days to refresh the AUR metadata dump
+    --aurmetadata         Answer AUR queries from a local metadata dump
+    --noaurmetadata       Query the AUR RPC directly
+    --aurcachettl     <n> Time in seconds to cache AUR RPC responses
     --sortby    <field>   Sort AUR results by a specific field during search
     --searchby  <field>   Search for packages using a specified field
     --answerclean   <a>   Set a predetermined answer for the clean build menu
@@ -95,6 +111,9 @@ Permanent configuration options:
     --askremovemake       Ask to remove makedepends after install
     --removemake          Remove makedepends after install
     --noremovemake        Don't remove makedepends after install
+    --recovercheckout <mode> Fix a checkout that can't be pulled: ask, stash, reset, reclone or abort
+    --diskcheck <mode>    When builds may not fit on disk: warn, refuse or no
+    --diskmultiplier <n>  Factor applied to the estimated build size
 
     --cleanafter          Remove package sources after successful install
     --nocleanafter        Do not remove package sources after successful build
@@ -114,6 +133,10 @@ Permanent configuration options:
     --noprovides          Just look for packages by pkgname
     --pgpfetch            Prompt to import PGP keys from PKGBUILDs
     --nopgpfetch          Don't prompt to import PGP keys
+    --pgpkeyring          Import PGP keys to a keyring dedicated to yay
+    --nopgpkeyring        Import PGP keys to the default keyring of the user
+    --pgpwkd              Look missing PGP keys up in the Web Key Directory
+    --nopgpwkd            Only import PGP keys from keyservers
     --useask              Automatically resolve conflicts using pacman's ask flag
     --nouseask            Confirm conflicts manually during the install
     --combinedupgrade     Refresh then perform the repo and AUR upgrade together
@@ -128,17 +151,40 @@ Permanent configuration options:
 
     --timeupdate          Check packages' AUR page for changes during sysupgrade
     --notimeupdate        Do not check packages' AUR page for changes
+    --checknews           Show unread Arch news before sysupgrade
+    --nochecknews         Do not check Arch news during sysupgrade
+    --newsstop            Stop --noconfirm upgrades on news mentioning pending packages
+    --nonewsstop          Proceed with --noconfirm upgrades whatever the news
+    --offline             Answer AUR queries from the metadata dump only
+    --refresh-aur         Ignore cached AUR RPC responses
+
+query specific options:
+       --json             Print the update list (-Qu, -Pu) or count (-Pn) as JSON
+       --format    <fmt>  Print -Qi as json or with a Go template
+
+sync specific options:
+       --aur-version <ver> Build an AUR target at a previous version and pin it
+       --aur-commit  <sha> Build an AUR target at a PKGBUILD commit and pin it
+       --format    <fmt>  Print -Ss and -Si as json or with a Go template
+       --comments         Print the pinned and latest AUR comments with -Si
+       --required-by      Print the AUR packages depending on -Si targets
 
 show specific options:
     -c --complete         Used for completions
     -d --defaultconfig    Print default yay configuration
     -g --currentconfig    Print current yay configuration
     -s --stats            Display system package statistics
+    -u --upgrades         Print the list of available updates, same as -Qu
     -w --news             Print arch news
 
 yay specific options:
     -c --clean            Remove unneeded dependencies
        --gendb            Generates development package DB used for updating
+       --publish          Validate PKGBUILD directories and push them to the AUR
+       --dry-run          Validate with --publish without committing or pushing
+       --keys             List the PGP keys imported for PKGBUILDs
+       --keys -y          Refresh the PGP keys imported for PKGBUILDs
+       --keys --delete    Delete PGP keys imported for PKGBUILDs
 
 getpkgbuild specific options:
     -f --force            Force download for existing ABS packages
@@ -179,6 +225,8 @@ func handleCmd(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Exe
 			cmdArgs, config.Runtime.Mode, settings.NoConfirm))
 	case "G", "getpkgbuild":
 		return handleGetpkgbuild(ctx, cmdArgs, dbExecutor)
+	case "B", "build":
+		return handleBuild(ctx, cmdArgs, dbExecutor)
 	case "P", "show":
 		return handlePrint(ctx, cmdArgs, dbExecutor)
 	case "Y", "--yay":
@@ -222,6 +270,17 @@ func handleQuery(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.E
 		return printUpdateList(ctx, cmdArgs, dbExecutor, cmdArgs.ExistsDouble("u", "sysupgrade"), filter)
 	}
 
+	if cmdArgs.ExistsArg("i", "info") {
+		printer, err := formatPrinter(cmdArgs)
+		if err != nil {
+			return err
+		}
+
+		if printer != nil {
+			return formatQueryInfo(cmdArgs.Targets, printer, dbExecutor)
+		}
+	}
+
 	if err := config.Runtime.CmdBuilder.Show(config.Runtime.CmdBuilder.BuildPacmanCmd(ctx,
 		cmdArgs, config.Runtime.Mode, settings.NoConfirm)); err != nil {
 		if str := err.Error(); strings.Contains(str, "exit status") {
@@ -237,7 +296,7 @@ func handleQuery(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.E
 
 func handleHelp(ctx context.Context, cmdArgs *parser.Arguments) error {
 	switch cmdArgs.Op {
-	case "Y", "yay", "G", "getpkgbuild", "P", "show":
+	case "Y", "yay", "G", "getpkgbuild", "P", "show", "B", "build":
 		usage()
 		return nil
 	}
@@ -258,21 +317,35 @@ func handlePrint(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.E
 
 		return nil
 	case cmdArgs.ExistsArg("g", "currentconfig"):
-		fmt.Printf("%v", config)
-
-		return nil
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+
+		// the effective makepkg.conf settings are shown but never saved
+		return enc.Encode(struct {
+			*settings.Configuration
+			Makepkg *makepkgconf.Config `json:"makepkg,omitempty"`
+		}{config, config.Runtime.MakepkgConf})
 	case cmdArgs.ExistsArg("n", "numberupgrades"):
 		filter, err := getFilter(cmdArgs)
 		if err != nil {
 			return err
 		}
 
-		return printNumberOfUpdates(ctx, dbExecutor, cmdArgs.ExistsDouble("u", "sysupgrade"), filter)
+		return printNumberOfUpdates(ctx, dbExecutor, cmdArgs.ExistsDouble("u", "sysupgrade"),
+			cmdArgs.ExistsArg("json"), filter)
+	case cmdArgs.ExistsArg("u", "upgrades"):
+		filter, err := getFilter(cmdArgs)
+		if err != nil {
+			return err
+		}
+
+		return printUpdateList(ctx, cmdArgs, dbExecutor, cmdArgs.ExistsDouble("u", "sysupgrade"), filter)
 	case cmdArgs.ExistsArg("w", "news"):
 		double := cmdArgs.ExistsDouble("w", "news")
 		quiet := cmdArgs.ExistsArg("q", "quiet")
 
-		return news.PrintNewsFeed(ctx, config.Runtime.HTTPClient, dbExecutor.LastBuildTime(), config.SortMode, double, quiet)
+		return news.PrintNewsFeed(ctx, config.Runtime.HTTPClient, config.NewsFeeds,
+			dbExecutor.LastBuildTime(), config.SortMode, double, quiet)
 	case cmdArgs.ExistsDouble("c", "complete"):
 		return completion.Show(ctx, config.Runtime.HTTPClient, dbExecutor,
 			config.AURURL, config.Runtime.CompletionPath, config.CompletionInterval, true)
@@ -290,6 +363,10 @@ func handleYay(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Exe
 	switch {
 	case cmdArgs.ExistsArg("gendb"):
 		return createDevelDB(ctx, config, dbExecutor)
+	case cmdArgs.ExistsArg("publish"):
+		return publishPkgbuilds(ctx, cmdArgs.Targets, cmdArgs.ExistsArg("dry-run"))
+	case cmdArgs.ExistsArg("keys"):
+		return handleKeys(cmdArgs)
 	case cmdArgs.ExistsDouble("c"):
 		return cleanDependencies(ctx, cmdArgs, dbExecutor, true)
 	case cmdArgs.ExistsArg("c", "clean"):
@@ -303,7 +380,8 @@ func handleYay(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Exe
 
 func handleGetpkgbuild(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor download.DBSearcher) error {
 	if cmdArgs.ExistsArg("p", "print") {
-		return printPkgbuilds(dbExecutor, config.Runtime.HTTPClient, cmdArgs.Targets, config.Runtime.Mode, config.AURURL)
+		return printPkgbuilds(ctx, dbExecutor, config.Runtime.HTTPClient, cmdArgs.Targets, config.Runtime.Mode,
+			config.AURURL, config.ABSURLs)
 	}
 
 	return getPkgbuilds(ctx, dbExecutor, config, cmdArgs.Targets, cmdArgs.ExistsArg("f", "force"))
@@ -319,13 +397,22 @@ func handleSync(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Ex
 
 	switch {
 	case cmdArgs.ExistsArg("s", "search"):
+		printer, err := formatPrinter(cmdArgs)
+		if err != nil {
+			return err
+		}
+
+		if printer != nil {
+			return formatSearch(ctx, targets, printer, dbExecutor)
+		}
+
 		if cmdArgs.ExistsArg("q", "quiet") {
 			config.SearchMode = minimal
 		} else {
 			config.SearchMode = detailed
 		}
 
-		return syncSearch(ctx, targets, config.Runtime.AURClient, dbExecutor)
+		return syncSearch(ctx, targets, config.Runtime.QueryClient, dbExecutor)
 	case cmdArgs.ExistsArg("p", "print", "print-format"):
 		return config.Runtime.CmdBuilder.Show(config.Runtime.CmdBuilder.BuildPacmanCmd(ctx,
 			cmdArgs, config.Runtime.Mode, settings.NoConfirm))
@@ -337,6 +424,15 @@ func handleSync(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Ex
 		return config.Runtime.CmdBuilder.Show(config.Runtime.CmdBuilder.BuildPacmanCmd(ctx,
 			cmdArgs, config.Runtime.Mode, settings.NoConfirm))
 	case cmdArgs.ExistsArg("i", "info"):
+		printer, err := formatPrinter(cmdArgs)
+		if err != nil {
+			return err
+		}
+
+		if printer != nil {
+			return formatSyncInfo(ctx, targets, printer, dbExecutor)
+		}
+
 		return syncInfo(ctx, cmdArgs, targets, dbExecutor)
 	case cmdArgs.ExistsArg("u", "sysupgrade"):
 		return install(ctx, cmdArgs, dbExecutor, false)
@@ -355,11 +451,79 @@ func handleRemove(ctx context.Context, cmdArgs *parser.Arguments, localCache *vc
 		cmdArgs, config.Runtime.Mode, settings.NoConfirm))
 	if err == nil {
 		localCache.RemovePackage(cmdArgs.Targets)
+
+		if _, errPin := config.Runtime.PinStore.Release(cmdArgs.Targets); errPin != nil {
+			text.Errorln(errPin)
+		}
+
+		if _, errOrigin := config.Runtime.OriginStore.Release(cmdArgs.Targets); errOrigin != nil {
+			text.Errorln(errOrigin)
+		}
 	}
 
 	return err
 }
 
+// readNumberMenu asks for the packages to install and returns whether the
+// package displayed with a given number was selected.
+func readNumberMenu() (func(int) bool, error) {
+	text.Infoln(gotext.Get("Packages to install (eg: 1 2 3, 1-3 or ^4)"))
+	text.Info()
+
+	reader := bufio.NewReader(os.Stdin)
+
+	numberBuf, overflow, err := reader.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+
+	if overflow {
+		return nil, fmt.Errorf(gotext.Get("input too long"))
+	}
+
+	include, exclude, _, otherExclude := intrange.ParseNumberMenu(string(numberBuf))
+	isInclude := len(exclude) == 0 && len(otherExclude) == 0
+
+	return func(n int) bool {
+		return (isInclude && include.Get(n)) || (!isInclude && !exclude.Get(n))
+	}, nil
+}
+
+// displayRankedMenu presents the number menu with the relevance ranked results.
+func displayRankedMenu(ctx context.Context, pkgS []string, dbExecutor db.Executor, cmdArgs *parser.Arguments) error {
+	rq, aurErr := rankedSearch(ctx, config.Runtime.QueryClient, pkgS, dbExecutor)
+	if aurErr != nil {
+		text.Errorln(gotext.Get("Error during AUR search: %s\n", aurErr))
+		text.Warnln(gotext.Get("Showing repo packages only"))
+	}
+
+	if len(rq) == 0 {
+		return fmt.Errorf(gotext.Get("no packages match search"))
+	}
+
+	rq.printSearch(dbExecutor)
+
+	isSelected, err := readNumberMenu()
+	if err != nil {
+		return err
+	}
+
+	arguments := cmdArgs.CopyGlobal()
+
+	for i := range rq {
+		if isSelected(rq.number(i)) {
+			arguments.AddTarget(rq[i].target())
+		}
+	}
+
+	if len(arguments.Targets) == 0 {
+		fmt.Println(gotext.Get(" there is nothing to do"))
+		return nil
+	}
+
+	return install(ctx, arguments, dbExecutor, true)
+}
+
 // NumberMenu presents a CLI for selecting packages to install.
 func displayNumberMenu(ctx context.Context, pkgS []string, dbExecutor db.Executor, cmdArgs *parser.Arguments) error {
 	var (
@@ -371,8 +535,12 @@ func displayNumberMenu(ctx context.Context, pkgS []string, dbExecutor db.Executo
 
 	pkgS = query.RemoveInvalidTargets(pkgS, config.Runtime.Mode)
 
+	if config.SortBy == sortByRelevance {
+		return displayRankedMenu(ctx, pkgS, dbExecutor, cmdArgs)
+	}
+
 	if config.Runtime.Mode.AtLeastAUR() {
-		aq, aurErr = narrowSearch(ctx, config.Runtime.AURClient, pkgS, true)
+		aq, aurErr = narrowSearch(ctx, config.Runtime.QueryClient, pkgS, true)
 		lenaq = len(aq)
 	}
 
@@ -411,25 +579,13 @@ func displayNumberMenu(ctx context.Context, pkgS []string, dbExecutor db.Executo
 		return fmt.Errorf(gotext.Get("invalid sort mode. Fix with yay -Y --bottomup --save"))
 	}
 
-	text.Infoln(gotext.Get("Packages to install (eg: 1 2 3, 1-3 or ^4)"))
-	text.Info()
-
-	reader := bufio.NewReader(os.Stdin)
-
-	numberBuf, overflow, err := reader.ReadLine()
+	isSelected, err := readNumberMenu()
 	if err != nil {
 		return err
 	}
 
-	if overflow {
-		return fmt.Errorf(gotext.Get("input too long"))
-	}
-
-	include, exclude, _, otherExclude := intrange.ParseNumberMenu(string(numberBuf))
 	arguments := cmdArgs.CopyGlobal()
 
-	isInclude := len(exclude) == 0 && len(otherExclude) == 0
-
 	for i, pkg := range pq {
 		var target int
 
@@ -442,7 +598,7 @@ func displayNumberMenu(ctx context.Context, pkgS []string, dbExecutor db.Executo
 			return fmt.Errorf(gotext.Get("invalid sort mode. Fix with yay -Y --bottomup --save"))
 		}
 
-		if (isInclude && include.Get(target)) || (!isInclude && !exclude.Get(target)) {
+		if isSelected(target) {
 			arguments.AddTarget(pkg.DB().Name() + "/" + pkg.Name())
 		}
 	}
@@ -459,7 +615,7 @@ func displayNumberMenu(ctx context.Context, pkgS []string, dbExecutor db.Executo
 			return fmt.Errorf(gotext.Get("invalid sort mode. Fix with yay -Y --bottomup --save"))
 		}
 
-		if (isInclude && include.Get(target)) || (!isInclude && !exclude.Get(target)) {
+		if isSelected(target) {
 			arguments.AddTarget("aur/" + aq[i].Name)
 		}
 	}
@@ -10,18 +10,26 @@ import (
 
 	"github.com/Jguer/yay/v11/pkg/dep"
 	"github.com/Jguer/yay/v11/pkg/multierror"
+	"github.com/Jguer/yay/v11/pkg/pin"
 	"github.com/Jguer/yay/v11/pkg/text"
 )
 
 const gitDiffRefName = "AUR_SEEN"
 
-func showPkgbuildDiffs(ctx context.Context, bases []dep.Base, cloned map[string]bool) error {
+// showPkgbuildDiffs shows the changes since the last reviewed revision of bases,
+// up to upstream or up to the pinned commit.
+func showPkgbuildDiffs(ctx context.Context, bases []dep.Base, cloned map[string]bool, pinned pin.Pin) error {
 	var errMulti multierror.MultiError
 
 	for _, base := range bases {
 		pkg := base.Pkgbase()
 		dir := filepath.Join(config.BuildDir, pkg)
 
+		target := "HEAD@{upstream}"
+		if pkg == pinned.Pkgbase {
+			target = pinned.Commit
+		}
+
 		start, err := getLastSeenHash(ctx, config.BuildDir, pkg)
 		if err != nil {
 			errMulti.Add(err)
@@ -32,7 +40,7 @@ func showPkgbuildDiffs(ctx context.Context, bases []dep.Base, cloned map[string]
 		if cloned[pkg] {
 			start = gitEmptyTree
 		} else {
-			hasDiff, err := gitHasDiff(ctx, config.BuildDir, pkg)
+			hasDiff, err := gitHasDiff(ctx, config.BuildDir, pkg, target)
 			if err != nil {
 				errMulti.Add(err)
 
@@ -42,13 +50,18 @@ func showPkgbuildDiffs(ctx context.Context, bases []dep.Base, cloned map[string]
 			if !hasDiff {
 				text.Warnln(gotext.Get("%s: No changes -- skipping", text.Cyan(base.String())))
 
+				// local patches are applied whether or not upstream changed
+				if err := showPatches(pkg); err != nil {
+					errMulti.Add(err)
+				}
+
 				continue
 			}
 		}
 
 		args := []string{
 			"diff",
-			start + "..HEAD@{upstream}", "--src-prefix",
+			start + ".." + target, "--src-prefix",
 			dir + "/", "--dst-prefix", dir + "/", "--", ".", ":(exclude).SRCINFO",
 		}
 		if text.UseColor {
@@ -58,17 +71,21 @@ func showPkgbuildDiffs(ctx context.Context, bases []dep.Base, cloned map[string]
 		}
 
 		_ = config.Runtime.CmdBuilder.Show(config.Runtime.CmdBuilder.BuildGitCmd(ctx, dir, args...))
+
+		if err := showPatches(pkg); err != nil {
+			errMulti.Add(err)
+		}
 	}
 
 	return errMulti.Return()
 }
 
 // Check whether or not a diff exists between the last reviewed diff and
-// HEAD@{upstream}.
-func gitHasDiff(ctx context.Context, path, name string) (bool, error) {
+// target.
+func gitHasDiff(ctx context.Context, path, name, target string) (bool, error) {
 	if gitHasLastSeenRef(ctx, path, name) {
 		stdout, stderr, err := config.Runtime.CmdBuilder.Capture(
-			config.Runtime.CmdBuilder.BuildGitCmd(ctx, filepath.Join(path, name), "rev-parse", gitDiffRefName, "HEAD@{upstream}"))
+			config.Runtime.CmdBuilder.BuildGitCmd(ctx, filepath.Join(path, name), "rev-parse", gitDiffRefName, target))
 		if err != nil {
 			return false, fmt.Errorf("%s%s", stderr, err)
 		}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/db"
+	"github.com/Jguer/yay/v11/pkg/dep"
+	"github.com/Jguer/yay/v11/pkg/diskspace"
+	"github.com/Jguer/yay/v11/pkg/settings/makepkgconf"
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+// estimateDiskSpace guesses the space building base takes from the size of its
+// previous build and the installed size of its packages, or of the repo
+// packages of the same name. Bases nothing is known about are assumed to need
+// diskspace.MinimumBuild.
+func estimateDiskSpace(base dep.Base, dbExecutor db.Executor) *diskspace.Estimate {
+	pkgbase := base.Pkgbase()
+	dir := pkgbuildDir(pkgbase)
+
+	conf := config.MakepkgConfFor(pkgbase)
+	if conf == nil {
+		conf = &makepkgconf.Config{}
+	}
+
+	// locations left to their default are inside BuildDir
+	location := func(path string) string {
+		if path == dir {
+			return config.BuildDir
+		}
+
+		return path
+	}
+
+	workDir := conf.WorkDir(dir, pkgbase)
+	previous := diskspace.DirSize(filepath.Join(workDir, "src")) + diskspace.DirSize(filepath.Join(workDir, "pkg"))
+
+	names := make([]string, 0, len(base))
+
+	var installed, repo int64
+
+	for _, pkg := range base {
+		names = append(names, pkg.Name)
+
+		if local := dbExecutor.LocalPackage(pkg.Name); local != nil {
+			installed += local.ISize()
+		}
+
+		if syncPkg := dbExecutor.SyncPackage(pkg.Name); syncPkg != nil {
+			repo += syncPkg.ISize()
+		}
+	}
+
+	if installed == 0 {
+		installed = repo
+	}
+
+	if previous == 0 && installed == 0 {
+		text.Warnln(gotext.Get("no disk space estimate for %s, assuming %s",
+			text.Cyan(pkgbase), text.Human(diskspace.MinimumBuild)))
+
+		installed = diskspace.MinimumBuild
+	}
+
+	estimate := &diskspace.Estimate{
+		Pkgbase:  pkgbase,
+		BuildDir: filepath.Dir(workDir),
+		SrcDest:  location(conf.SourceDir(dir)),
+		PkgDest:  location(conf.PackageDir(dir)),
+		Packages: diskspace.PackagesSize(conf.PackageDir(dir), names),
+	}
+
+	build := previous
+	if installed > build {
+		build = installed
+	}
+
+	estimate.Build = int64(float64(build) * config.DiskMultiplier)
+
+	// sources of previous builds are already downloaded
+	if previous == 0 {
+		estimate.Sources = installed
+	}
+
+	if estimate.Packages == 0 {
+		estimate.Packages = installed
+	}
+
+	return estimate
+}
+
+// checkDiskSpace compares the space needed to build bases with the space free
+// where they are built, warning about or refusing builds that won't fit as set
+// by DiskCheck.
+func checkDiskSpace(bases []dep.Base, dbExecutor db.Executor) error {
+	if config.DiskCheck == "no" || len(bases) == 0 {
+		return nil
+	}
+
+	plan := diskspace.NewPlan()
+
+	for _, base := range bases {
+		if err := plan.Add(estimateDiskSpace(base, dbExecutor)); err != nil {
+			text.Warnln(gotext.Get("unable to check disk space: %s", err))
+			return nil
+		}
+	}
+
+	short := false
+
+	for _, volume := range plan.Volumes() {
+		if !volume.Short() {
+			continue
+		}
+
+		short = true
+
+		text.Warnln(gotext.Get("Not enough disk space for %s: %s needed, %s free",
+			strings.Join(volume.Paths, ", "), text.Human(volume.Required), text.Human(volume.Free)))
+		text.Infoln(gotext.Get("Built there: %s", text.Cyan(strings.Join(volume.Pkgbases, " "))))
+	}
+
+	if short && config.DiskCheck == "refuse" {
+		return errors.New(gotext.Get("refusing to build without enough disk space"))
+	}
+
+	return nil
+}
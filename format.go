@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/db"
+	"github.com/Jguer/yay/v11/pkg/format"
+	"github.com/Jguer/yay/v11/pkg/query"
+	"github.com/Jguer/yay/v11/pkg/settings"
+	"github.com/Jguer/yay/v11/pkg/settings/parser"
+	"github.com/Jguer/yay/v11/pkg/stringset"
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+// formatPrinter returns the printer requested with --format, or nil.
+// The option is removed from cmdArgs so it is never passed to pacman.
+func formatPrinter(cmdArgs *parser.Arguments) (*format.Printer, error) {
+	value, _, exists := cmdArgs.GetArg("format")
+	if !exists {
+		return nil, nil
+	}
+
+	cmdArgs.DelArg("format")
+
+	return format.NewPrinter(value)
+}
+
+// formatSearch prints the results of a search with printer, in the same
+// order as the text output.
+func formatSearch(ctx context.Context, pkgS []string, printer *format.Printer, dbExecutor db.Executor) error {
+	pkgS = query.RemoveInvalidTargets(pkgS, config.Runtime.Mode)
+
+	var (
+		aurErr error
+		pkgs   = make([]format.Package, 0)
+	)
+
+	if config.SortBy == sortByRelevance {
+		var rq rankedQuery
+
+		rq, aurErr = rankedSearch(ctx, config.Runtime.QueryClient, pkgS, dbExecutor)
+
+		for i := range rq {
+			if rq[i].repo != nil {
+				pkgs = append(pkgs, format.FromALPM(rq[i].repo, dbExecutor.LocalPackage(rq[i].repo.Name())))
+			} else {
+				pkgs = append(pkgs, format.FromAUR(rq[i].aur, dbExecutor.LocalPackage(rq[i].aur.Name)))
+			}
+		}
+	} else {
+		var (
+			aurPkgs  = make([]format.Package, 0)
+			repoPkgs = make([]format.Package, 0)
+		)
+
+		if config.Runtime.Mode.AtLeastAUR() {
+			var aq aurQuery
+
+			aq, aurErr = narrowSearch(ctx, config.Runtime.QueryClient, pkgS, true)
+			for i := range aq {
+				aurPkgs = append(aurPkgs, format.FromAUR(&aq[i], dbExecutor.LocalPackage(aq[i].Name)))
+			}
+		}
+
+		if config.Runtime.Mode.AtLeastRepo() {
+			for _, pkg := range queryRepo(pkgS, dbExecutor) {
+				repoPkgs = append(repoPkgs, format.FromALPM(pkg, dbExecutor.LocalPackage(pkg.Name())))
+			}
+		}
+
+		if config.SortMode == settings.BottomUp {
+			pkgs = append(pkgs, aurPkgs...)
+			pkgs = append(pkgs, repoPkgs...)
+		} else {
+			pkgs = append(pkgs, repoPkgs...)
+			pkgs = append(pkgs, aurPkgs...)
+		}
+	}
+
+	if aurErr != nil {
+		text.Errorln(gotext.Get("error during AUR search: %s", aurErr))
+	}
+
+	return printer.Print(os.Stdout, pkgs)
+}
+
+// formatSyncInfo prints the repo and AUR packages of pkgS with printer.
+func formatSyncInfo(ctx context.Context, pkgS []string, printer *format.Printer, dbExecutor db.Executor) error {
+	pkgS = query.RemoveInvalidTargets(pkgS, config.Runtime.Mode)
+	aurS, repoS := packageSlices(pkgS, dbExecutor)
+
+	var (
+		pkgs    = make([]format.Package, 0, len(pkgS))
+		missing = make([]string, 0)
+	)
+
+	for _, target := range repoS {
+		dbName, name := text.SplitDBFromName(target)
+
+		var pkg db.IPackage
+		if dbName != "" {
+			pkg = dbExecutor.SatisfierFromDB(name, dbName)
+		} else {
+			pkg = dbExecutor.SyncPackage(name)
+		}
+
+		if pkg == nil {
+			missing = append(missing, target)
+			continue
+		}
+
+		pkgs = append(pkgs, format.FromALPM(pkg, dbExecutor.LocalPackage(pkg.Name())))
+	}
+
+	if len(aurS) != 0 {
+		names := make([]string, 0, len(aurS))
+		for _, target := range aurS {
+			_, name := text.SplitDBFromName(target)
+			names = append(names, name)
+		}
+
+		info, err := query.AURInfo(ctx, config.Runtime.QueryClient, names, &query.AURWarnings{}, config.RequestSplitN)
+		if err != nil {
+			return err
+		}
+
+		found := make(stringset.StringSet)
+
+		for _, pkg := range info {
+			found.Set(pkg.Name)
+			pkgs = append(pkgs, format.FromAUR(pkg, dbExecutor.LocalPackage(pkg.Name)))
+		}
+
+		for _, name := range names {
+			if !found.Get(name) {
+				missing = append(missing, name)
+			}
+		}
+	}
+
+	return printFormatted(printer, pkgs, missing)
+}
+
+// formatQueryInfo prints the local packages of pkgS with printer, or every
+// local package when pkgS is empty.
+func formatQueryInfo(pkgS []string, printer *format.Printer, dbExecutor db.Executor) error {
+	var (
+		pkgs    = make([]format.Package, 0, len(pkgS))
+		missing = make([]string, 0)
+	)
+
+	if len(pkgS) == 0 {
+		for _, pkg := range dbExecutor.LocalPackages() {
+			pkgs = append(pkgs, format.FromALPM(pkg, pkg))
+		}
+	}
+
+	for _, name := range pkgS {
+		pkg := dbExecutor.LocalPackage(name)
+		if pkg == nil {
+			missing = append(missing, name)
+			continue
+		}
+
+		pkgs = append(pkgs, format.FromALPM(pkg, pkg))
+	}
+
+	return printFormatted(printer, pkgs, missing)
+}
+
+// printFormatted prints pkgs and reports the packages that were not found.
+func printFormatted(printer *format.Printer, pkgs []format.Package, missing []string) error {
+	if err := printer.Print(os.Stdout, pkgs); err != nil {
+		return err
+	}
+
+	for _, name := range missing {
+		text.Errorln(gotext.Get("package '%s' was not found", name))
+	}
+
+	if len(missing) != 0 {
+		return fmt.Errorf("")
+	}
+
+	return nil
+}
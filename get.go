@@ -16,13 +16,25 @@ import (
 )
 
 // yay -Gp.
-func printPkgbuilds(dbExecutor download.DBSearcher, httpClient *http.Client, targets []string,
-	mode parser.TargetMode, aurURL string) error {
-	pkgbuilds, err := download.PKGBUILDs(dbExecutor, httpClient, targets, aurURL, mode)
+func printPkgbuilds(ctx context.Context, dbExecutor download.DBSearcher, httpClient *http.Client, targets []string,
+	mode parser.TargetMode, aurURL string, absURLs download.ABSURLs) error {
+	fromSources, otherTargets := sourceTargets(ctx, dbExecutor, targets)
+
+	pkgbuilds, err := download.PKGBUILDs(dbExecutor, httpClient, otherTargets, aurURL, absURLs, mode)
 	if err != nil {
 		text.Errorln(err)
 	}
 
+	for target, base := range fromSources {
+		pkgbuild, errS := config.Runtime.Sources.PKGBUILD(ctx, base)
+		if errS != nil {
+			text.Errorln(errS)
+			continue
+		}
+
+		pkgbuilds[target] = pkgbuild
+	}
+
 	if len(pkgbuilds) != 0 {
 		for target, pkgbuild := range pkgbuilds {
 			fmt.Printf("\n\n# %s\n\n", target)
@@ -55,12 +67,25 @@ func getPkgbuilds(ctx context.Context, dbExecutor download.DBSearcher,
 		return err
 	}
 
+	fromSources, otherTargets := sourceTargets(ctx, dbExecutor, targets)
+
 	cloned, errD := download.PKGBUILDRepos(ctx, dbExecutor,
-		config.Runtime.CmdBuilder, targets, config.Runtime.Mode, config.AURURL, wd, force)
+		config.Runtime.CmdBuilder, otherTargets, config.Runtime.Mode, config.AURURL, config.ABSURLs,
+		config.FetchOptions(), wd, force)
 	if errD != nil {
 		text.Errorln(errD)
 	}
 
+	for target, base := range fromSources {
+		newClone, errS := config.Runtime.Sources.Download(ctx, base, wd, force)
+		if errS != nil {
+			text.Errorln(errS)
+			continue
+		}
+
+		cloned[target] = newClone
+	}
+
 	if len(targets) != len(cloned) {
 		missing := []string{}
 
@@ -18,13 +18,15 @@ import (
 	"github.com/Jguer/yay/v11/pkg/completion"
 	"github.com/Jguer/yay/v11/pkg/db"
 	"github.com/Jguer/yay/v11/pkg/dep"
-	"github.com/Jguer/yay/v11/pkg/download"
 	"github.com/Jguer/yay/v11/pkg/intrange"
 	"github.com/Jguer/yay/v11/pkg/multierror"
 	"github.com/Jguer/yay/v11/pkg/pgp"
+	"github.com/Jguer/yay/v11/pkg/pin"
 	"github.com/Jguer/yay/v11/pkg/query"
 	"github.com/Jguer/yay/v11/pkg/settings"
+	"github.com/Jguer/yay/v11/pkg/settings/makepkgconf"
 	"github.com/Jguer/yay/v11/pkg/settings/parser"
+	"github.com/Jguer/yay/v11/pkg/sources"
 	"github.com/Jguer/yay/v11/pkg/stringset"
 	"github.com/Jguer/yay/v11/pkg/text"
 )
@@ -81,10 +83,24 @@ func install(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Execu
 		warnings        = query.NewWarnings()
 	)
 
+	pinRequest, err := pinRequestFromArgs(cmdArgs)
+	if err != nil {
+		return err
+	}
+
 	if noDeps {
 		config.Runtime.CmdBuilder.AddMakepkgFlag("-d")
 	}
 
+	if sysupgradeArg && config.CheckNews && config.Runtime.Mode.AtLeastRepo() {
+		if errN := checkNews(ctx, cmdArgs, dbExecutor); errN != nil {
+			return errN
+		}
+
+		// databases may have been refreshed to find pending upgrades
+		refreshArg = cmdArgs.ExistsArg("y", "refresh")
+	}
+
 	if config.Runtime.Mode.AtLeastRepo() {
 		if config.CombinedUpgrade {
 			if refreshArg {
@@ -114,6 +130,7 @@ func install(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Execu
 	localNamesCache := stringset.FromSlice(localNames)
 
 	requestTargets := cmdArgs.Copy().Targets
+	explicitTargets := cmdArgs.Copy().Targets
 
 	// create the arguments to pass for the repo install
 	arguments := cmdArgs.Copy()
@@ -145,8 +162,25 @@ func install(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Execu
 
 	targets := stringset.FromSlice(cmdArgs.Targets)
 
+	var (
+		pinned      pin.Pin
+		pinnedNames []string
+	)
+
+	if pinRequest != nil {
+		var srcinfo *gosrc.Srcinfo
+
+		pinned, srcinfo, err = resolvePinRequest(ctx, config.Runtime.QueryClient, pinRequest)
+		if err != nil {
+			return err
+		}
+
+		// dependencies of the pinned revision may differ from the current ones
+		config.Runtime.QueryClient = sources.NewLocal(config.Runtime.QueryClient, []*gosrc.Srcinfo{srcinfo})
+	}
+
 	dp, err := dep.GetPool(ctx, requestTargets,
-		warnings, dbExecutor, config.Runtime.AURClient, config.Runtime.Mode,
+		warnings, dbExecutor, config.Runtime.QueryClient, config.Runtime.Mode,
 		ignoreProviders, settings.NoConfirm, config.Provides, config.ReBuild, config.RequestSplitN, noDeps, noCheck, assumeInstalled)
 	if err != nil {
 		return err
@@ -200,8 +234,11 @@ func install(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Execu
 	do.Print()
 	fmt.Println()
 
+	// bases built in place with yay -B are not downloaded, diffed or cleaned
+	remote := remoteBases(do.Aur)
+
 	if config.CleanAfter {
-		defer cleanAfter(ctx, do.Aur)
+		defer cleanAfter(ctx, remote)
 	}
 
 	if do.HasMake() {
@@ -223,10 +260,10 @@ func install(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Execu
 	}
 
 	if config.CleanMenu {
-		if anyExistInCache(do.Aur) {
-			askClean := pkgbuildNumberMenu(do.Aur, remoteNamesCache)
+		if anyExistInCache(remote) {
+			askClean := pkgbuildNumberMenu(remote, remoteNamesCache)
 
-			toClean, errClean := cleanNumberMenu(do.Aur, remoteNamesCache, askClean)
+			toClean, errClean := cleanNumberMenu(remote, remoteNamesCache, askClean)
 			if errClean != nil {
 				return errClean
 			}
@@ -235,10 +272,10 @@ func install(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Execu
 		}
 	}
 
-	toSkip := pkgbuildsToSkip(do.Aur, targets)
-	toClone := make([]string, 0, len(do.Aur))
+	toSkip := pkgbuildsToSkip(remote, targets)
+	toClone := make([]string, 0, len(remote))
 
-	for _, base := range do.Aur {
+	for _, base := range remote {
 		if !toSkip.Get(base.Pkgbase()) {
 			toClone = append(toClone, base.Pkgbase())
 		}
@@ -251,24 +288,35 @@ func install(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Execu
 				len(toSkipSlice), len(toClone), text.Cyan(strings.Join(toSkipSlice, ", "))))
 	}
 
-	cloned, errA := download.AURPKGBUILDRepos(ctx,
-		config.Runtime.CmdBuilder, toClone, config.AURURL, config.BuildDir, false)
+	cloned, errA := downloadPKGBUILDRepos(ctx, toClone, config.BuildDir, false)
 	if errA != nil {
 		return errA
 	}
 
+	if pinRequest != nil {
+		var restore func()
+
+		pinnedNames, restore, err = applyPinRequest(ctx,
+			config.Runtime.CmdBuilder, config.BuildDir, remote, pinRequest, pinned)
+		if err != nil {
+			return err
+		}
+
+		defer restore()
+	}
+
 	var toDiff, toEdit []dep.Base
 
 	if config.DiffMenu {
-		pkgbuildNumberMenu(do.Aur, remoteNamesCache)
+		pkgbuildNumberMenu(remote, remoteNamesCache)
 
-		toDiff, err = diffNumberMenu(do.Aur, remoteNamesCache)
+		toDiff, err = diffNumberMenu(remote, remoteNamesCache)
 		if err != nil {
 			return err
 		}
 
 		if len(toDiff) > 0 {
-			err = showPkgbuildDiffs(ctx, toDiff, cloned)
+			err = showPkgbuildDiffs(ctx, toDiff, cloned, pinned)
 			if err != nil {
 				return err
 			}
@@ -293,10 +341,14 @@ func install(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Execu
 		settings.NoConfirm = oldValue
 	}
 
-	if errM := mergePkgbuilds(ctx, do.Aur); errM != nil {
+	if errM := mergePkgbuilds(ctx, remote, pinned); errM != nil {
 		return errM
 	}
 
+	if errP := applyPatches(ctx, remote); errP != nil {
+		return errP
+	}
+
 	srcinfos, err = parseSrcinfoFiles(do.Aur, true)
 	if err != nil {
 		return err
@@ -336,12 +388,22 @@ func install(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Execu
 		return err
 	}
 
+	if errPin := pgp.CheckPins(do.Aur, srcinfos, config.PGPPins); errPin != nil {
+		return errPin
+	}
+
 	if config.PGPFetch {
-		if errCPK := pgp.CheckPgpKeys(do.Aur, srcinfos, config.GpgBin, config.GpgFlags, settings.NoConfirm); errCPK != nil {
+		checked := pgpCheckedBases(do.Aur, config.MakepkgOverrides)
+		if errCPK := pgp.CheckPgpKeys(checked, srcinfos, pgpKeyring(), pgpKeyStore(),
+			pkgbuildSigners, settings.NoConfirm); errCPK != nil {
 			return errCPK
 		}
 	}
 
+	if errD := checkDiskSpace(do.Aur, dbExecutor); errD != nil {
+		return errD
+	}
+
 	if !config.CombinedUpgrade {
 		arguments.DelArg("u", "sysupgrade")
 	}
@@ -383,7 +445,15 @@ func install(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Execu
 			config.AURURL, config.Runtime.CompletionPath, config.CompletionInterval, false)
 	}()
 
-	if errP := downloadPKGBUILDSourceFanout(ctx, config.Runtime.CmdBuilder, config.BuildDir, do.Aur, incompatible); errP != nil {
+	downloadOpts := sourceDownload{
+		cache:     config.SourceCache(),
+		jobs:      config.DownloadJobs,
+		live:      config.LiveProgress(),
+		localDirs: config.Runtime.LocalBuildDirs,
+	}
+
+	if errP := downloadPKGBUILDSourceFanout(ctx, config.Runtime.CmdBuilder,
+		downloadOpts, config.BuildDir, do.Aur, incompatible); errP != nil {
 		text.Errorln(errP)
 	}
 
@@ -391,6 +461,9 @@ func install(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Execu
 		return errB
 	}
 
+	updatePins(pinRequest, pinned, pinnedNames, explicitTargets)
+	updateOrigins(do.Aur)
+
 	return nil
 }
 
@@ -527,9 +600,14 @@ nextpkg:
 	return incompatible, nil
 }
 
-func parsePackageList(ctx context.Context, dir string) (pkgdests map[string]string, pkgVersion string, err error) {
+func parsePackageList(ctx context.Context, pkgbase, dir string,
+	srcinfo *gosrc.Srcinfo) (pkgdests map[string]string, pkgVersion string, err error) {
+	if pkgdests, pkgVersion, ok := predictPackageList(pkgbase, dir, srcinfo); ok {
+		return pkgdests, pkgVersion, nil
+	}
+
 	stdout, stderr, err := config.Runtime.CmdBuilder.Capture(
-		config.Runtime.CmdBuilder.BuildMakepkgCmd(ctx, dir, "--packagelist"))
+		config.Runtime.CmdBuilder.BuildMakepkgBaseCmd(ctx, pkgbase, dir, "--packagelist"))
 	if err != nil {
 		return nil, "", fmt.Errorf("%s %s", stderr, err)
 	}
@@ -560,10 +638,59 @@ func parsePackageList(ctx context.Context, dir string) (pkgdests map[string]stri
 	return pkgdests, pkgVersion, nil
 }
 
+// predictPackageList names the packages built from dir using makepkg.conf and
+// the PKGBUILD instead of running makepkg. ok is false when the names can't
+// be known for sure.
+func predictPackageList(pkgbase, dir string,
+	srcinfo *gosrc.Srcinfo) (pkgdests map[string]string, pkgVersion string, ok bool) {
+	conf := config.MakepkgConfFor(pkgbase)
+	if conf == nil || srcinfo == nil || !conf.Exact() {
+		return nil, "", false
+	}
+
+	info, ok, err := makepkgconf.ReadPKGBUILD(filepath.Join(dir, "PKGBUILD"))
+	if err != nil || !ok {
+		return nil, "", false
+	}
+
+	// .SRCINFO may be out of date, only trust it when it agrees
+	splits := srcinfo.SplitPackages()
+	if len(splits) != len(info.Pkgnames) ||
+		!stringset.Equal(stringset.FromSlice(srcinfo.Arch), stringset.FromSlice(info.Arch)) {
+		return nil, "", false
+	}
+
+	pkgdest := conf.PackageDir(dir)
+	pkgdests = make(map[string]string)
+
+	fileName := func(name string, archs []string) string {
+		arch := conf.CArch
+		if stringset.FromSlice(archs).Get("any") {
+			arch = "any"
+		}
+
+		return filepath.Join(pkgdest, name+"-"+info.Version+"-"+arch+conf.PkgExt)
+	}
+
+	for i, pkg := range splits {
+		if pkg.Pkgname != info.Pkgnames[i] {
+			return nil, "", false
+		}
+
+		pkgdests[pkg.Pkgname] = fileName(pkg.Pkgname, pkg.Arch)
+	}
+
+	if conf.OptionEnabled("debug", srcinfo.Options) && conf.OptionEnabled("strip", srcinfo.Options) {
+		pkgdests[pkgbase+"-debug"] = fileName(pkgbase+"-debug", srcinfo.Arch)
+	}
+
+	return pkgdests, info.Version, true
+}
+
 func anyExistInCache(bases []dep.Base) bool {
 	for _, base := range bases {
 		pkg := base.Pkgbase()
-		dir := filepath.Join(config.BuildDir, pkg)
+		dir := pkgbuildDir(pkg)
 
 		if _, err := os.Stat(dir); !os.IsNotExist(err) {
 			return true
@@ -579,7 +706,7 @@ func pkgbuildNumberMenu(bases []dep.Base, installed stringset.StringSet) bool {
 
 	for n, base := range bases {
 		pkg := base.Pkgbase()
-		dir := filepath.Join(config.BuildDir, pkg)
+		dir := pkgbuildDir(pkg)
 
 		toPrint += fmt.Sprintf(text.Magenta("%3d")+" %-40s", len(bases)-n,
 			text.Bold(base.String()))
@@ -637,7 +764,7 @@ func cleanNumberMenu(bases []dep.Base, installed stringset.StringSet, hasClean b
 				anyInstalled = anyInstalled || installed.Get(b.Name)
 			}
 
-			dir := filepath.Join(config.BuildDir, pkg)
+			dir := pkgbuildDir(pkg)
 			if _, err := os.Stat(dir); os.IsNotExist(err) {
 				continue
 			}
@@ -775,7 +902,7 @@ func editPkgbuilds(bases []dep.Base, srcinfos map[string]*gosrc.Srcinfo) error {
 
 	for _, base := range bases {
 		pkg := base.Pkgbase()
-		dir := filepath.Join(config.BuildDir, pkg)
+		dir := pkgbuildDir(pkg)
 		pkgbuilds = append(pkgbuilds, filepath.Join(dir, "PKGBUILD"))
 
 		for _, splitPkg := range srcinfos[pkg].SplitPackages() {
@@ -804,7 +931,7 @@ func parseSrcinfoFiles(bases []dep.Base, errIsFatal bool) (map[string]*gosrc.Src
 
 	for k, base := range bases {
 		pkg := base.Pkgbase()
-		dir := filepath.Join(config.BuildDir, pkg)
+		dir := pkgbuildDir(pkg)
 
 		text.OperationInfoln(gotext.Get("(%d/%d) Parsing SRCINFO: %s", k+1, len(bases), text.Cyan(base.String())))
 
@@ -837,7 +964,7 @@ func pkgbuildsToSkip(bases []dep.Base, targets stringset.StringSet) stringset.St
 			continue
 		}
 
-		dir := filepath.Join(config.BuildDir, base.Pkgbase(), ".SRCINFO")
+		dir := filepath.Join(pkgbuildDir(base.Pkgbase()), ".SRCINFO")
 		pkgbuild, err := gosrc.ParseFile(dir)
 
 		if err == nil {
@@ -850,8 +977,14 @@ func pkgbuildsToSkip(bases []dep.Base, targets stringset.StringSet) stringset.St
 	return toSkip
 }
 
-func mergePkgbuilds(ctx context.Context, bases []dep.Base) error {
+// mergePkgbuilds fast-forwards the PKGBUILD repositories to upstream, except the
+// one checked out at the pinned revision.
+func mergePkgbuilds(ctx context.Context, bases []dep.Base, pinned pin.Pin) error {
 	for _, base := range bases {
+		if config.Runtime.KeptEdits.Get(base.Pkgbase()) || base.Pkgbase() == pinned.Pkgbase {
+			continue
+		}
+
 		err := gitMerge(ctx, config.BuildDir, base.Pkgbase())
 		if err != nil {
 			return err
@@ -901,7 +1034,7 @@ func buildInstallPkgbuilds(
 
 	for _, base := range do.Aur {
 		pkg := base.Pkgbase()
-		dir := filepath.Join(config.BuildDir, pkg)
+		dir := pkgbuildDir(pkg)
 		built := true
 
 		satisfied := true
@@ -941,11 +1074,11 @@ func buildInstallPkgbuilds(
 
 		// pkgver bump
 		if err = config.Runtime.CmdBuilder.Show(
-			config.Runtime.CmdBuilder.BuildMakepkgCmd(ctx, dir, args...)); err != nil {
+			config.Runtime.CmdBuilder.BuildMakepkgBaseCmd(ctx, pkg, dir, args...)); err != nil {
 			return errors.New(gotext.Get("error making: %s", base.String()))
 		}
 
-		pkgdests, pkgVersion, errList := parsePackageList(ctx, dir)
+		pkgdests, pkgVersion, errList := parsePackageList(ctx, pkg, dir, srcinfo)
 		if errList != nil {
 			return errList
 		}
@@ -980,7 +1113,7 @@ func buildInstallPkgbuilds(
 
 			if installed {
 				err = config.Runtime.CmdBuilder.Show(
-					config.Runtime.CmdBuilder.BuildMakepkgCmd(ctx,
+					config.Runtime.CmdBuilder.BuildMakepkgBaseCmd(ctx, pkg,
 						dir, "-c", "--nobuild", "--noextract", "--ignorearch"))
 				if err != nil {
 					return errors.New(gotext.Get("error making: %s", err))
@@ -994,7 +1127,7 @@ func buildInstallPkgbuilds(
 
 		if built {
 			err = config.Runtime.CmdBuilder.Show(
-				config.Runtime.CmdBuilder.BuildMakepkgCmd(ctx,
+				config.Runtime.CmdBuilder.BuildMakepkgBaseCmd(ctx, pkg,
 					dir, "-c", "--nobuild", "--noextract", "--ignorearch"))
 			if err != nil {
 				return errors.New(gotext.Get("error making: %s", err))
@@ -1009,7 +1142,7 @@ func buildInstallPkgbuilds(
 			}
 
 			if errMake := config.Runtime.CmdBuilder.Show(
-				config.Runtime.CmdBuilder.BuildMakepkgCmd(ctx,
+				config.Runtime.CmdBuilder.BuildMakepkgBaseCmd(ctx, pkg,
 					dir, args...)); errMake != nil {
 				return errors.New(gotext.Get("error making: %s", base.String()))
 			}
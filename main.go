@@ -146,6 +146,10 @@ func main() {
 
 	config.Runtime.CmdBuilder.SetPacmanDBPath(config.Runtime.PacmanConf.DBPath)
 
+	if errM := config.LoadMakepkgConf(); errM != nil {
+		text.Warnln(gotext.Get("failed to read makepkg.conf: %s", errM))
+	}
+
 	text.UseColor = useColor
 
 	dbExecutor, err := ialpm.NewExecutor(config.Runtime.PacmanConf)
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/dep"
+	"github.com/Jguer/yay/v11/pkg/multierror"
+	"github.com/Jguer/yay/v11/pkg/patch"
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+// applyPatches applies the local patches of bases on top of their checkout.
+func applyPatches(ctx context.Context, bases []dep.Base) error {
+	var errs multierror.MultiError
+
+	for _, base := range bases {
+		pkgbase := base.Pkgbase()
+
+		patches, err := patch.Find(config.PatchDir, pkgbase)
+		if err != nil {
+			errs.Add(err)
+			continue
+		}
+
+		if len(patches) == 0 {
+			continue
+		}
+
+		if err := patch.Apply(ctx, config.Runtime.CmdBuilder, pkgbuildDir(pkgbase), pkgbase, patches); err != nil {
+			errs.Add(err)
+			continue
+		}
+
+		text.OperationInfoln(gotext.Get("Applied %d local patches: %s", len(patches), text.Cyan(pkgbase)))
+	}
+
+	return errs.Return()
+}
+
+// resetPatched undoes the patches applied to the checkouts of bases in dest
+// so they can be pulled.
+func resetPatched(ctx context.Context, bases []string, dest string) error {
+	var errs multierror.MultiError
+
+	for _, pkgbase := range bases {
+		dir := filepath.Join(dest, pkgbase)
+		if !isGitRepository(dir) {
+			continue
+		}
+
+		patches, err := patch.Find(config.PatchDir, pkgbase)
+		if err != nil || len(patches) == 0 {
+			errs.Add(err)
+			continue
+		}
+
+		errs.Add(patch.Reset(ctx, config.Runtime.CmdBuilder, dir))
+	}
+
+	return errs.Return()
+}
+
+// showPatches prints the local patches of pkgbase for review.
+func showPatches(pkgbase string) error {
+	patches, err := patch.Find(config.PatchDir, pkgbase)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range patches {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		text.Infoln(gotext.Get("%s: local patch %s", text.Cyan(pkgbase), text.Bold(filepath.Base(path))))
+		fmt.Print(string(content))
+	}
+
+	return nil
+}
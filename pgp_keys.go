@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/dep"
+	"github.com/Jguer/yay/v11/pkg/pgp"
+	"github.com/Jguer/yay/v11/pkg/settings"
+	"github.com/Jguer/yay/v11/pkg/settings/exe"
+	"github.com/Jguer/yay/v11/pkg/settings/parser"
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+// pgpKeyring returns the keyring PKGBUILD signature keys are imported to.
+func pgpKeyring() *pgp.Keyring {
+	return &pgp.Keyring{
+		GpgBin:     config.GpgBin,
+		GpgFlags:   strings.Fields(config.GpgFlags),
+		Home:       config.PGPHomeDir(),
+		Keyservers: config.PGPKeyservers,
+		WKD:        config.PGPWKD,
+	}
+}
+
+// pgpKeyStore returns the package bases requiring each PGP key, empty when
+// they could not be read.
+func pgpKeyStore() *pgp.Store {
+	store := pgp.NewStore(config.Runtime.PGPKeysPath)
+	if err := store.Load(); err != nil {
+		text.Warnln(err)
+	}
+
+	return store
+}
+
+// pkgbuildSigners returns the addresses the PKGBUILD of pkgbase gives for
+// its validpgpkeys.
+func pkgbuildSigners(pkgbase string) map[string][]string {
+	return pgp.SignerEmails(filepath.Join(pkgbuildDir(pkgbase), "PKGBUILD"))
+}
+
+// pgpCheckedBases leaves out of bases the ones makepkg overrides build with
+// --skippgpcheck, their keys are not needed.
+func pgpCheckedBases(bases []dep.Base, overrides []exe.MakepkgOverride) []dep.Base {
+	checked := make([]dep.Base, 0, len(bases))
+
+	for _, base := range bases {
+		if !exe.MergeOverrides(overrides, base.Pkgbase()).SkipPGPCheck {
+			checked = append(checked, base)
+		}
+	}
+
+	return checked
+}
+
+// managedKeys returns the keys of the keyring yay manages: all of them for the
+// dedicated keyring, the ones PKGBUILDs required otherwise.
+func managedKeys(keyring *pgp.Keyring, store *pgp.Store) ([]pgp.Key, error) {
+	if keyring.Home != "" {
+		return keyring.List(nil)
+	}
+
+	fingerprints := store.Fingerprints()
+	if len(fingerprints) == 0 {
+		return []pgp.Key{}, nil
+	}
+
+	return keyring.List(fingerprints)
+}
+
+// handleKeys lists the PGP keys imported for PKGBUILDs with the package bases
+// requiring them, refreshes them with -y or deletes them with --delete.
+func handleKeys(cmdArgs *parser.Arguments) error {
+	keyring := pgpKeyring()
+	store := pgpKeyStore()
+
+	keys, err := managedKeys(keyring, store)
+	if err != nil {
+		return err
+	}
+
+	if cmdArgs.ExistsArg("delete") {
+		return deleteKeys(cmdArgs.Targets, keyring, keys, store)
+	}
+
+	if cmdArgs.ExistsArg("y", "refresh") {
+		fingerprints := cmdArgs.Targets
+		if len(fingerprints) == 0 {
+			for i := range keys {
+				fingerprints = append(fingerprints, keys[i].Fingerprint)
+			}
+		}
+
+		if len(fingerprints) == 0 {
+			text.Infoln(gotext.Get("No keys to refresh."))
+			return nil
+		}
+
+		return keyring.Refresh(fingerprints)
+	}
+
+	printKeys(keys, store)
+
+	return nil
+}
+
+// deleteKeys deletes targets from the keyring and the store after
+// confirmation. Only the keys yay manages can be deleted.
+func deleteKeys(targets []string, keyring *pgp.Keyring, keys []pgp.Key, store *pgp.Store) error {
+	if len(targets) == 0 {
+		return errors.New(gotext.Get("no keys specified"))
+	}
+
+	fingerprints, recorded, err := resolveManagedKeys(targets, keys, store)
+	if err != nil {
+		return err
+	}
+
+	// keys of the store already listed by their fingerprint are not repeated
+	listed := append([]string{}, fingerprints...)
+
+	for _, key := range recorded {
+		inKeyring := false
+		for _, fingerprint := range fingerprints {
+			inKeyring = inKeyring || sameKey(key, fingerprint)
+		}
+
+		if !inKeyring {
+			listed = append(listed, key)
+		}
+	}
+
+	text.Infoln(gotext.Get("Keys to delete:"))
+
+	for _, key := range listed {
+		fmt.Println("  " + key)
+	}
+
+	if !text.ContinueTask(gotext.Get("Delete %d keys?", len(listed)), true, settings.NoConfirm) {
+		return nil
+	}
+
+	if len(fingerprints) > 0 {
+		if err := keyring.Delete(fingerprints); err != nil {
+			return err
+		}
+	}
+
+	return store.Forget(recorded)
+}
+
+// resolveManagedKeys returns the fingerprints of the keys of the keyring and
+// the keys of the store targets name. Each target must be the fingerprint, or
+// a key ID of at least 16 hex digits, of a key yay manages.
+func resolveManagedKeys(targets []string, keys []pgp.Key, store *pgp.Store) (fingerprints, recorded []string, err error) {
+	add := func(list *[]string, key string) {
+		for _, listed := range *list {
+			if listed == key {
+				return
+			}
+		}
+
+		*list = append(*list, key)
+	}
+
+	for _, target := range targets {
+		target = strings.ToUpper(strings.ReplaceAll(target, " ", ""))
+		found := false
+
+		for i := range keys {
+			if sameKey(target, keys[i].Fingerprint) {
+				add(&fingerprints, keys[i].Fingerprint)
+
+				found = true
+			}
+		}
+
+		for _, key := range store.Fingerprints() {
+			if sameKey(target, key) {
+				add(&recorded, key)
+
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, nil, errors.New(gotext.Get("%s is not a key managed by yay", target))
+		}
+	}
+
+	return fingerprints, recorded, nil
+}
+
+// sameKey reports whether the upper case key IDs a and b name the same key,
+// the shorter ending the longer and being at least 16 hex digits long.
+func sameKey(a, b string) bool {
+	const minKeyIDLength = 16
+
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	return len(a) >= minKeyIDLength && strings.HasSuffix(b, a)
+}
+
+func printKeys(keys []pgp.Key, store *pgp.Store) {
+	imported := make(map[string]bool, len(keys))
+
+	for i := range keys {
+		imported[keys[i].Fingerprint] = true
+
+		text.PrintInfoValue(gotext.Get("Fingerprint"), keys[i].Fingerprint)
+		text.PrintInfoValue(gotext.Get("User IDs"), keys[i].UserIDs...)
+
+		if problem := keys[i].Problem(); problem != "" {
+			text.PrintInfoValue(gotext.Get("Status"), text.Red(problem))
+		} else {
+			text.PrintInfoValue(gotext.Get("Status"), gotext.Get("Usable"))
+		}
+
+		text.PrintInfoValue(gotext.Get("Required By"), store.Keys[keys[i].Fingerprint]...)
+		fmt.Println()
+	}
+
+	// keys required by PKGBUILDs that were never imported or were deleted
+	for _, fingerprint := range store.Fingerprints() {
+		if imported[fingerprint] {
+			continue
+		}
+
+		text.PrintInfoValue(gotext.Get("Fingerprint"), fingerprint)
+		text.PrintInfoValue(gotext.Get("User IDs"))
+		text.PrintInfoValue(gotext.Get("Status"), text.Red(gotext.Get("Not in keyring")))
+		text.PrintInfoValue(gotext.Get("Required By"), store.Keys[fingerprint]...)
+		fmt.Println()
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/aur"
+
+	"github.com/Jguer/yay/v11/pkg/dep"
+	"github.com/Jguer/yay/v11/pkg/pgp"
+	"github.com/Jguer/yay/v11/pkg/settings/exe"
+)
+
+func TestPgpCheckedBases(t *testing.T) {
+	t.Parallel()
+
+	bases := []dep.Base{
+		{&aur.Pkg{PackageBase: "yay"}},
+		{&aur.Pkg{PackageBase: "yay-bin"}},
+		{&aur.Pkg{PackageBase: "linux-zen"}},
+	}
+	overrides := []exe.MakepkgOverride{
+		{Pattern: "yay-*", SkipPGPCheck: true},
+		{Pattern: "linux-*", NoCheck: true},
+	}
+
+	assert.Equal(t, []dep.Base{bases[0], bases[2]}, pgpCheckedBases(bases, overrides))
+	assert.Equal(t, bases, pgpCheckedBases(bases, nil))
+}
+
+func TestResolveManagedKeys(t *testing.T) {
+	t.Parallel()
+
+	keys := []pgp.Key{{Fingerprint: "487EACC08557AD082088DABA1EB2638FF56C0C53"}}
+	store := &pgp.Store{Keys: map[string][]string{
+		"487EACC08557AD082088DABA1EB2638FF56C0C53": {"foo"},
+		"8F0871F202119294":                         {"bar"},
+	}}
+
+	testCases := []struct {
+		name             string
+		targets          []string
+		wantFingerprints []string
+		wantRecorded     []string
+		wantErr          bool
+	}{
+		{
+			name:             "fingerprint",
+			targets:          []string{"487eacc08557ad082088daba1eb2638ff56c0c53"},
+			wantFingerprints: []string{"487EACC08557AD082088DABA1EB2638FF56C0C53"},
+			wantRecorded:     []string{"487EACC08557AD082088DABA1EB2638FF56C0C53"},
+		},
+		{
+			name:             "key id",
+			targets:          []string{"1EB2 638F F56C 0C53"},
+			wantFingerprints: []string{"487EACC08557AD082088DABA1EB2638FF56C0C53"},
+			wantRecorded:     []string{"487EACC08557AD082088DABA1EB2638FF56C0C53"},
+		},
+		{
+			name:         "store only",
+			targets:      []string{"11E521D646982372EB577A1F8F0871F202119294"},
+			wantRecorded: []string{"8F0871F202119294"},
+		},
+		{
+			name:    "short key id",
+			targets: []string{"F56C0C53"},
+			wantErr: true,
+		},
+		{
+			name:    "unmanaged key",
+			targets: []string{"ABAF11C65A2970B130ABE3C479BE3E4300411886"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			fingerprints, recorded, err := resolveManagedKeys(tc.targets, keys, store)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantFingerprints, fingerprints)
+			assert.Equal(t, tc.wantRecorded, recorded)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+// commentRegex matches a comment of an aurweb package page: its id, the header
+// with the author and date, and the rendered content.
+var commentRegex = regexp.MustCompile(`(?s)<h4 id="comment-(\d+)" class="comment-header">(.*?)</h4>` +
+	`\s*<div id="comment-\d+-content" class="article-content">(.*?)</div>\s*</div>`)
+
+// Comment is a comment of an AUR package base.
+type Comment struct {
+	ID     string
+	Header string
+	Body   string
+	Pinned bool
+}
+
+// URL returns the page of pkgbase on the aurweb instance at aurURL.
+func URL(aurURL, pkgbase string) string {
+	return strings.TrimRight(aurURL, "/") + "/pkgbase/" + url.PathEscape(pkgbase)
+}
+
+// Fetch downloads the package page of pkgbase and returns its pinned comments
+// followed by the latest ones.
+func Fetch(ctx context.Context, client *http.Client, aurURL, pkgbase string) ([]Comment, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", URL(aurURL, pkgbase), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseComments(string(body)), nil
+}
+
+// parseComments extracts the comments of a package page. Comments found
+// before the "Latest Comments" section are the pinned ones.
+func parseComments(page string) []Comment {
+	latest := strings.Index(page, "Latest Comments")
+	if latest < 0 {
+		latest = len(page)
+	}
+
+	comments := make([]Comment, 0)
+	seen := make(map[string]bool)
+
+	for _, match := range commentRegex.FindAllStringSubmatchIndex(page, -1) {
+		id := page[match[2]:match[3]]
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+
+		comments = append(comments, Comment{
+			ID:     id,
+			Header: strings.Join(strings.Fields(toText(page[match[4]:match[5]])), " "),
+			Body:   toText(page[match[6]:match[7]]),
+			Pinned: match[0] < latest,
+		})
+	}
+
+	return comments
+}
+
+// toText converts a fragment of a page to trimmed terminal text.
+func toText(fragment string) string {
+	return strings.TrimSpace(strings.TrimSuffix(text.ParseHTML(fragment), text.ResetCode))
+}
+
+func (c *Comment) print() {
+	fmt.Println(text.Bold(c.Header))
+
+	for _, line := range strings.Split(c.Body, "\n") {
+		fmt.Println("    " + strings.TrimRight(line, " \t"))
+	}
+
+	fmt.Println()
+}
+
+// Print prints the pinned comments followed by at most limit of the latest
+// comments.
+func Print(comments []Comment, limit int) {
+	pinned := make([]*Comment, 0)
+	latest := make([]*Comment, 0, limit)
+
+	for i := range comments {
+		switch {
+		case comments[i].Pinned:
+			pinned = append(pinned, &comments[i])
+		case len(latest) < limit:
+			latest = append(latest, &comments[i])
+		}
+	}
+
+	if len(pinned) != 0 {
+		text.OperationInfoln(gotext.Get("Pinned Comments"))
+
+		for _, c := range pinned {
+			c.print()
+		}
+	}
+
+	text.OperationInfoln(gotext.Get("Latest Comments"))
+
+	if len(latest) == 0 {
+		fmt.Println(gotext.Get("No comments"))
+		fmt.Println()
+	}
+
+	for _, c := range latest {
+		c.print()
+	}
+}
@@ -0,0 +1,152 @@
+package comments
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+const samplePage = `<div id="pkgdetails" class="box">
+<h2>Package Details: yay 11.0.2-1</h2>
+</div>
+<div class="comments package-comments">
+  <div class="comments-header">
+    <h3>
+      <span class="text">Pinned Comments</span>
+    </h3>
+  </div>
+  <h4 id="comment-800" class="comment-header">
+    jguer commented on
+    <a href="#comment-800" class="date">2021-01-10 12:00 (UTC)</a>
+  </h4>
+  <div id="comment-800-content" class="article-content">
+    <div>
+      <p>Run <code>yay -Syu --devel</code> after &quot;go&quot; updates.</p>
+    </div>
+  </div>
+</div>
+<div class="comments package-comments">
+  <div class="comments-header">
+    <h3>
+      <span class="text">Latest Comments</span>
+    </h3>
+  </div>
+  <h4 id="comment-812" class="comment-header">
+    alice commented on
+    <a href="#comment-812" class="date">2021-03-04 10:00 (UTC)</a>
+  </h4>
+  <div id="comment-812-content" class="article-content">
+    <div>
+      <p>Builds fine.</p>
+    </div>
+  </div>
+  <h4 id="comment-811" class="comment-header">
+    bob commented on
+    <a href="#comment-811" class="date">2021-03-01 09:00 (UTC)</a>
+    <span class="edited">(edited on 2021-03-01 09:30 (UTC) by bob)</span>
+  </h4>
+  <div id="comment-811-content" class="article-content">
+    <div>
+      <p>First line<br />
+second line</p>
+    </div>
+  </div>
+</div>`
+
+func TestParseComments(t *testing.T) {
+	t.Parallel()
+
+	got := parseComments(samplePage)
+
+	assert.Len(t, got, 3)
+
+	assert.Equal(t, "800", got[0].ID)
+	assert.True(t, got[0].Pinned)
+	assert.Equal(t, "jguer commented on 2021-01-10 12:00 (UTC)", got[0].Header)
+	assert.Equal(t, "Run "+text.CyanCode+"yay -Syu --devel"+text.ResetCode+` after "go" updates.`, got[0].Body)
+
+	assert.Equal(t, "812", got[1].ID)
+	assert.False(t, got[1].Pinned)
+
+	assert.Equal(t, "811", got[2].ID)
+	assert.False(t, got[2].Pinned)
+	assert.Contains(t, got[2].Header, "(edited on 2021-03-01 09:30 (UTC) by bob)")
+	assert.Contains(t, got[2].Body, "First line\nsecond line")
+}
+
+func TestParseCommentsEscapes(t *testing.T) {
+	t.Parallel()
+
+	page := `<h4 id="comment-900" class="comment-header">mallory&#27;]0;pwned&#7; commented</h4>
+  <div id="comment-900-content" class="article-content">
+    <div>
+      <p>` + "\x1b[2J\x1b]8;;https://example.com\x1b\\" + `click&#x1b;[0m</p>
+    </div>
+  </div>`
+
+	got := parseComments(page)
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, "mallory]0;pwned commented", got[0].Header)
+	assert.Equal(t, `[2J]8;;https://example.com\click[0m`, got[0].Body)
+	assert.NotContains(t, got[0].Header+got[0].Body, "\x1b")
+}
+
+func TestParseCommentsEmpty(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, parseComments(`<div id="pkgdetails" class="box"></div>`))
+}
+
+func TestURL(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "https://aur.archlinux.org/pkgbase/yay", URL("https://aur.archlinux.org/", "yay"))
+	assert.Equal(t, "http://localhost:8080/pkgbase/c++-lib", URL("http://localhost:8080", "c++-lib"))
+}
+
+func TestFetch(t *testing.T) {
+	gock.New("http://localhost:8080").
+		Get("/pkgbase/yay").
+		Reply(200).
+		BodyString(samplePage)
+	gock.New("http://localhost:8080").
+		Get("/pkgbase/missing").
+		Reply(404)
+
+	defer gock.Off()
+
+	got, err := Fetch(context.Background(), &http.Client{}, "http://localhost:8080", "yay")
+	assert.NoError(t, err)
+	assert.Len(t, got, 3)
+
+	_, err = Fetch(context.Background(), &http.Client{}, "http://localhost:8080", "missing")
+	assert.Error(t, err)
+}
+
+func TestPrint(t *testing.T) {
+	rescueStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	Print(parseComments(samplePage), 1)
+
+	w.Close()
+	out, _ := io.ReadAll(r)
+	os.Stdout = rescueStdout
+
+	output := string(out)
+	assert.Contains(t, output, "Pinned Comments")
+	assert.Contains(t, output, "jguer commented on")
+	assert.Contains(t, output, "alice commented on")
+	assert.NotContains(t, output, "bob commented on")
+	assert.Less(t, strings.Index(output, "jguer"), strings.Index(output, "alice"))
+}
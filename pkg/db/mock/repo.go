@@ -11,6 +11,7 @@ type Package struct {
 	PBuildDate    time.Time
 	PDB           alpm.IDB
 	PDescription  string
+	PInstallDate  time.Time
 	PISize        int64
 	PName         string
 	PShouldIgnore bool
@@ -123,7 +124,7 @@ func (p *Package) Groups() alpm.StringList {
 
 // InstallDate returns the package install date.
 func (p *Package) InstallDate() time.Time {
-	panic("not implemented") // TODO: Implement
+	return p.PInstallDate
 }
 
 // Licenses returns the package license list.
@@ -186,3 +187,16 @@ func (p *Package) SyncNewVersion(l alpm.IDBList) alpm.IPackage {
 func (p *Package) Type() string {
 	panic("not implemented") // TODO: Implement
 }
+
+type DB struct {
+	alpm.IDB
+	name string
+}
+
+func NewDB(name string) *DB {
+	return &DB{name: name}
+}
+
+func (d *DB) Name() string {
+	return d.name
+}
@@ -62,10 +62,10 @@ type Pool struct {
 	Groups       []string
 	AlpmExecutor db.Executor
 	Warnings     *query.AURWarnings
-	aurClient    *aur.Client
+	aurClient    aur.ClientInterface
 }
 
-func makePool(dbExecutor db.Executor, aurClient *aur.Client) *Pool {
+func makePool(dbExecutor db.Executor, aurClient aur.ClientInterface) *Pool {
 	dp := &Pool{
 		Targets:      []Target{},
 		Explicit:     map[string]struct{}{},
@@ -390,7 +390,7 @@ func (dp *Pool) ResolveRepoDependency(pkg db.IPackage, noDeps bool) {
 func GetPool(ctx context.Context, pkgs []string,
 	warnings *query.AURWarnings,
 	dbExecutor db.Executor,
-	aurClient *aur.Client,
+	aurClient aur.ClientInterface,
 	mode parser.TargetMode,
 	ignoreProviders, noConfirm, provides bool,
 	rebuild string, splitN int, noDeps bool, noCheckDeps bool, assumeInstalled []string) (*Pool, error) {
@@ -0,0 +1,222 @@
+// Package diskspace estimates the disk space builds need and compares it with
+// the free space of the filesystems they write to.
+package diskspace
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// MinimumBuild is the space assumed for each of the build, sources and
+// packages of a package base that was never built nor installed.
+const MinimumBuild int64 = 256 << 20
+
+// Estimate is the space a package base is expected to take in each location.
+type Estimate struct {
+	Pkgbase  string
+	BuildDir string
+	Build    int64
+	SrcDest  string
+	Sources  int64
+	PkgDest  string
+	Packages int64
+}
+
+// Volume is a filesystem along with the space the planned builds need on it.
+type Volume struct {
+	Paths    []string
+	Pkgbases []string
+	Free     int64
+	Required int64
+}
+
+// Short reports whether the volume lacks the space required.
+func (v *Volume) Short() bool {
+	return v.Required > v.Free
+}
+
+// Plan sums estimates per filesystem.
+type Plan struct {
+	volumes map[uint64]*Volume
+	order   []uint64
+}
+
+// NewPlan returns an empty plan.
+func NewPlan() *Plan {
+	return &Plan{volumes: make(map[uint64]*Volume)}
+}
+
+// Add charges the estimate to the filesystems of its locations.
+func (p *Plan) Add(estimate *Estimate) error {
+	for _, use := range []struct {
+		path string
+		size int64
+	}{
+		{estimate.BuildDir, estimate.Build},
+		{estimate.SrcDest, estimate.Sources},
+		{estimate.PkgDest, estimate.Packages},
+	} {
+		if use.path == "" {
+			continue
+		}
+
+		if err := p.add(estimate.Pkgbase, use.path, use.size); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Plan) add(pkgbase, path string, size int64) error {
+	existing, err := existingParent(path)
+	if err != nil {
+		return err
+	}
+
+	var stat unix.Stat_t
+	if err := unix.Stat(existing, &stat); err != nil {
+		return &os.PathError{Op: "stat", Path: existing, Err: err}
+	}
+
+	dev := stat.Dev
+
+	volume, ok := p.volumes[dev]
+	if !ok {
+		var statfs unix.Statfs_t
+		if err := unix.Statfs(existing, &statfs); err != nil {
+			return &os.PathError{Op: "statfs", Path: existing, Err: err}
+		}
+
+		volume = &Volume{Free: int64(statfs.Bavail) * int64(statfs.Bsize)}
+		p.volumes[dev] = volume
+		p.order = append(p.order, dev)
+	}
+
+	volume.Required += size
+
+	if !contains(volume.Paths, path) {
+		volume.Paths = append(volume.Paths, path)
+	}
+
+	if size > 0 && !contains(volume.Pkgbases, pkgbase) {
+		volume.Pkgbases = append(volume.Pkgbases, pkgbase)
+	}
+
+	return nil
+}
+
+// Volumes returns the filesystems in the order they were first used.
+func (p *Plan) Volumes() []*Volume {
+	volumes := make([]*Volume, 0, len(p.order))
+	for _, dev := range p.order {
+		volumes = append(volumes, p.volumes[dev])
+	}
+
+	return volumes
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// existingParent returns path or its closest parent that exists, as
+// directories are only created when the build starts.
+func existingParent(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		_, err := os.Stat(path)
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return path, err
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path, err
+		}
+
+		path = parent
+	}
+}
+
+// DirSize returns the space taken by the files under dir, 0 if it does not
+// exist. Symbolic links are not followed.
+func DirSize(dir string) int64 {
+	var size int64
+
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if info, errInfo := d.Info(); errInfo == nil && info.Mode().IsRegular() {
+			size += fileSize(info)
+		}
+
+		return nil
+	})
+
+	return size
+}
+
+// fileSize returns the space allocated to the file, which is less than its
+// length for sparse files.
+func fileSize(info fs.FileInfo) int64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Blocks*512 < info.Size() {
+		return stat.Blocks * 512
+	}
+
+	return info.Size()
+}
+
+// PackagesSize returns the size of the largest package file in dir built
+// for each of names, as the last builds hint at the size of the next ones.
+func PackagesSize(dir string, names []string) int64 {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+
+	largest := make(map[string]int64, len(names))
+	for _, name := range names {
+		largest[name] = 0
+	}
+
+	for _, entry := range entries {
+		// pkgname-pkgver-pkgrel-arch.pkgext
+		fields := strings.Split(entry.Name(), "-")
+		if len(fields) < 4 || !strings.Contains(fields[len(fields)-1], ".pkg.tar") {
+			continue
+		}
+
+		name := strings.Join(fields[:len(fields)-3], "-")
+		if size, ok := largest[name]; ok {
+			if info, err := entry.Info(); err == nil && info.Mode().IsRegular() && info.Size() > size {
+				largest[name] = info.Size()
+			}
+		}
+	}
+
+	var total int64
+	for _, size := range largest {
+		total += size
+	}
+
+	return total
+}
@@ -0,0 +1,71 @@
+package diskspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeSized(t *testing.T, path string, size int) {
+	t.Helper()
+
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.NoError(t, os.WriteFile(path, make([]byte, size), 0o644))
+}
+
+func TestDirSize(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeSized(t, filepath.Join(dir, "a"), 100)
+	writeSized(t, filepath.Join(dir, "sub", "b"), 50)
+	assert.NoError(t, os.Symlink(filepath.Join(dir, "a"), filepath.Join(dir, "link")))
+
+	assert.Equal(t, int64(150), DirSize(dir))
+	assert.Equal(t, int64(0), DirSize(filepath.Join(dir, "missing")))
+}
+
+func TestPackagesSize(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeSized(t, filepath.Join(dir, "foo-1.0-1-x86_64.pkg.tar.zst"), 100)
+	writeSized(t, filepath.Join(dir, "foo-1.1-1-x86_64.pkg.tar.zst"), 120)
+	writeSized(t, filepath.Join(dir, "foo-docs-1.1-1-any.pkg.tar.zst"), 30)
+	writeSized(t, filepath.Join(dir, "foo-1.1-1-x86_64.pkg.tar.zst.sig"), 5)
+	writeSized(t, filepath.Join(dir, "bar-1.0-1-any.pkg.tar.xz"), 1000)
+
+	assert.Equal(t, int64(120), PackagesSize(dir, []string{"foo"}))
+	assert.Equal(t, int64(150), PackagesSize(dir, []string{"foo", "foo-docs"}))
+	assert.Equal(t, int64(0), PackagesSize(dir, []string{"baz"}))
+	assert.Equal(t, int64(0), PackagesSize(filepath.Join(dir, "missing"), []string{"foo"}))
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	plan := NewPlan()
+
+	assert.NoError(t, plan.Add(&Estimate{
+		Pkgbase:  "foo",
+		BuildDir: dir,
+		Build:    100,
+		PkgDest:  filepath.Join(dir, "not", "created", "yet"),
+		Packages: 10,
+	}))
+	assert.NoError(t, plan.Add(&Estimate{Pkgbase: "bar", BuildDir: dir, Build: 0}))
+
+	volumes := plan.Volumes()
+	assert.Len(t, volumes, 1)
+	assert.Equal(t, int64(110), volumes[0].Required)
+	assert.Equal(t, []string{dir, filepath.Join(dir, "not", "created", "yet")}, volumes[0].Paths)
+	assert.Equal(t, []string{"foo"}, volumes[0].Pkgbases)
+	assert.Greater(t, volumes[0].Free, int64(0))
+	assert.False(t, volumes[0].Short())
+
+	volumes[0].Required = volumes[0].Free + 1
+	assert.True(t, volumes[0].Short())
+}
@@ -3,63 +3,145 @@ package download
 import (
 	"context"
 	"errors"
-	"fmt"
 	"io"
 	"net/http"
+	"regexp"
+	"strings"
 
 	"github.com/leonelquinteros/gotext"
 
 	"github.com/Jguer/yay/v11/pkg/settings/exe"
 )
 
-const (
-	MaxConcurrentFetch = 20
-	_urlPackagePath    = "%s/raw/packages/%s/trunk/PKGBUILD"
-)
+const MaxConcurrentFetch = 20
 
 var (
 	ErrInvalidRepository  = errors.New(gotext.Get("invalid repository"))
 	ErrABSPackageNotFound = errors.New(gotext.Get("package not found in repos"))
-	ABSPackageURL         = "https://github.com/archlinux/svntogit-packages"
-	ABSCommunityURL       = "https://github.com/archlinux/svntogit-community"
 )
 
-func getRepoURL(db string) (string, error) {
-	switch db {
-	case "core", "extra", "testing":
-		return ABSPackageURL, nil
-	case "community", "multilib", "community-testing", "multilib-testing":
-		return ABSCommunityURL, nil
+// ABSTemplate holds the URL templates of the PKGBUILDs of a distribution.
+// {pkgbase} is replaced by the package base, {project} by the package base
+// escaped as a GitLab project name and {repo} by the repository name.
+// Example:
+//
+//	"arch": {
+//		"repo": "https://gitlab.archlinux.org/archlinux/packaging/packages/{project}.git",
+//		"pkgbuild": "https://gitlab.archlinux.org/archlinux/packaging/packages/{project}/-/raw/main/PKGBUILD",
+//		"branch": ""
+//	}
+type ABSTemplate struct {
+	// Repo is the git repository cloned by -G.
+	Repo string `json:"repo"`
+	// PKGBUILD is the raw PKGBUILD printed by -Gp.
+	PKGBUILD string `json:"pkgbuild"`
+	// Branch is the branch cloned by -G, the default branch when empty.
+	Branch string `json:"branch"`
+}
+
+// ABSURLs maps repositories to the templates of their PKGBUILD URLs.
+type ABSURLs struct {
+	// Templates holds the URL templates by name.
+	Templates map[string]ABSTemplate `json:"templates"`
+	// Repos maps repository names to the name of their template.
+	Repos map[string]string `json:"repos"`
+}
+
+// DefaultABSTemplates returns the templates of Arch Linux's GitLab packaging layout.
+func DefaultABSTemplates() map[string]ABSTemplate {
+	return map[string]ABSTemplate{
+		"arch": {
+			Repo:     "https://gitlab.archlinux.org/archlinux/packaging/packages/{project}.git",
+			PKGBUILD: "https://gitlab.archlinux.org/archlinux/packaging/packages/{project}/-/raw/main/PKGBUILD",
+		},
+	}
+}
+
+// DefaultABSRepos returns the official Arch Linux repositories mapped to the
+// arch template.
+func DefaultABSRepos() map[string]string {
+	repos := make(map[string]string)
+
+	for _, repo := range []string{
+		"core", "extra", "multilib",
+		"core-testing", "extra-testing", "multilib-testing",
+		"core-staging", "extra-staging", "multilib-staging",
+		"gnome-unstable", "kde-unstable",
+		"testing", "community", "community-testing",
+	} {
+		repos[repo] = "arch"
+	}
+
+	return repos
+}
+
+var (
+	gitlabJoinedPlus  = regexp.MustCompile(`([a-zA-Z0-9]+)\+([a-zA-Z]+)`)
+	gitlabPlus        = regexp.MustCompile(`\+`)
+	gitlabInvalid     = regexp.MustCompile(`[^a-zA-Z0-9_\-.]`)
+	gitlabSeparators  = regexp.MustCompile(`[_\-]{2,}`)
+	gitlabReservedCmd = regexp.MustCompile(`^tree$`)
+)
+
+// gitlabProject returns the GitLab project name of pkgbase, following the
+// naming rules of Arch Linux's packaging repositories.
+func gitlabProject(pkgbase string) string {
+	project := gitlabJoinedPlus.ReplaceAllString(pkgbase, "$1-$2")
+	project = gitlabPlus.ReplaceAllString(project, "plus")
+	project = gitlabInvalid.ReplaceAllString(project, "-")
+	project = gitlabSeparators.ReplaceAllString(project, "-")
+
+	return gitlabReservedCmd.ReplaceAllString(project, "unix-tree")
+}
+
+// template returns the URL templates of db.
+func (u ABSURLs) template(db string) (ABSTemplate, error) {
+	name, ok := u.Repos[db]
+	if !ok {
+		return ABSTemplate{}, ErrInvalidRepository
 	}
 
-	return "", ErrInvalidRepository
+	tmpl, ok := u.Templates[name]
+	if !ok {
+		return ABSTemplate{}, ErrInvalidRepository
+	}
+
+	return tmpl, nil
+}
+
+func expandABSTemplate(tmpl, db, pkgName string) string {
+	return strings.NewReplacer(
+		"{pkgbase}", pkgName,
+		"{project}", gitlabProject(pkgName),
+		"{repo}", db,
+	).Replace(tmpl)
 }
 
 // Return format for pkgbuild
-// https://github.com/archlinux/svntogit-community/raw/packages/neovim/trunk/PKGBUILD
-func getPackageURL(db, pkgName string) (string, error) {
-	repoURL, err := getRepoURL(db)
-	if err != nil {
-		return "", err
+// https://gitlab.archlinux.org/archlinux/packaging/packages/neovim/-/raw/main/PKGBUILD
+func getPackageURL(urls ABSURLs, db, pkgName string) (string, error) {
+	tmpl, err := urls.template(db)
+	if err != nil || tmpl.PKGBUILD == "" {
+		return "", ErrInvalidRepository
 	}
 
-	return fmt.Sprintf(_urlPackagePath, repoURL, pkgName), err
+	return expandABSTemplate(tmpl.PKGBUILD, db, pkgName), nil
 }
 
 // Return format for pkgbuild repo
-// https://github.com/archlinux/svntogit-community.git
-func getPackageRepoURL(db string) (string, error) {
-	repoURL, err := getRepoURL(db)
-	if err != nil {
-		return "", err
+// https://gitlab.archlinux.org/archlinux/packaging/packages/neovim.git
+func getPackageRepoURL(urls ABSURLs, db, pkgName string) (url, branch string, err error) {
+	tmpl, err := urls.template(db)
+	if err != nil || tmpl.Repo == "" {
+		return "", "", ErrInvalidRepository
 	}
 
-	return repoURL + ".git", err
+	return expandABSTemplate(tmpl.Repo, db, pkgName), expandABSTemplate(tmpl.Branch, db, pkgName), nil
 }
 
 // ABSPKGBUILD retrieves the PKGBUILD file to a dest directory.
-func ABSPKGBUILD(httpClient httpRequestDoer, dbName, pkgName string) ([]byte, error) {
-	packageURL, err := getPackageURL(dbName, pkgName)
+func ABSPKGBUILD(httpClient httpRequestDoer, urls ABSURLs, dbName, pkgName string) ([]byte, error) {
+	packageURL, err := getPackageURL(urls, dbName, pkgName)
 	if err != nil {
 		return nil, err
 	}
@@ -83,14 +165,14 @@ func ABSPKGBUILD(httpClient httpRequestDoer, dbName, pkgName string) ([]byte, er
 	return pkgBuild, nil
 }
 
-// ABSPKGBUILDRepo retrieves the PKGBUILD repository to a dest directory.
-func ABSPKGBUILDRepo(ctx context.Context, cmdBuilder exe.GitCmdBuilder,
+// ABSPKGBUILDRepo retrieves the PKGBUILD repository to a dest directory,
+// retrying network failures. The AUR mirrors of fetch are not used.
+func ABSPKGBUILDRepo(ctx context.Context, cmdBuilder exe.GitCmdBuilder, urls ABSURLs, fetch FetchOptions,
 	dbName, pkgName, dest string, force bool) (bool, error) {
-	pkgURL, err := getPackageRepoURL(dbName)
+	pkgURL, branch, err := getPackageRepoURL(urls, dbName, pkgName)
 	if err != nil {
 		return false, err
 	}
 
-	return downloadGitRepo(ctx, cmdBuilder, pkgURL,
-		pkgName, dest, force, "--single-branch", "-b", "packages/"+pkgName)
+	return fetchWithRetries(ctx, cmdBuilder, fetch, endpoint{url: pkgURL, branch: branch}, pkgName, dest, force)
 }
@@ -34,6 +34,21 @@ package() {
     install -Dm644 LICENSE "${pkgdir}/usr/share/licenses/${pkgname}/LICENSE"
 }`
 
+var defaultABSURLs = ABSURLs{Templates: DefaultABSTemplates(), Repos: DefaultABSRepos()}
+
+// testABSURLs returns the default URLs with an internal mirror added.
+func testABSURLs() ABSURLs {
+	urls := ABSURLs{Templates: DefaultABSTemplates(), Repos: DefaultABSRepos()}
+	urls.Templates["mirror"] = ABSTemplate{
+		Repo:     "https://git.example.com/{repo}.git",
+		PKGBUILD: "https://git.example.com/{repo}/{pkgbase}/PKGBUILD",
+		Branch:   "packages/{pkgbase}",
+	}
+	urls.Repos["internal"] = "mirror"
+
+	return urls
+}
+
 func Test_getPackageURL(t *testing.T) {
 	t.Parallel()
 	type args struct {
@@ -52,7 +67,7 @@ func Test_getPackageURL(t *testing.T) {
 				db:      "community",
 				pkgName: "kitty",
 			},
-			want:    "https://github.com/archlinux/svntogit-community/raw/packages/kitty/trunk/PKGBUILD",
+			want:    "https://gitlab.archlinux.org/archlinux/packaging/packages/kitty/-/raw/main/PKGBUILD",
 			wantErr: false,
 		},
 		{
@@ -61,7 +76,25 @@ func Test_getPackageURL(t *testing.T) {
 				db:      "core",
 				pkgName: "linux",
 			},
-			want:    "https://github.com/archlinux/svntogit-packages/raw/packages/linux/trunk/PKGBUILD",
+			want:    "https://gitlab.archlinux.org/archlinux/packaging/packages/linux/-/raw/main/PKGBUILD",
+			wantErr: false,
+		},
+		{
+			name: "escaped project name",
+			args: args{
+				db:      "extra",
+				pkgName: "libsigc++",
+			},
+			want:    "https://gitlab.archlinux.org/archlinux/packaging/packages/libsigcplusplus/-/raw/main/PKGBUILD",
+			wantErr: false,
+		},
+		{
+			name: "mirror package",
+			args: args{
+				db:      "internal",
+				pkgName: "linux",
+			},
+			want:    "https://git.example.com/internal/linux/PKGBUILD",
 			wantErr: false,
 		},
 		{
@@ -78,7 +111,7 @@ func Test_getPackageURL(t *testing.T) {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			got, err := getPackageURL(tt.args.db, tt.args.pkgName)
+			got, err := getPackageURL(testABSURLs(), tt.args.db, tt.args.pkgName)
 			if tt.wantErr {
 				assert.ErrorIs(t, err, ErrInvalidRepository)
 			}
@@ -110,7 +143,7 @@ func TestGetABSPkgbuild(t *testing.T) {
 				body:    gitExtrasPKGBUILD,
 				status:  200,
 				pkgName: "git-extras",
-				wantURL: "https://github.com/archlinux/svntogit-packages/raw/packages/git-extras/trunk/PKGBUILD",
+				wantURL: "https://gitlab.archlinux.org/archlinux/packaging/packages/git-extras/-/raw/main/PKGBUILD",
 			},
 			want:    gitExtrasPKGBUILD,
 			wantErr: false,
@@ -122,7 +155,7 @@ func TestGetABSPkgbuild(t *testing.T) {
 				body:    "",
 				status:  404,
 				pkgName: "git-git",
-				wantURL: "https://github.com/archlinux/svntogit-packages/raw/packages/git-git/trunk/PKGBUILD",
+				wantURL: "https://gitlab.archlinux.org/archlinux/packaging/packages/git-git/-/raw/main/PKGBUILD",
 			},
 			want:    "",
 			wantErr: true,
@@ -138,7 +171,7 @@ func TestGetABSPkgbuild(t *testing.T) {
 				body:    tt.args.body,
 				status:  tt.args.status,
 			}
-			got, err := ABSPKGBUILD(httpClient, tt.args.dbName, tt.args.pkgName)
+			got, err := ABSPKGBUILD(httpClient, defaultABSURLs, tt.args.dbName, tt.args.pkgName)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -154,29 +187,38 @@ func Test_getPackageRepoURL(t *testing.T) {
 	t.Parallel()
 
 	type args struct {
-		db string
+		db      string
+		pkgName string
 	}
 	tests := []struct {
-		name    string
-		args    args
-		want    string
-		wantErr bool
+		name       string
+		args       args
+		want       string
+		wantBranch string
+		wantErr    bool
 	}{
 		{
 			name:    "community package",
-			args:    args{db: "community"},
-			want:    "https://github.com/archlinux/svntogit-community.git",
+			args:    args{db: "community", pkgName: "kitty"},
+			want:    "https://gitlab.archlinux.org/archlinux/packaging/packages/kitty.git",
 			wantErr: false,
 		},
 		{
 			name:    "core package",
-			args:    args{db: "core"},
-			want:    "https://github.com/archlinux/svntogit-packages.git",
+			args:    args{db: "core", pkgName: "linux"},
+			want:    "https://gitlab.archlinux.org/archlinux/packaging/packages/linux.git",
 			wantErr: false,
 		},
+		{
+			name:       "mirror package",
+			args:       args{db: "internal", pkgName: "linux"},
+			want:       "https://git.example.com/internal.git",
+			wantBranch: "packages/linux",
+			wantErr:    false,
+		},
 		{
 			name:    "personal repo package",
-			args:    args{db: "sweswe"},
+			args:    args{db: "sweswe", pkgName: "linux"},
 			want:    "",
 			wantErr: true,
 		},
@@ -185,28 +227,45 @@ func Test_getPackageRepoURL(t *testing.T) {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			got, err := getPackageRepoURL(tt.args.db)
+			got, branch, err := getPackageRepoURL(testABSURLs(), tt.args.db, tt.args.pkgName)
 			if tt.wantErr {
 				assert.ErrorIs(t, err, ErrInvalidRepository)
 			}
 			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantBranch, branch)
 		})
 	}
 }
 
+func Test_gitlabProject(t *testing.T) {
+	t.Parallel()
+
+	for pkgbase, want := range map[string]string{
+		"linux":       "linux",
+		"libsigc++":   "libsigcplusplus",
+		"gtk2+extra":  "gtk2-extra",
+		"tree":        "unix-tree",
+		"foo__bar":    "foo-bar",
+		"python-pip":  "python-pip",
+		"dvd+rw-tool": "dvd-rw-tool",
+	} {
+		assert.Equal(t, want, gitlabProject(pkgbase), pkgbase)
+	}
+}
+
 // GIVEN no previous existing folder
 // WHEN ABSPKGBUILDRepo is called
 // THEN a clone command should be formed
 func TestABSPKGBUILDRepo(t *testing.T) {
 	t.Parallel()
 	cmdRunner := &testRunner{}
-	want := "/usr/local/bin/git --no-replace-objects -C /tmp/doesnt-exist clone --no-progress --single-branch -b packages/linux https://github.com/archlinux/svntogit-packages.git linux"
+	want := "/usr/local/bin/git --no-replace-objects -C /tmp/doesnt-exist clone --no-progress https://gitlab.archlinux.org/archlinux/packaging/packages/linux.git linux"
 	if os.Getuid() == 0 {
 		ld := "systemd-run"
 		if path, _ := exec.LookPath(ld); path != "" {
 			ld = path
 		}
-		want = fmt.Sprintf("%s --service-type=oneshot --pipe --wait --pty -p DynamicUser=yes -p CacheDirectory=yay -E HOME=/tmp  --no-replace-objects -C /tmp/doesnt-exist clone --no-progress --single-branch -b packages/linux https://github.com/archlinux/svntogit-packages.git linux", ld)
+		want = fmt.Sprintf("%s --service-type=oneshot --pipe --wait --pty -p DynamicUser=yes -p CacheDirectory=yay -E HOME=/tmp  --no-replace-objects -C /tmp/doesnt-exist clone --no-progress https://gitlab.archlinux.org/archlinux/packaging/packages/linux.git linux", ld)
 	}
 
 	cmdBuilder := &testGitBuilder{
@@ -219,7 +278,7 @@ func TestABSPKGBUILDRepo(t *testing.T) {
 			GitFlags: []string{"--no-replace-objects"},
 		},
 	}
-	newClone, err := ABSPKGBUILDRepo(context.TODO(), cmdBuilder, "core", "linux", "/tmp/doesnt-exist", false)
+	newClone, err := ABSPKGBUILDRepo(context.TODO(), cmdBuilder, defaultABSURLs, FetchOptions{}, "core", "linux", "/tmp/doesnt-exist", false)
 	assert.NoError(t, err)
 	assert.Equal(t, true, newClone)
 }
@@ -254,7 +313,7 @@ func TestABSPKGBUILDRepoExistsPerms(t *testing.T) {
 			GitFlags: []string{"--no-replace-objects"},
 		},
 	}
-	newClone, err := ABSPKGBUILDRepo(context.TODO(), cmdBuilder, "core", "linux", dir, false)
+	newClone, err := ABSPKGBUILDRepo(context.TODO(), cmdBuilder, defaultABSURLs, FetchOptions{}, "core", "linux", dir, false)
 	assert.NoError(t, err)
 	assert.Equal(t, false, newClone)
 }
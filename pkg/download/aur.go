@@ -2,15 +2,14 @@ package download
 
 import (
 	"context"
-	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"sync"
 
 	"github.com/leonelquinteros/gotext"
 
-	"github.com/Jguer/yay/v11/pkg/multierror"
 	"github.com/Jguer/yay/v11/pkg/settings/exe"
 	"github.com/Jguer/yay/v11/pkg/text"
 )
@@ -39,26 +38,32 @@ func AURPKGBUILD(httpClient httpRequestDoer, pkgName, aurURL string) ([]byte, er
 	return pkgBuild, nil
 }
 
-// AURPkgbuildRepo retrieves the PKGBUILD repository to a dest directory.
-func AURPKGBUILDRepo(ctx context.Context, cmdBuilder exe.GitCmdBuilder, aurURL, pkgName, dest string, force bool) (bool, error) {
-	pkgURL := fmt.Sprintf("%s/%s.git", aurURL, pkgName)
-
+// PKGBUILDRepo clones the git repository at pkgURL to dest/pkgName, or pulls it
+// when already cloned. It returns whether a new clone was made.
+func PKGBUILDRepo(ctx context.Context, cmdBuilder exe.GitCmdBuilder, pkgURL, pkgName, dest string, force bool) (bool, error) {
 	return downloadGitRepo(ctx, cmdBuilder, pkgURL, pkgName, dest, force)
 }
 
+// AURPkgbuildRepo retrieves the PKGBUILD repository to a dest directory,
+// retrying network failures and falling back to the mirrors of fetch.
+func AURPKGBUILDRepo(ctx context.Context, cmdBuilder exe.GitCmdBuilder, aurURL string, fetch FetchOptions,
+	pkgName, dest string, force bool) (bool, error) {
+	return fetchGitRepo(ctx, cmdBuilder, fetch, aurEndpoints(aurURL, pkgName, fetch.Mirrors), pkgName, dest, force)
+}
+
 func AURPKGBUILDRepos(
 	ctx context.Context,
 	cmdBuilder exe.GitCmdBuilder,
-	targets []string, aurURL, dest string, force bool) (map[string]bool, error) {
+	targets []string, aurURL string, fetch FetchOptions, dest string, force bool) (map[string]bool, error) {
 	cloned := make(map[string]bool, len(targets))
 
 	var (
-		mux  sync.Mutex
-		errs multierror.MultiError
-		wg   sync.WaitGroup
+		mux sync.Mutex
+		wg  sync.WaitGroup
 	)
 
-	sem := make(chan uint8, MaxConcurrentFetch)
+	fetch.progress = text.NewProgress(os.Stdout, gotext.Get("Downloading PKGBUILDs"), fetch.Live, targets)
+	sem := make(chan uint8, fetch.jobs())
 
 	for _, target := range targets {
 		sem <- 1
@@ -66,22 +71,16 @@ func AURPKGBUILDRepos(
 		wg.Add(1)
 
 		go func(target string) {
-			newClone, err := AURPKGBUILDRepo(ctx, cmdBuilder, aurURL, target, dest, force)
-
-			progress := 0
-
+			newClone, err := AURPKGBUILDRepo(ctx, cmdBuilder, aurURL, fetch, target, dest, force)
 			if err != nil {
-				errs.Add(err)
+				fetch.progress.Fail(target, err)
 			} else {
 				mux.Lock()
 				cloned[target] = newClone
-				progress = len(cloned)
 				mux.Unlock()
-			}
 
-			text.OperationInfoln(
-				gotext.Get("(%d/%d) Downloaded PKGBUILD: %s",
-					progress, len(targets), text.Cyan(target)))
+				fetch.progress.Done(target, gotext.Get("Downloaded PKGBUILD"))
+			}
 
 			<-sem
 
@@ -91,5 +90,5 @@ func AURPKGBUILDRepos(
 
 	wg.Wait()
 
-	return cloned, errs.Return()
+	return cloned, fetch.progress.Close()
 }
@@ -98,7 +98,7 @@ func TestAURPKGBUILDRepo(t *testing.T) {
 			GitFlags: []string{"--no-replace-objects"},
 		},
 	}
-	newCloned, err := AURPKGBUILDRepo(context.TODO(), cmdBuilder, "https://aur.archlinux.org", "yay-bin", "/tmp/doesnt-exist", false)
+	newCloned, err := AURPKGBUILDRepo(context.TODO(), cmdBuilder, "https://aur.archlinux.org", FetchOptions{}, "yay-bin", "/tmp/doesnt-exist", false)
 	assert.NoError(t, err)
 	assert.Equal(t, true, newCloned)
 }
@@ -112,6 +112,7 @@ func TestAURPKGBUILDRepoExistsPerms(t *testing.T) {
 	defer os.RemoveAll(dir)
 
 	os.MkdirAll(filepath.Join(dir, "yay-bin", ".git"), 0o777)
+	os.WriteFile(filepath.Join(dir, "yay-bin", "PKGBUILD"), []byte("pkgname=yay-bin"), 0o644)
 
 	want := fmt.Sprintf("/usr/local/bin/git --no-replace-objects -C %s/yay-bin pull --ff-only", dir)
 	if os.Getuid() == 0 {
@@ -133,7 +134,7 @@ func TestAURPKGBUILDRepoExistsPerms(t *testing.T) {
 			GitFlags: []string{"--no-replace-objects"},
 		},
 	}
-	cloned, err := AURPKGBUILDRepo(context.TODO(), cmdBuilder, "https://aur.archlinux.org", "yay-bin", dir, false)
+	cloned, err := AURPKGBUILDRepo(context.TODO(), cmdBuilder, "https://aur.archlinux.org", FetchOptions{}, "yay-bin", dir, false)
 	assert.NoError(t, err)
 	assert.Equal(t, false, cloned)
 }
@@ -144,6 +145,7 @@ func TestAURPKGBUILDRepos(t *testing.T) {
 	defer os.RemoveAll(dir)
 
 	os.MkdirAll(filepath.Join(dir, "yay-bin", ".git"), 0o777)
+	os.WriteFile(filepath.Join(dir, "yay-bin", "PKGBUILD"), []byte("pkgname=yay-bin"), 0o644)
 
 	targets := []string{"yay", "yay-bin", "yay-git"}
 	cmdRunner := &testRunner{}
@@ -157,7 +159,7 @@ func TestAURPKGBUILDRepos(t *testing.T) {
 			GitFlags: []string{},
 		},
 	}
-	cloned, err := AURPKGBUILDRepos(context.TODO(), cmdBuilder, targets, "https://aur.archlinux.org", dir, false)
+	cloned, err := AURPKGBUILDRepos(context.TODO(), cmdBuilder, targets, "https://aur.archlinux.org", FetchOptions{}, dir, false)
 
 	assert.NoError(t, err)
 	assert.EqualValues(t, map[string]bool{"yay": true, "yay-bin": false, "yay-git": true}, cloned)
@@ -0,0 +1,149 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/settings/exe"
+)
+
+// CheckoutState describes why an existing checkout can not be fast-forwarded.
+type CheckoutState int
+
+const (
+	// CheckoutClean means the checkout can be fast-forwarded.
+	CheckoutClean CheckoutState = iota
+	// CheckoutDiverged means upstream was rewritten or local commits were made.
+	CheckoutDiverged
+	// CheckoutDirty means tracked files have uncommitted changes.
+	CheckoutDirty
+	// CheckoutDetached means HEAD is not on a branch.
+	CheckoutDetached
+)
+
+func (s CheckoutState) String() string {
+	switch s {
+	case CheckoutDiverged:
+		return gotext.Get("the local branch diverged from upstream, the repository may have been force-pushed")
+	case CheckoutDirty:
+		return gotext.Get("tracked files have local changes conflicting with the update")
+	case CheckoutDetached:
+		return gotext.Get("HEAD is detached from the branch")
+	default:
+		return gotext.Get("the checkout is clean")
+	}
+}
+
+// ErrPKGBUILDRepoCheckout means the PKGBUILD repository of Pkgbase could not
+// be updated because of the State of its checkout.
+type ErrPKGBUILDRepoCheckout struct {
+	Pkgbase string
+	State   CheckoutState
+	errOut  string
+}
+
+func (e ErrPKGBUILDRepoCheckout) Error() string {
+	return fmt.Sprintln(gotext.Get("unable to update %s: %s", e.Pkgbase, e.State), "\n\t context:", e.errOut)
+}
+
+// InspectCheckout reports whether the checkout in dir is detached, has
+// uncommitted changes to tracked files or diverged from its upstream.
+func InspectCheckout(ctx context.Context, cmdBuilder exe.GitCmdBuilder, dir string) (CheckoutState, error) {
+	if _, _, err := cmdBuilder.Capture(cmdBuilder.BuildGitCmd(ctx, dir, "symbolic-ref", "-q", "HEAD")); err != nil {
+		return CheckoutDetached, nil
+	}
+
+	status, stderr, err := cmdBuilder.Capture(
+		cmdBuilder.BuildGitCmd(ctx, dir, "status", "--porcelain", "--untracked-files=no"))
+	if err != nil {
+		return CheckoutClean, errors.New(stderr)
+	}
+
+	if status != "" {
+		return CheckoutDirty, nil
+	}
+
+	// is-ancestor exits with 1 when HEAD has commits missing upstream
+	if _, _, err := cmdBuilder.Capture(
+		cmdBuilder.BuildGitCmd(ctx, dir, "merge-base", "--is-ancestor", "HEAD", "@{upstream}")); err != nil {
+		return CheckoutDiverged, nil
+	}
+
+	return CheckoutClean, nil
+}
+
+// pullError turns the failure of a pull in dir into ErrPKGBUILDRepoCheckout
+// when the checkout is the cause, or ErrGetPKGBUILDRepo otherwise.
+func pullError(ctx context.Context, cmdBuilder exe.GitCmdBuilder, dir, pkgName string, err error, stderr string) error {
+	if !containsAny(stderr, networkMessages) {
+		if state, errI := InspectCheckout(ctx, cmdBuilder, dir); errI == nil && state != CheckoutClean {
+			return ErrPKGBUILDRepoCheckout{Pkgbase: pkgName, State: state, errOut: stderr}
+		}
+	}
+
+	return ErrGetPKGBUILDRepo{inner: err, pkgName: pkgName, errOut: stderr}
+}
+
+// trackingBranch returns the local branch of the checkout in dir that has an
+// upstream, and that upstream.
+func trackingBranch(ctx context.Context, cmdBuilder exe.GitCmdBuilder, dir string) (branch, upstream string, err error) {
+	refs, stderr, err := cmdBuilder.Capture(cmdBuilder.BuildGitCmd(ctx, dir,
+		"for-each-ref", "--format=%(refname:short) %(upstream:short)", "refs/heads"))
+	if err != nil {
+		return "", "", errors.New(stderr)
+	}
+
+	for _, line := range strings.Split(refs, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			return fields[0], fields[1], nil
+		}
+	}
+
+	return "", "", errors.New(gotext.Get("no branch tracking upstream in %s", dir))
+}
+
+// ResetCheckout moves the checkout in dir back to the last fetched upstream,
+// dropping local commits. Uncommitted changes to tracked files are stashed and
+// reapplied on top when keepEdits is set, and discarded otherwise.
+func ResetCheckout(ctx context.Context, cmdBuilder exe.GitCmdBuilder, dir string, keepEdits bool) error {
+	branch, upstream, err := trackingBranch(ctx, cmdBuilder, dir)
+	if err != nil {
+		return errors.New(gotext.Get("error resetting %s: %s", dir, err))
+	}
+
+	stashed := false
+
+	if keepEdits {
+		status, _, errStatus := cmdBuilder.Capture(
+			cmdBuilder.BuildGitCmd(ctx, dir, "status", "--porcelain", "--untracked-files=no"))
+		if errStatus == nil && status != "" {
+			if _, stderr, errStash := cmdBuilder.Capture(
+				cmdBuilder.BuildGitCmd(ctx, dir, "stash", "push", "-m", "yay: local edits")); errStash != nil {
+				return errors.New(gotext.Get("error stashing the local edits of %s: %s", dir, stderr))
+			}
+
+			stashed = true
+		}
+	}
+
+	if _, stderr, errCheckout := cmdBuilder.Capture(
+		cmdBuilder.BuildGitCmd(ctx, dir, "checkout", "-q", "-f", "-B", branch, upstream)); errCheckout != nil {
+		return errors.New(gotext.Get("error resetting %s: %s", dir, stderr))
+	}
+
+	if !stashed {
+		return nil
+	}
+
+	if _, stderr, errPop := cmdBuilder.Capture(cmdBuilder.BuildGitCmd(ctx, dir, "stash", "pop")); errPop != nil {
+		return errors.New(gotext.Get("the local edits of %s conflict with the update and are kept in git stash: %s",
+			dir, stderr))
+	}
+
+	return nil
+}
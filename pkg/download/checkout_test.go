@@ -0,0 +1,137 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const pkgbuildLines = "pkgver=1\n#\n#\n#\n#\n#\npkgrel=1\n"
+
+// setupCheckout returns an AUR serving yay, a clone used to push to it and
+// dest holding a checkout of yay.
+func setupCheckout(t *testing.T) (aur, work, dest string) {
+	t.Helper()
+
+	aur = t.TempDir()
+	work = t.TempDir()
+	dest = t.TempDir()
+
+	runGit(t, aur, "init", "--bare", "-q", "yay.git")
+	runGit(t, work, "init", "-q")
+	commitPKGBUILD(t, work, pkgbuildLines)
+	runGit(t, work, "push", "-q", filepath.Join(aur, "yay.git"), "HEAD")
+
+	_, err := AURPKGBUILDRepo(context.Background(), &localGitBuilder{}, aur, FetchOptions{}, "yay", dest, false)
+	assert.NoError(t, err)
+
+	return aur, work, dest
+}
+
+func TestAURPKGBUILDRepoCheckout(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		setup func(t *testing.T, aur, work, dir string)
+		want  CheckoutState
+	}{
+		{
+			name: "force pushed",
+			setup: func(t *testing.T, aur, work, dir string) {
+				runGit(t, work, "commit", "-q", "--amend", "-m", "rewritten")
+				runGit(t, work, "push", "-q", "-f", filepath.Join(aur, "yay.git"), "HEAD")
+			},
+			want: CheckoutDiverged,
+		},
+		{
+			name: "local edits",
+			setup: func(t *testing.T, aur, work, dir string) {
+				commitPKGBUILD(t, work, "pkgver=2\n#\n#\n#\n#\n#\npkgrel=1\n")
+				runGit(t, work, "push", "-q", filepath.Join(aur, "yay.git"), "HEAD")
+				assert.NoError(t, os.WriteFile(filepath.Join(dir, "PKGBUILD"),
+					[]byte("pkgver=1\n#\n#\n#\n#\n#\npkgrel=2\n"), 0o644))
+			},
+			want: CheckoutDirty,
+		},
+		{
+			name: "detached",
+			setup: func(t *testing.T, aur, work, dir string) {
+				runGit(t, dir, "checkout", "-q", "--detach")
+			},
+			want: CheckoutDetached,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			aur, work, dest := setupCheckout(t)
+			dir := filepath.Join(dest, "yay")
+			tc.setup(t, aur, work, dir)
+
+			_, err := AURPKGBUILDRepo(context.Background(), &localGitBuilder{}, aur, FetchOptions{}, "yay", dest, false)
+
+			var errCheckout ErrPKGBUILDRepoCheckout
+			if assert.True(t, errors.As(err, &errCheckout), err) {
+				assert.Equal(t, tc.want, errCheckout.State)
+				assert.Equal(t, "yay", errCheckout.Pkgbase)
+			}
+		})
+	}
+}
+
+func TestResetCheckout(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		keepEdits bool
+		want      string
+	}{
+		{name: "stash", keepEdits: true, want: "pkgver=2\n#\n#\n#\n#\n#\npkgrel=2\n"},
+		{name: "reset", keepEdits: false, want: "pkgver=2\n#\n#\n#\n#\n#\npkgrel=1\n"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			aur, work, dest := setupCheckout(t)
+			dir := filepath.Join(dest, "yay")
+
+			// upstream rewrites history while the PKGBUILD is edited locally
+			assert.NoError(t, os.WriteFile(filepath.Join(work, "PKGBUILD"),
+				[]byte("pkgver=2\n#\n#\n#\n#\n#\npkgrel=1\n"), 0o644))
+			runGit(t, work, "commit", "-q", "-a", "--amend", "-m", "rewritten")
+			runGit(t, work, "push", "-q", "-f", filepath.Join(aur, "yay.git"), "HEAD")
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "PKGBUILD"),
+				[]byte("pkgver=1\n#\n#\n#\n#\n#\npkgrel=2\n"), 0o644))
+
+			_, err := AURPKGBUILDRepo(context.Background(), &localGitBuilder{}, aur, FetchOptions{}, "yay", dest, false)
+			assert.Error(t, err)
+
+			assert.NoError(t, ResetCheckout(context.Background(), &localGitBuilder{}, dir, tc.keepEdits))
+
+			pkgbuild, err := os.ReadFile(filepath.Join(dir, "PKGBUILD"))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, string(pkgbuild))
+
+			state, err := InspectCheckout(context.Background(), &localGitBuilder{}, dir)
+			assert.NoError(t, err)
+
+			if tc.keepEdits {
+				assert.Equal(t, CheckoutDirty, state)
+			} else {
+				assert.Equal(t, CheckoutClean, state)
+			}
+		})
+	}
+}
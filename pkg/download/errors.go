@@ -26,6 +26,35 @@ func (e ErrGetPKGBUILDRepo) Error() string {
 		"\n\t context:", e.inner.Error())
 }
 
-func (e *ErrGetPKGBUILDRepo) Unwrap() error {
+func (e ErrGetPKGBUILDRepo) Unwrap() error {
+	return e.inner
+}
+
+// ErrPKGBUILDRepoNotFound means the PKGBUILD repository does not exist at url.
+type ErrPKGBUILDRepoNotFound struct {
+	pkgName string
+	url     string
+	errOut  string
+}
+
+func (e ErrPKGBUILDRepoNotFound) Error() string {
+	return fmt.Sprintln(gotext.Get("package %s not found at %s: %s", e.pkgName, e.url, e.errOut))
+}
+
+// ErrPKGBUILDRepoNetwork means the PKGBUILD repository could not be fetched
+// from url because of a network failure.
+type ErrPKGBUILDRepoNetwork struct {
+	inner   error
+	pkgName string
+	url     string
+	errOut  string
+}
+
+func (e ErrPKGBUILDRepoNetwork) Error() string {
+	return fmt.Sprintln(gotext.Get("network error fetching %s from %s: %s", e.pkgName, e.url, e.errOut),
+		"\n\t context:", e.inner.Error())
+}
+
+func (e ErrPKGBUILDRepoNetwork) Unwrap() error {
 	return e.inner
 }
@@ -0,0 +1,289 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/settings/exe"
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+// AURMirror is a git endpoint serving AUR PKGBUILD repositories, used when the
+// AUR can not be reached. {pkgbase} is replaced by the package base.
+// Example, the GitHub mirror holding one branch per package:
+//
+//	{
+//		"url": "https://github.com/archlinux/aur.git",
+//		"branch": "{pkgbase}"
+//	}
+type AURMirror struct {
+	URL string `json:"url"`
+	// Branch is the branch holding the package, the default branch when empty.
+	Branch string `json:"branch"`
+}
+
+// FetchOptions controls how PKGBUILD repositories are cloned and pulled.
+type FetchOptions struct {
+	// Retries is the number of times a network failure is retried per endpoint.
+	Retries int
+	// Backoff is the delay before the first retry, doubled after each retry.
+	Backoff time.Duration
+	// Mirrors are tried in order when the AUR fails.
+	Mirrors []AURMirror
+	// Jobs is the number of repositories fetched at once, MaxConcurrentFetch
+	// when 0.
+	Jobs int
+	// Live redraws the state of the fetches in place instead of printing a
+	// line per repository.
+	Live bool
+
+	progress *text.Progress
+}
+
+func (o *FetchOptions) jobs() int {
+	if o.Jobs > 0 {
+		return o.Jobs
+	}
+
+	return MaxConcurrentFetch
+}
+
+// endpoint is a git repository and branch a PKGBUILD repository is fetched from.
+type endpoint struct {
+	url    string
+	branch string
+	mirror bool
+	// aur is set for the AUR itself, which serves missing package bases as
+	// empty repositories.
+	aur bool
+}
+
+// aurEndpoints returns the AUR endpoint of pkgName followed by its mirrors.
+func aurEndpoints(aurURL, pkgName string, mirrors []AURMirror) []endpoint {
+	endpoints := make([]endpoint, 0, len(mirrors)+1)
+	endpoints = append(endpoints, endpoint{url: aurURL + "/" + pkgName + ".git", aur: true})
+
+	replacer := strings.NewReplacer("{pkgbase}", pkgName)
+
+	for _, mirror := range mirrors {
+		endpoints = append(endpoints, endpoint{
+			url:    replacer.Replace(mirror.URL),
+			branch: replacer.Replace(mirror.Branch),
+			mirror: true,
+		})
+	}
+
+	return endpoints
+}
+
+// notFoundMessages are git errors meaning the repository or branch does not exist.
+var notFoundMessages = []string{
+	"not found",
+	"does not exist",
+	"does not appear to be a git repository",
+	"couldn't find remote ref",
+	"could not find remote branch",
+	"returned error: 404",
+}
+
+// networkMessages are git errors caused by transient network failures.
+var networkMessages = []string{
+	"could not resolve host",
+	"could not resolve proxy",
+	"failed to connect",
+	"connection timed out",
+	"connection refused",
+	"connection reset",
+	"operation timed out",
+	"timed out after",
+	"the remote end hung up unexpectedly",
+	"early eof",
+	"rpc failed",
+	"unexpected disconnect",
+	"temporary failure in name resolution",
+	"network is unreachable",
+	"gnutls_handshake",
+	"ssl_error",
+	"returned error: 429",
+	"returned error: 5",
+}
+
+func containsAny(s string, substrs []string) bool {
+	s = strings.ToLower(s)
+
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// classifyFetchError turns the failure of a git command into
+// ErrPKGBUILDRepoNotFound, ErrPKGBUILDRepoNetwork or ErrGetPKGBUILDRepo.
+// A missing repository is permanent even when git also reports the remote
+// hanging up after the 404.
+func classifyFetchError(err error, pkgName, url, stderr string) error {
+	switch {
+	case containsAny(stderr, notFoundMessages):
+		return ErrPKGBUILDRepoNotFound{pkgName: pkgName, url: url, errOut: stderr}
+	case containsAny(stderr, networkMessages):
+		return ErrPKGBUILDRepoNetwork{inner: err, pkgName: pkgName, url: url, errOut: stderr}
+	default:
+		return ErrGetPKGBUILDRepo{inner: err, pkgName: pkgName, errOut: stderr}
+	}
+}
+
+// fetchGitRepo clones or pulls the repository of pkgName from each endpoint in
+// turn until one succeeds. Network failures are retried with exponential
+// backoff, then move on to the next endpoint. A package missing from the AUR
+// does not exist, mirrors are only tried for one missing from a mirror. When
+// every endpoint fails, a network failure is reported over a missing
+// repository.
+func fetchGitRepo(ctx context.Context, cmdBuilder exe.GitCmdBuilder, opts FetchOptions,
+	endpoints []endpoint, pkgName, dest string, force bool) (bool, error) {
+	var errFetch error
+
+	for i, e := range endpoints {
+		if i > 0 {
+			opts.progress.Warnln(gotext.Get("%s: trying mirror %s", text.Cyan(pkgName), e.url))
+		}
+
+		newClone, err := fetchWithRetries(ctx, cmdBuilder, opts, e, pkgName, dest, force)
+		if err == nil {
+			return newClone, nil
+		}
+
+		var (
+			errNotFound ErrPKGBUILDRepoNotFound
+			errNetwork  ErrPKGBUILDRepoNetwork
+		)
+
+		switch {
+		case errors.As(err, &errNetwork):
+			errFetch = err
+		case errors.As(err, &errNotFound) && !e.mirror:
+			return false, err
+		case errors.As(err, &errNotFound):
+			if !errors.As(errFetch, &errNetwork) {
+				errFetch = err
+			}
+		default:
+			// local failures are not solved by another endpoint
+			return false, err
+		}
+	}
+
+	return false, errFetch
+}
+
+func fetchWithRetries(ctx context.Context, cmdBuilder exe.GitCmdBuilder, opts FetchOptions,
+	e endpoint, pkgName, dest string, force bool) (bool, error) {
+	delay := opts.Backoff
+
+	for attempt := 0; ; attempt++ {
+		state := text.Cloning
+		if _, err := os.Stat(filepath.Join(dest, pkgName, ".git")); err == nil && !force {
+			state = text.Pulling
+		}
+
+		opts.progress.Set(pkgName, state)
+
+		newClone, err := fetchEndpoint(ctx, cmdBuilder, e, pkgName, dest, force)
+
+		var errNetwork ErrPKGBUILDRepoNetwork
+		if err == nil || !errors.As(err, &errNetwork) || attempt >= opts.Retries {
+			return newClone, err
+		}
+
+		opts.progress.Warnln(gotext.Get("%s: network failure, retrying in %s (%d/%d)",
+			text.Cyan(pkgName), delay, attempt+1, opts.Retries))
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+	}
+}
+
+// checkAURRepo reports the AUR clone in dir as not found when it holds no
+// PKGBUILD. A new empty clone is removed again.
+func checkAURRepo(dir, pkgName, url string, newClone bool) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		// git left nothing to check
+		return nil
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "PKGBUILD")); err == nil {
+		return nil
+	}
+
+	if newClone {
+		if err := os.RemoveAll(dir); err != nil {
+			return ErrGetPKGBUILDRepo{inner: err, pkgName: pkgName, errOut: ""}
+		}
+	}
+
+	return ErrPKGBUILDRepoNotFound{pkgName: pkgName, url: url, errOut: gotext.Get("empty repository")}
+}
+
+// fetchEndpoint clones e to dest/pkgName, or pulls it into the existing clone.
+// Mirrors are fetched into the upstream branch of the clone so the changes
+// show up in the diff menu like a regular pull.
+func fetchEndpoint(ctx context.Context, cmdBuilder exe.GitCmdBuilder, e endpoint,
+	pkgName, dest string, force bool) (bool, error) {
+	dir := filepath.Join(dest, pkgName)
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); !e.mirror || err != nil || force {
+		var gitArgs []string
+		if e.branch != "" {
+			gitArgs = []string{"--single-branch", "-b", e.branch}
+		}
+
+		newClone, err := downloadGitRepo(ctx, cmdBuilder, e.url, pkgName, dest, force, gitArgs...)
+
+		var errGet ErrGetPKGBUILDRepo
+		if errors.As(err, &errGet) && errGet.errOut != "" {
+			return false, classifyFetchError(errGet.inner, pkgName, e.url, errGet.errOut)
+		}
+
+		if err == nil && e.aur {
+			err = checkAURRepo(dir, pkgName, e.url, newClone)
+		}
+
+		return newClone, err
+	}
+
+	upstream, stderr, err := cmdBuilder.Capture(
+		cmdBuilder.BuildGitCmd(ctx, dir, "rev-parse", "--symbolic-full-name", "@{upstream}"))
+	if err != nil {
+		return false, ErrGetPKGBUILDRepo{inner: err, pkgName: pkgName, errOut: stderr}
+	}
+
+	branch := e.branch
+	if branch == "" {
+		branch = "HEAD"
+	}
+
+	if _, stderr, err = cmdBuilder.Capture(
+		cmdBuilder.BuildGitCmd(ctx, dir, "fetch", "--no-progress", e.url, "+"+branch+":"+upstream)); err != nil {
+		return false, classifyFetchError(err, pkgName, e.url, stderr)
+	}
+
+	if _, stderr, err = cmdBuilder.Capture(
+		cmdBuilder.BuildGitCmd(ctx, dir, "merge", "--ff-only", "@{upstream}")); err != nil {
+		return false, pullError(ctx, cmdBuilder, dir, pkgName, err, stderr)
+	}
+
+	return false, nil
+}
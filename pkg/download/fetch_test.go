@@ -0,0 +1,244 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v11/pkg/settings/exe"
+)
+
+func Test_classifyFetchError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		stderr string
+		want   error
+	}{
+		{
+			name:   "missing repository",
+			stderr: "fatal: repository 'https://aur.archlinux.org/nope.git/' not found",
+			want:   ErrPKGBUILDRepoNotFound{},
+		},
+		{
+			name:   "missing branch",
+			stderr: "warning: Could not find remote branch nope to clone.",
+			want:   ErrPKGBUILDRepoNotFound{},
+		},
+		{
+			name:   "dns",
+			stderr: "fatal: unable to access 'https://aur.archlinux.org/yay.git/': Could not resolve host: aur.archlinux.org",
+			want:   ErrPKGBUILDRepoNetwork{},
+		},
+		{
+			name:   "server error",
+			stderr: "fatal: unable to access 'https://aur.archlinux.org/yay.git/': The requested URL returned error: 503",
+			want:   ErrPKGBUILDRepoNetwork{},
+		},
+		{
+			name:   "missing github repository",
+			stderr: "remote: Repository not found.\nfatal: the remote end hung up unexpectedly",
+			want:   ErrPKGBUILDRepoNotFound{},
+		},
+		{
+			name:   "missing over http",
+			stderr: "fatal: unable to access 'https://aur.archlinux.org/nope.git/': The requested URL returned error: 404",
+			want:   ErrPKGBUILDRepoNotFound{},
+		},
+		{
+			name:   "hung up",
+			stderr: "fatal: the remote end hung up unexpectedly",
+			want:   ErrPKGBUILDRepoNetwork{},
+		},
+		{
+			name:   "diverged",
+			stderr: "fatal: Not possible to fast-forward, aborting.",
+			want:   ErrGetPKGBUILDRepo{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := classifyFetchError(errors.New("exit status 128"), "yay", "url", tc.stderr)
+			assert.IsType(t, tc.want, got)
+		})
+	}
+}
+
+// flakyBuilder fails the first failures git commands with stderr.
+type flakyBuilder struct {
+	exe.GitCmdBuilder
+	failures int
+	stderr   string
+	calls    int
+}
+
+func (b *flakyBuilder) BuildGitCmd(ctx context.Context, dir string, extraArgs ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, "true")
+}
+
+func (b *flakyBuilder) Capture(cmd *exec.Cmd) (stdout, stderr string, err error) {
+	b.calls++
+	if b.calls <= b.failures {
+		return "", b.stderr, errors.New("exit status 128")
+	}
+
+	return "", "", nil
+}
+
+func TestFetchGitRepoRetries(t *testing.T) {
+	t.Parallel()
+
+	network := "fatal: unable to access 'https://aur.archlinux.org/yay.git/': Connection timed out"
+	notFound := "fatal: repository 'https://aur.archlinux.org/yay.git/' not found"
+
+	testCases := []struct {
+		name      string
+		failures  int
+		stderr    string
+		retries   int
+		mirrors   []AURMirror
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "recovers", failures: 2, stderr: network, retries: 3, wantCalls: 3},
+		{name: "gives up", failures: 5, stderr: network, retries: 1, wantCalls: 2, wantErr: ErrPKGBUILDRepoNetwork{}},
+		{name: "not found is not retried", failures: 5, stderr: notFound, retries: 3, wantCalls: 1, wantErr: ErrPKGBUILDRepoNotFound{}},
+		{
+			name:      "not found skips mirrors",
+			failures:  5,
+			stderr:    notFound,
+			retries:   3,
+			mirrors:   []AURMirror{{URL: "https://github.com/archlinux/aur.git", Branch: "{pkgbase}"}},
+			wantCalls: 1,
+			wantErr:   ErrPKGBUILDRepoNotFound{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			builder := &flakyBuilder{failures: tc.failures, stderr: tc.stderr}
+			opts := FetchOptions{Retries: tc.retries, Mirrors: tc.mirrors}
+
+			newClone, err := AURPKGBUILDRepo(context.Background(), builder,
+				"https://aur.archlinux.org", opts, "yay", t.TempDir(), false)
+
+			assert.Equal(t, tc.wantCalls, builder.calls)
+
+			if tc.wantErr == nil {
+				assert.NoError(t, err)
+				assert.True(t, newClone)
+			} else {
+				assert.IsType(t, tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// localGitBuilder runs git as the current user.
+type localGitBuilder struct {
+	exe.OSRunner
+}
+
+func (b *localGitBuilder) BuildGitCmd(ctx context.Context, dir string, extraArgs ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, extraArgs...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=yay", "GIT_AUTHOR_EMAIL=yay@localhost",
+		"GIT_COMMITTER_NAME=yay", "GIT_COMMITTER_EMAIL=yay@localhost")
+
+	return cmd
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	out, err := (&localGitBuilder{}).BuildGitCmd(context.Background(), dir, args...).CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
+
+func commitPKGBUILD(t *testing.T, dir, content string) {
+	t.Helper()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "PKGBUILD"), []byte(content), 0o644))
+	runGit(t, dir, "add", "PKGBUILD")
+	runGit(t, dir, "commit", "-q", "-m", content)
+}
+
+// GIVEN an unreachable AUR and a mirror holding the package in a branch
+// WHEN AURPKGBUILDRepo is called
+// THEN the package is cloned, then pulled, from the mirror.
+func TestAURPKGBUILDRepoMirror(t *testing.T) {
+	t.Parallel()
+
+	aur := t.TempDir()
+	mirror := t.TempDir()
+	work := t.TempDir()
+	dest := t.TempDir()
+
+	runGit(t, aur, "init", "--bare", "-q", "yay.git")
+	runGit(t, mirror, "init", "--bare", "-q")
+	runGit(t, work, "init", "-q")
+	commitPKGBUILD(t, work, "pkgver=1")
+	runGit(t, work, "push", "-q", filepath.Join(aur, "yay.git"), "HEAD")
+	runGit(t, work, "push", "-q", mirror, "HEAD:refs/heads/yay")
+
+	opts := FetchOptions{Mirrors: []AURMirror{{URL: mirror, Branch: "{pkgbase}"}}}
+
+	newClone, err := AURPKGBUILDRepo(context.Background(), &localGitBuilder{}, aur, opts, "yay", dest, false)
+	assert.NoError(t, err)
+	assert.True(t, newClone)
+
+	// the AUR goes down, updates only reach the mirror
+	runGit(t, filepath.Join(dest, "yay"), "remote", "set-url", "origin", "http://127.0.0.1:1/yay.git")
+	commitPKGBUILD(t, work, "pkgver=2")
+	runGit(t, work, "push", "-q", mirror, "HEAD:refs/heads/yay")
+
+	newClone, err = AURPKGBUILDRepo(context.Background(), &localGitBuilder{}, aur, opts, "yay", dest, false)
+	assert.NoError(t, err)
+	assert.False(t, newClone)
+
+	pkgbuild, err := os.ReadFile(filepath.Join(dest, "yay", "PKGBUILD"))
+	assert.NoError(t, err)
+	assert.Equal(t, "pkgver=2", strings.TrimSpace(string(pkgbuild)))
+
+	// a package missing everywhere is reported as not found
+	_, err = AURPKGBUILDRepo(context.Background(), &localGitBuilder{}, aur, opts, "nope", dest, false)
+
+	var errNotFound ErrPKGBUILDRepoNotFound
+	assert.True(t, errors.As(err, &errNotFound), err)
+}
+
+// GIVEN an AUR answering with an empty repository, as for a missing package base
+// WHEN AURPKGBUILDRepo is called
+// THEN the package is reported as not found and the empty clone is removed.
+func TestAURPKGBUILDRepoEmpty(t *testing.T) {
+	t.Parallel()
+
+	aur := t.TempDir()
+	dest := t.TempDir()
+
+	runGit(t, aur, "init", "--bare", "-q", "nope.git")
+
+	opts := FetchOptions{Mirrors: []AURMirror{{URL: filepath.Join(aur, "nope.git")}}}
+
+	newClone, err := AURPKGBUILDRepo(context.Background(), &localGitBuilder{}, aur, opts, "nope", dest, false)
+	assert.False(t, newClone)
+
+	var errNotFound ErrPKGBUILDRepoNotFound
+	assert.True(t, errors.As(err, &errNotFound), err)
+
+	_, err = os.Stat(filepath.Join(dest, "nope"))
+	assert.True(t, os.IsNotExist(err))
+}
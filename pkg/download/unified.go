@@ -56,11 +56,11 @@ func downloadGitRepo(ctx context.Context, cmdBuilder exe.GitCmdBuilder,
 			errOut:  gotext.Get("error reading %s", filepath.Join(dest, pkgName, ".git")),
 		}
 	default:
-		cmd := cmdBuilder.BuildGitCmd(ctx, filepath.Join(dest, pkgName), "pull", "--ff-only")
+		cmd := cmdBuilder.BuildGitCmd(ctx, finalDir, "pull", "--ff-only")
 
 		_, stderr, errCmd := cmdBuilder.Capture(cmd)
 		if errCmd != nil {
-			return false, ErrGetPKGBUILDRepo{inner: errCmd, pkgName: pkgName, errOut: stderr}
+			return false, pullError(ctx, cmdBuilder, finalDir, pkgName, errCmd, stderr)
 		}
 
 		newClone = false
@@ -79,7 +79,7 @@ func getURLName(pkg db.IPackage) string {
 }
 
 func PKGBUILDs(dbExecutor DBSearcher, httpClient *http.Client, targets []string,
-	aurURL string, mode parser.TargetMode) (map[string][]byte, error) {
+	aurURL string, absURLs ABSURLs, mode parser.TargetMode) (map[string][]byte, error) {
 	pkgbuilds := make(map[string][]byte, len(targets))
 
 	var (
@@ -110,7 +110,7 @@ func PKGBUILDs(dbExecutor DBSearcher, httpClient *http.Client, targets []string,
 			if aur {
 				pkgbuild, err = AURPKGBUILD(httpClient, pkgName, aurURL)
 			} else {
-				pkgbuild, err = ABSPKGBUILD(httpClient, dbName, pkgName)
+				pkgbuild, err = ABSPKGBUILD(httpClient, absURLs, dbName, pkgName)
 			}
 
 			if err == nil {
@@ -133,16 +133,22 @@ func PKGBUILDs(dbExecutor DBSearcher, httpClient *http.Client, targets []string,
 
 func PKGBUILDRepos(ctx context.Context, dbExecutor DBSearcher,
 	cmdBuilder exe.GitCmdBuilder,
-	targets []string, mode parser.TargetMode, aurURL, dest string, force bool) (map[string]bool, error) {
+	targets []string, mode parser.TargetMode, aurURL string, absURLs ABSURLs, fetch FetchOptions,
+	dest string, force bool) (map[string]bool, error) {
 	cloned := make(map[string]bool, len(targets))
 
 	var (
-		mux  sync.Mutex
-		errs multierror.MultiError
-		wg   sync.WaitGroup
+		mux sync.Mutex
+		wg  sync.WaitGroup
 	)
 
-	sem := make(chan uint8, MaxConcurrentFetch)
+	type usableName struct {
+		target, dbName, pkgName string
+		aur                     bool
+	}
+
+	usable := make([]usableName, 0, len(targets))
+	names := make([]string, 0, len(targets))
 
 	for _, target := range targets {
 		// Probably replaceable by something in query.
@@ -151,6 +157,14 @@ func PKGBUILDRepos(ctx context.Context, dbExecutor DBSearcher,
 			continue
 		}
 
+		usable = append(usable, usableName{target, dbName, name, aur})
+		names = append(names, name)
+	}
+
+	fetch.progress = text.NewProgress(os.Stdout, gotext.Get("Downloading PKGBUILDs"), fetch.Live, names)
+	sem := make(chan uint8, fetch.jobs())
+
+	for _, u := range usable {
 		sem <- 1
 
 		wg.Add(1)
@@ -159,44 +173,36 @@ func PKGBUILDRepos(ctx context.Context, dbExecutor DBSearcher,
 			var (
 				err      error
 				newClone bool
+				message  string
 			)
 
 			if aur {
-				newClone, err = AURPKGBUILDRepo(ctx, cmdBuilder, aurURL, pkgName, dest, force)
+				newClone, err = AURPKGBUILDRepo(ctx, cmdBuilder, aurURL, fetch, pkgName, dest, force)
+				message = gotext.Get("Downloaded PKGBUILD")
 			} else {
-				newClone, err = ABSPKGBUILDRepo(ctx, cmdBuilder, dbName, pkgName, dest, force)
+				newClone, err = ABSPKGBUILDRepo(ctx, cmdBuilder, absURLs, fetch, dbName, pkgName, dest, force)
+				message = gotext.Get("Downloaded PKGBUILD from ABS")
 			}
 
-			progress := 0
-
 			if err != nil {
-				errs.Add(err)
+				fetch.progress.Fail(pkgName, err)
 			} else {
 				mux.Lock()
 				cloned[target] = newClone
-				progress = len(cloned)
 				mux.Unlock()
-			}
 
-			if aur {
-				text.OperationInfoln(
-					gotext.Get("(%d/%d) Downloaded PKGBUILD: %s",
-						progress, len(targets), text.Cyan(pkgName)))
-			} else {
-				text.OperationInfoln(
-					gotext.Get("(%d/%d) Downloaded PKGBUILD from ABS: %s",
-						progress, len(targets), text.Cyan(pkgName)))
+				fetch.progress.Done(pkgName, message)
 			}
 
 			<-sem
 
 			wg.Done()
-		}(target, dbName, name, aur)
+		}(u.target, u.dbName, u.pkgName, u.aur)
 	}
 
 	wg.Wait()
 
-	return cloned, errs.Return()
+	return cloned, fetch.progress.Close()
 }
 
 // TODO: replace with dep.ResolveTargets.
@@ -41,7 +41,7 @@ func TestPKGBUILDReposDefinedDBPull(t *testing.T) {
 	}
 	cloned, err := PKGBUILDRepos(context.TODO(), searcher,
 		cmdBuilder,
-		targets, parser.ModeAny, "https://aur.archlinux.org", dir, false)
+		targets, parser.ModeAny, "https://aur.archlinux.org", defaultABSURLs, FetchOptions{}, dir, false)
 
 	assert.NoError(t, err)
 	assert.EqualValues(t, map[string]bool{"core/yay": false, "yay-bin": true, "yay-git": true}, cloned)
@@ -71,7 +71,7 @@ func TestPKGBUILDReposDefinedDBClone(t *testing.T) {
 	}
 	cloned, err := PKGBUILDRepos(context.TODO(), searcher,
 		cmdBuilder,
-		targets, parser.ModeAny, "https://aur.archlinux.org", dir, false)
+		targets, parser.ModeAny, "https://aur.archlinux.org", defaultABSURLs, FetchOptions{}, dir, false)
 
 	assert.NoError(t, err)
 	assert.EqualValues(t, map[string]bool{"core/yay": true, "yay-bin": true, "yay-git": true}, cloned)
@@ -101,7 +101,7 @@ func TestPKGBUILDReposClone(t *testing.T) {
 	}
 	cloned, err := PKGBUILDRepos(context.TODO(), searcher,
 		cmdBuilder,
-		targets, parser.ModeAny, "https://aur.archlinux.org", dir, false)
+		targets, parser.ModeAny, "https://aur.archlinux.org", defaultABSURLs, FetchOptions{}, dir, false)
 
 	assert.NoError(t, err)
 	assert.EqualValues(t, map[string]bool{"yay": true, "yay-bin": true, "yay-git": true}, cloned)
@@ -131,7 +131,7 @@ func TestPKGBUILDReposNotFound(t *testing.T) {
 	}
 	cloned, err := PKGBUILDRepos(context.TODO(), searcher,
 		cmdBuilder,
-		targets, parser.ModeAny, "https://aur.archlinux.org", dir, false)
+		targets, parser.ModeAny, "https://aur.archlinux.org", defaultABSURLs, FetchOptions{}, dir, false)
 
 	assert.NoError(t, err)
 	assert.EqualValues(t, map[string]bool{"yay-bin": true, "yay-git": true}, cloned)
@@ -161,7 +161,7 @@ func TestPKGBUILDReposRepoMode(t *testing.T) {
 	}
 	cloned, err := PKGBUILDRepos(context.TODO(), searcher,
 		cmdBuilder,
-		targets, parser.ModeRepo, "https://aur.archlinux.org", dir, false)
+		targets, parser.ModeRepo, "https://aur.archlinux.org", defaultABSURLs, FetchOptions{}, dir, false)
 
 	assert.NoError(t, err)
 	assert.EqualValues(t, map[string]bool{"yay": true}, cloned)
@@ -182,8 +182,8 @@ func TestPKGBUILDFull(t *testing.T) {
 		Reply(200).
 		BodyString("example_yay-bin")
 
-	gock.New("https://github.com/").
-		Get("/archlinux/svntogit-packages/raw/packages/yay/trunk/PKGBUILD").
+	gock.New("https://gitlab.archlinux.org/").
+		Get("/archlinux/packaging/packages/yay/-/raw/main/PKGBUILD").
 		Reply(200).
 		BodyString("example_yay")
 
@@ -194,7 +194,7 @@ func TestPKGBUILDFull(t *testing.T) {
 	}
 
 	fetched, err := PKGBUILDs(searcher, &http.Client{},
-		targets, "https://aur.archlinux.org", parser.ModeAny)
+		targets, "https://aur.archlinux.org", defaultABSURLs, parser.ModeAny)
 
 	assert.NoError(t, err)
 	assert.EqualValues(t, map[string][]byte{
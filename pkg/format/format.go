@@ -0,0 +1,238 @@
+package format
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Jguer/aur"
+	alpm "github.com/Jguer/go-alpm/v2"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/db"
+)
+
+// Package holds the fields of a repo, local or AUR package available to
+// --format. Fields that do not apply to a package are left empty.
+type Package struct {
+	Repository       string     `json:"repository"`
+	Name             string     `json:"name"`
+	Base             string     `json:"base"`
+	Version          string     `json:"version"`
+	Description      string     `json:"description"`
+	URL              string     `json:"url"`
+	Architecture     string     `json:"architecture"`
+	Licenses         []string   `json:"licenses"`
+	Groups           []string   `json:"groups"`
+	Keywords         []string   `json:"keywords"`
+	Provides         []string   `json:"provides"`
+	Depends          []string   `json:"depends"`
+	MakeDepends      []string   `json:"makedepends"`
+	CheckDepends     []string   `json:"checkdepends"`
+	OptDepends       []string   `json:"optdepends"`
+	Conflicts        []string   `json:"conflicts"`
+	Replaces         []string   `json:"replaces"`
+	Size             int64      `json:"size"`
+	InstalledSize    int64      `json:"installedsize"`
+	Packager         string     `json:"packager"`
+	BuildDate        *time.Time `json:"builddate"`
+	InstallDate      *time.Time `json:"installdate"`
+	Reason           string     `json:"reason"`
+	ID               int        `json:"id"`
+	PackageBaseID    int        `json:"packagebaseid"`
+	Maintainer       string     `json:"maintainer"`
+	NumVotes         int        `json:"numvotes"`
+	Popularity       float64    `json:"popularity"`
+	OutOfDate        *time.Time `json:"outofdate"`
+	FirstSubmitted   *time.Time `json:"firstsubmitted"`
+	LastModified     *time.Time `json:"lastmodified"`
+	URLPath          string     `json:"urlpath"`
+	Installed        bool       `json:"installed"`
+	InstalledVersion string     `json:"installedversion"`
+}
+
+func unixTime(sec int) *time.Time {
+	if sec == 0 {
+		return nil
+	}
+
+	t := time.Unix(int64(sec), 0).UTC()
+
+	return &t
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() || t.Unix() == 0 {
+		return nil
+	}
+
+	t = t.UTC()
+
+	return &t
+}
+
+func depStrings(deps []alpm.Depend) []string {
+	result := make([]string, 0, len(deps))
+	for i := range deps {
+		result = append(result, deps[i].String())
+	}
+
+	return result
+}
+
+func orEmpty(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+
+	return s
+}
+
+func reasonString(reason alpm.PkgReason) string {
+	if reason == alpm.PkgReasonDepend {
+		return "dependency"
+	}
+
+	return "explicit"
+}
+
+// setInstalled fills the install status from the local package, if any.
+func (p *Package) setInstalled(local db.IPackage) {
+	if local == nil {
+		return
+	}
+
+	p.Installed = true
+	p.InstalledVersion = local.Version()
+	p.InstallDate = optionalTime(local.InstallDate())
+	p.Reason = reasonString(local.Reason())
+}
+
+// FromAUR converts an AUR package. local is the installed package, or nil.
+func FromAUR(pkg *aur.Pkg, local db.IPackage) Package {
+	result := Package{
+		Repository:     "aur",
+		Name:           pkg.Name,
+		Base:           pkg.PackageBase,
+		Version:        pkg.Version,
+		Description:    pkg.Description,
+		URL:            pkg.URL,
+		Licenses:       orEmpty(pkg.License),
+		Groups:         orEmpty(pkg.Groups),
+		Keywords:       orEmpty(pkg.Keywords),
+		Provides:       orEmpty(pkg.Provides),
+		Depends:        orEmpty(pkg.Depends),
+		MakeDepends:    orEmpty(pkg.MakeDepends),
+		CheckDepends:   orEmpty(pkg.CheckDepends),
+		OptDepends:     orEmpty(pkg.OptDepends),
+		Conflicts:      orEmpty(pkg.Conflicts),
+		Replaces:       orEmpty(pkg.Replaces),
+		ID:             pkg.ID,
+		PackageBaseID:  pkg.PackageBaseID,
+		Maintainer:     pkg.Maintainer,
+		NumVotes:       pkg.NumVotes,
+		Popularity:     pkg.Popularity,
+		OutOfDate:      unixTime(pkg.OutOfDate),
+		FirstSubmitted: unixTime(pkg.FirstSubmitted),
+		LastModified:   unixTime(pkg.LastModified),
+		URLPath:        pkg.URLPath,
+	}
+
+	result.setInstalled(local)
+
+	return result
+}
+
+// FromALPM converts a sync or local package. local is the installed package,
+// or nil, and is pkg itself for local packages.
+func FromALPM(pkg db.IPackage, local db.IPackage) Package {
+	result := Package{
+		Repository:    pkg.DB().Name(),
+		Name:          pkg.Name(),
+		Base:          pkg.Base(),
+		Version:       pkg.Version(),
+		Description:   pkg.Description(),
+		URL:           pkg.URL(),
+		Architecture:  pkg.Architecture(),
+		Licenses:      orEmpty(pkg.Licenses().Slice()),
+		Groups:        orEmpty(pkg.Groups().Slice()),
+		Keywords:      []string{},
+		Provides:      depStrings(pkg.Provides().Slice()),
+		Depends:       depStrings(pkg.Depends().Slice()),
+		MakeDepends:   depStrings(pkg.MakeDepends().Slice()),
+		CheckDepends:  depStrings(pkg.CheckDepends().Slice()),
+		OptDepends:    depStrings(pkg.OptionalDepends().Slice()),
+		Conflicts:     depStrings(pkg.Conflicts().Slice()),
+		Replaces:      depStrings(pkg.Replaces().Slice()),
+		Size:          pkg.Size(),
+		InstalledSize: pkg.ISize(),
+		Packager:      pkg.Packager(),
+		BuildDate:     optionalTime(pkg.BuildDate()),
+	}
+
+	result.setInstalled(local)
+
+	return result
+}
+
+// Printer prints packages as a JSON array or with a Go template.
+type Printer struct {
+	tmpl *template.Template
+}
+
+// NewPrinter parses format, either json or a text/template executed for
+// every package.
+func NewPrinter(format string) (*Printer, error) {
+	if format == "" {
+		return nil, errors.New(gotext.Get("--format requires json or a template"))
+	}
+
+	if format == "json" {
+		return &Printer{}, nil
+	}
+
+	tmpl, err := template.New("format").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(format)
+	if err != nil {
+		return nil, errors.New(gotext.Get("invalid format template: %s", err))
+	}
+
+	return &Printer{tmpl: tmpl}, nil
+}
+
+// Print writes pkgs to w. Template output is terminated by a newline.
+func (p *Printer) Print(w io.Writer, pkgs []Package) error {
+	if p.tmpl == nil {
+		if pkgs == nil {
+			pkgs = []Package{}
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+
+		return encoder.Encode(pkgs)
+	}
+
+	for i := range pkgs {
+		var sb strings.Builder
+		if err := p.tmpl.Execute(&sb, &pkgs[i]); err != nil {
+			return err
+		}
+
+		out := sb.String()
+		if !strings.HasSuffix(out, "\n") {
+			out += "\n"
+		}
+
+		if _, err := fmt.Fprint(w, out); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
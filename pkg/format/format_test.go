@@ -0,0 +1,119 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Jguer/aur"
+	alpm "github.com/Jguer/go-alpm/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v11/pkg/db"
+	"github.com/Jguer/yay/v11/pkg/db/mock"
+)
+
+func TestFromAUR(t *testing.T) {
+	t.Parallel()
+
+	installDate := time.Date(2021, time.March, 4, 10, 0, 0, 0, time.UTC)
+	pkg := &aur.Pkg{
+		Name:         "yay",
+		PackageBase:  "yay",
+		Version:      "11.0.0-1",
+		Description:  "Yet another yogurt",
+		Depends:      []string{"pacman>5", "git"},
+		Maintainer:   "jguer",
+		NumVotes:     1500,
+		Popularity:   20.5,
+		LastModified: 1614852000,
+	}
+
+	testCases := []struct {
+		desc  string
+		local db.IPackage
+		want  func(p *Package)
+	}{
+		{
+			desc:  "not installed",
+			local: nil,
+			want: func(p *Package) {
+				assert.False(t, p.Installed)
+				assert.Empty(t, p.InstalledVersion)
+				assert.Nil(t, p.InstallDate)
+				assert.Empty(t, p.Reason)
+			},
+		},
+		{
+			desc:  "installed as a dependency",
+			local: &mock.Package{PName: "yay", PVersion: "10.3.0-1", PInstallDate: installDate, PReason: alpm.PkgReasonDepend},
+			want: func(p *Package) {
+				assert.True(t, p.Installed)
+				assert.Equal(t, "10.3.0-1", p.InstalledVersion)
+				assert.Equal(t, &installDate, p.InstallDate)
+				assert.Equal(t, "dependency", p.Reason)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		got := FromAUR(pkg, tc.local)
+
+		assert.Equal(t, "aur", got.Repository, tc.desc)
+		assert.Equal(t, "yay", got.Name, tc.desc)
+		assert.Equal(t, []string{"pacman>5", "git"}, got.Depends, tc.desc)
+		assert.Equal(t, []string{}, got.MakeDepends, tc.desc)
+		assert.Equal(t, 1500, got.NumVotes, tc.desc)
+		assert.Nil(t, got.OutOfDate, tc.desc)
+		assert.Equal(t, int64(1614852000), got.LastModified.Unix(), tc.desc)
+		tc.want(&got)
+	}
+}
+
+func TestPrinterJSON(t *testing.T) {
+	t.Parallel()
+
+	printer, err := NewPrinter("json")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, printer.Print(&buf, []Package{{Repository: "aur", Name: "yay", Installed: true}}))
+
+	var got []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Len(t, got, 1)
+	assert.Equal(t, "yay", got[0]["name"])
+	assert.Equal(t, "aur", got[0]["repository"])
+	assert.Equal(t, true, got[0]["installed"])
+	assert.Nil(t, got[0]["outofdate"])
+
+	buf.Reset()
+	assert.NoError(t, printer.Print(&buf, nil))
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestPrinterTemplate(t *testing.T) {
+	t.Parallel()
+
+	printer, err := NewPrinter(`{{.Repository}}/{{.Name}} {{join .Depends ","}}`)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = printer.Print(&buf, []Package{
+		{Repository: "aur", Name: "yay", Depends: []string{"pacman", "git"}},
+		{Repository: "extra", Name: "git", Depends: []string{}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "aur/yay pacman,git\nextra/git \n", buf.String())
+}
+
+func TestNewPrinterInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []string{"", "{{.Name", "{{.Name | unknown}}"} {
+		printer, err := NewPrinter(format)
+		assert.Error(t, err, format)
+		assert.Nil(t, printer, format)
+	}
+}
@@ -0,0 +1,266 @@
+package metadata
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jguer/aur"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+// dumpName is the aurweb metadata dump holding every AUR package.
+const dumpName = "packages-meta-ext-v1.json.gz"
+
+// Client answers AUR search and info queries from a local copy of the aurweb
+// metadata dump. The dump is downloaded again once it is older than Interval
+// days, and Fallback is queried when it is missing or can not be refreshed.
+// In Offline mode the local dump is always used and Fallback never is.
+type Client struct {
+	Fallback   aur.ClientInterface
+	HTTPClient *http.Client
+	AURURL     string
+	Path       string
+	Interval   int
+	Offline    bool
+
+	once    sync.Once
+	pkgs    []aur.Pkg
+	byName  map[string]*aur.Pkg
+	loadErr error
+}
+
+// NewClient creates a Client storing the dump in dumpPath.
+func NewClient(fallback aur.ClientInterface, httpClient *http.Client,
+	aurURL, dumpPath string, interval int, offline bool) *Client {
+	return &Client{
+		Fallback:   fallback,
+		HTTPClient: httpClient,
+		AURURL:     aurURL,
+		Path:       dumpPath,
+		Interval:   interval,
+		Offline:    offline,
+	}
+}
+
+// NeedsUpdate reports whether the local dump is missing or older than Interval.
+func (c *Client) NeedsUpdate() bool {
+	info, err := os.Stat(c.Path)
+	if err != nil {
+		return true
+	}
+
+	return c.Interval != -1 && time.Since(info.ModTime()).Hours() >= float64(c.Interval*24)
+}
+
+// Update downloads the metadata dump when it needs updating or force is set.
+func (c *Client) Update(ctx context.Context, force bool) error {
+	if !force && !c.NeedsUpdate() {
+		return nil
+	}
+
+	u, err := url.Parse(c.AURURL)
+	if err != nil {
+		return err
+	}
+
+	u.Path = path.Join(u.Path, dumpName)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("invalid status code: %d", resp.StatusCode)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(c.Path), 0o755); err != nil {
+		return err
+	}
+
+	tmpPath := c.Path + ".tmp"
+
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, resp.Body)
+	if errClose := out.Close(); err == nil {
+		err = errClose
+	}
+
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, c.Path)
+}
+
+func (c *Client) readDump() error {
+	in, err := os.Open(c.Path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	var pkgs []aur.Pkg
+	if err := json.NewDecoder(gz).Decode(&pkgs); err != nil {
+		return err
+	}
+
+	c.pkgs = pkgs
+	c.byName = make(map[string]*aur.Pkg, len(pkgs))
+
+	for i := range c.pkgs {
+		c.byName[c.pkgs[i].Name] = &c.pkgs[i]
+	}
+
+	return nil
+}
+
+// load refreshes the dump if needed and indexes it, only once per Client.
+func (c *Client) load(ctx context.Context) error {
+	c.once.Do(func() {
+		if !c.Offline && c.NeedsUpdate() {
+			c.loadErr = c.Update(ctx, false)
+		}
+
+		if c.loadErr == nil {
+			c.loadErr = c.readDump()
+		}
+
+		if c.loadErr != nil && !c.Offline {
+			text.Warnln(gotext.Get("unable to use the AUR metadata, falling back to the RPC: %s", c.loadErr))
+		}
+	})
+
+	return c.loadErr
+}
+
+// available loads the dump and reports whether queries can be answered from it.
+func (c *Client) available(ctx context.Context) (bool, error) {
+	err := c.load(ctx)
+	if err == nil {
+		return true, nil
+	}
+
+	if c.Offline {
+		if os.IsNotExist(err) {
+			return false, errors.New(gotext.Get("no offline AUR metadata available, run yay without --offline first"))
+		}
+
+		return false, errors.New(gotext.Get("unable to read offline AUR metadata: %s", err))
+	}
+
+	return false, nil
+}
+
+// Info returns the packages named pkgs.
+func (c *Client) Info(ctx context.Context, pkgs []string, reqEditors ...aur.RequestEditorFn) ([]aur.Pkg, error) {
+	ok, err := c.available(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return c.Fallback.Info(ctx, pkgs, reqEditors...)
+	}
+
+	results := make([]aur.Pkg, 0, len(pkgs))
+
+	for _, name := range pkgs {
+		if pkg, found := c.byName[name]; found {
+			results = append(results, *pkg)
+		}
+	}
+
+	return results, nil
+}
+
+// Search returns the packages matching query the way the RPC does.
+func (c *Client) Search(ctx context.Context, query string, by aur.By, reqEditors ...aur.RequestEditorFn) ([]aur.Pkg, error) {
+	ok, err := c.available(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return c.Fallback.Search(ctx, query, by, reqEditors...)
+	}
+
+	query = strings.ToLower(query)
+	results := make([]aur.Pkg, 0)
+
+	for i := range c.pkgs {
+		if Matches(&c.pkgs[i], query, by) {
+			results = append(results, c.pkgs[i])
+		}
+	}
+
+	return results, nil
+}
+
+// Matches reports whether pkg is a result of the lowercase query.
+func Matches(pkg *aur.Pkg, query string, by aur.By) bool {
+	switch by {
+	case aur.Name:
+		return strings.Contains(strings.ToLower(pkg.Name), query)
+	case aur.Maintainer:
+		return strings.ToLower(pkg.Maintainer) == query
+	case aur.Depends:
+		return hasDep(pkg.Depends, query)
+	case aur.MakeDepends:
+		return hasDep(pkg.MakeDepends, query)
+	case aur.OptDepends:
+		return hasDep(pkg.OptDepends, query)
+	case aur.CheckDepends:
+		return hasDep(pkg.CheckDepends, query)
+	default:
+		return strings.Contains(strings.ToLower(pkg.Name), query) ||
+			strings.Contains(strings.ToLower(pkg.Description), query)
+	}
+}
+
+// hasDep reports whether deps contains name, ignoring version constraints
+// and optional dependency descriptions.
+func hasDep(deps []string, name string) bool {
+	for _, dep := range deps {
+		if i := strings.IndexAny(dep, "<>=:"); i != -1 {
+			dep = dep[:i]
+		}
+
+		if strings.ToLower(strings.TrimSpace(dep)) == name {
+			return true
+		}
+	}
+
+	return false
+}
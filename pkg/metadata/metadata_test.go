@@ -0,0 +1,185 @@
+package metadata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jguer/aur"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+const sampleDump = `[
+{"ID":1,"Name":"yay","PackageBase":"yay","Version":"11.0.2-1","Description":"Yet another yogurt. Pacman wrapper and AUR helper written in go.",
+"Maintainer":"jguer","Depends":["pacman>5","git"],"MakeDepends":["go>=1.17"],"OptDepends":["sudo: privilege elevation"]},
+{"ID":2,"Name":"yay-bin","PackageBase":"yay-bin","Version":"11.0.2-1","Description":"Yet another yogurt. Pre-compiled.",
+"Maintainer":"jguer","Depends":["pacman>5","git"]},
+{"ID":3,"Name":"paru","PackageBase":"paru","Version":"1.9.2-1","Description":"Feature packed AUR helper",
+"Maintainer":"Morganamilo","Depends":["git","pacman"],"MakeDepends":["cargo"],"CheckDepends":["rust"]}
+]`
+
+type mockFallback struct {
+	infoCalls   int
+	searchCalls int
+}
+
+func (m *mockFallback) Search(ctx context.Context, query string, by aur.By,
+	reqEditors ...aur.RequestEditorFn) ([]aur.Pkg, error) {
+	m.searchCalls++
+	return []aur.Pkg{{Name: "rpc"}}, nil
+}
+
+func (m *mockFallback) Info(ctx context.Context, pkgs []string, reqEditors ...aur.RequestEditorFn) ([]aur.Pkg, error) {
+	m.infoCalls++
+	return []aur.Pkg{{Name: "rpc"}}, nil
+}
+
+func gzipDump(t *testing.T, dump string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(dump))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+
+	return buf.Bytes()
+}
+
+func writeDump(t *testing.T, dump string) string {
+	t.Helper()
+
+	dumpPath := filepath.Join(t.TempDir(), dumpName)
+	assert.NoError(t, os.WriteFile(dumpPath, gzipDump(t, dump), 0o644))
+
+	return dumpPath
+}
+
+func names(pkgs []aur.Pkg) []string {
+	result := make([]string, 0, len(pkgs))
+	for i := range pkgs {
+		result = append(result, pkgs[i].Name)
+	}
+
+	return result
+}
+
+func TestClientSearch(t *testing.T) {
+	t.Parallel()
+
+	fallback := &mockFallback{}
+	client := NewClient(fallback, &http.Client{}, "https://aur.archlinux.org", writeDump(t, sampleDump), 7, false)
+
+	type testCase struct {
+		query string
+		by    aur.By
+		want  []string
+	}
+
+	testCases := []testCase{
+		{query: "yay", by: aur.Name, want: []string{"yay", "yay-bin"}},
+		{query: "YOGURT", by: aur.NameDesc, want: []string{"yay", "yay-bin"}},
+		{query: "helper", by: aur.None, want: []string{"yay", "paru"}},
+		{query: "helper", by: aur.Name, want: []string{}},
+		{query: "morganamilo", by: aur.Maintainer, want: []string{"paru"}},
+		{query: "pacman", by: aur.Depends, want: []string{"yay", "yay-bin", "paru"}},
+		{query: "go", by: aur.MakeDepends, want: []string{"yay"}},
+		{query: "sudo", by: aur.OptDepends, want: []string{"yay"}},
+		{query: "rust", by: aur.CheckDepends, want: []string{"paru"}},
+	}
+
+	for _, tc := range testCases {
+		pkgs, err := client.Search(context.Background(), tc.query, tc.by)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, names(pkgs), "%s by %s", tc.query, tc.by)
+	}
+
+	assert.Zero(t, fallback.searchCalls)
+}
+
+func TestClientInfo(t *testing.T) {
+	t.Parallel()
+
+	fallback := &mockFallback{}
+	client := NewClient(fallback, &http.Client{}, "https://aur.archlinux.org", writeDump(t, sampleDump), 7, false)
+
+	pkgs, err := client.Info(context.Background(), []string{"paru", "missing", "yay"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"paru", "yay"}, names(pkgs))
+	assert.Equal(t, "1.9.2-1", pkgs[0].Version)
+	assert.Equal(t, []string{"cargo"}, pkgs[0].MakeDepends)
+	assert.Zero(t, fallback.infoCalls)
+}
+
+func TestClientStaleFallback(t *testing.T) {
+	dumpPath := writeDump(t, sampleDump)
+	old := time.Now().Add(-48 * time.Hour)
+	assert.NoError(t, os.Chtimes(dumpPath, old, old))
+
+	gock.New("https://aur.archlinux.org").
+		Get("/" + dumpName).
+		Reply(503)
+
+	defer gock.Off()
+
+	fallback := &mockFallback{}
+	client := NewClient(fallback, &http.Client{}, "https://aur.archlinux.org", dumpPath, 1, false)
+
+	pkgs, err := client.Info(context.Background(), []string{"yay"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rpc"}, names(pkgs))
+	assert.Equal(t, 1, fallback.infoCalls)
+}
+
+func TestClientUpdate(t *testing.T) {
+	dumpPath := filepath.Join(t.TempDir(), "cache", dumpName)
+
+	gock.New("https://aur.archlinux.org").
+		Get("/" + dumpName).
+		Reply(200).
+		Body(bytes.NewReader(gzipDump(t, sampleDump)))
+
+	defer gock.Off()
+
+	fallback := &mockFallback{}
+	client := NewClient(fallback, &http.Client{}, "https://aur.archlinux.org", dumpPath, 1, false)
+	assert.True(t, client.NeedsUpdate())
+
+	pkgs, err := client.Search(context.Background(), "paru", aur.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"paru"}, names(pkgs))
+	assert.Zero(t, fallback.searchCalls)
+	assert.False(t, client.NeedsUpdate())
+	assert.True(t, gock.IsDone())
+}
+
+func TestClientOffline(t *testing.T) {
+	t.Parallel()
+
+	fallback := &mockFallback{}
+
+	// a stale dump is still used offline
+	dumpPath := writeDump(t, sampleDump)
+	old := time.Now().Add(-48 * time.Hour)
+	assert.NoError(t, os.Chtimes(dumpPath, old, old))
+
+	client := NewClient(fallback, &http.Client{}, "https://aur.archlinux.org", dumpPath, 1, true)
+
+	pkgs, err := client.Info(context.Background(), []string{"yay"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"yay"}, names(pkgs))
+
+	missing := NewClient(fallback, &http.Client{}, "https://aur.archlinux.org",
+		filepath.Join(t.TempDir(), dumpName), 1, true)
+
+	_, err = missing.Search(context.Background(), "yay", aur.Name)
+	assert.Error(t, err)
+	assert.Zero(t, fallback.infoCalls+fallback.searchCalls)
+}
@@ -1,17 +1,19 @@
 package news
 
 import (
-	"bytes"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"html"
 	"io"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/leonelquinteros/gotext"
+
 	"github.com/Jguer/yay/v11/pkg/settings"
 	"github.com/Jguer/yay/v11/pkg/text"
 )
@@ -22,28 +24,45 @@ type item struct {
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
 	Creator     string `xml:"dc:creator"`
+	GUID        string `xml:"guid"`
+
+	date   time.Time
+	feed   string
+	cutOff time.Time
+}
+
+// id returns the identifier used to remember the item as read.
+func (item *item) id() string {
+	if item.GUID != "" {
+		return item.GUID
+	}
+
+	return item.Link
 }
 
-func (item *item) print(buildTime time.Time, all, quiet bool) {
+// isOld reports whether the item predates the cut off date of its feed.
+func (item *item) isOld() bool {
+	return !item.cutOff.IsZero() && !item.date.IsZero() && item.cutOff.After(item.date)
+}
+
+func (item *item) print(showFeed, quiet bool) {
 	var fd string
 
-	date, err := time.Parse(time.RFC1123Z, item.PubDate)
+	if !item.date.IsZero() {
+		fd = text.FormatTime(int(item.date.Unix()))
+	}
+
+	// titles come from the network like descriptions
+	title := text.StripControl(strings.TrimSpace(item.Title))
 
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
+	if showFeed {
+		fmt.Println(text.Bold(text.Magenta(fd)), text.Cyan("["+text.StripControl(item.feed)+"]"), text.Bold(title))
 	} else {
-		fd = text.FormatTime(int(date.Unix()))
-		if !all && !buildTime.IsZero() {
-			if buildTime.After(date) {
-				return
-			}
-		}
+		fmt.Println(text.Bold(text.Magenta(fd)), text.Bold(title))
 	}
 
-	fmt.Println(text.Bold(text.Magenta(fd)), text.Bold(strings.TrimSpace(item.Title)))
-
 	if !quiet {
-		desc := strings.TrimSpace(parseNews(item.Description))
+		desc := strings.TrimSpace(text.ParseHTML(item.Description))
 		fmt.Println(desc)
 	}
 }
@@ -61,115 +80,221 @@ type rss struct {
 	Channel channel `xml:"channel"`
 }
 
-func PrintNewsFeed(ctx context.Context, client *http.Client, cutOffDate time.Time, sortMode int, all, quiet bool) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://archlinux.org/feeds/news", nil)
-	if err != nil {
-		return err
-	}
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	ID        string     `xml:"id"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Summary   string     `xml:"summary"`
+	Content   string     `xml:"content"`
+	Author    string     `xml:"author>name"`
+}
+
+type atom struct {
+	Entries []atomEntry `xml:"entry"`
+}
+
+func (entry *atomEntry) toItem() item {
+	newItem := item{
+		Title:       entry.Title,
+		Description: entry.Content,
+		Creator:     entry.Author,
+		GUID:        entry.ID,
 	}
 
-	defer resp.Body.Close()
+	if newItem.Description == "" {
+		newItem.Description = entry.Summary
+	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
+	for _, link := range entry.Links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			newItem.Link = link.Href
+			break
+		}
 	}
 
-	rssGot := rss{}
+	published := entry.Published
+	if published == "" {
+		published = entry.Updated
+	}
 
-	d := xml.NewDecoder(bytes.NewReader(body))
-	if err := d.Decode(&rssGot); err != nil {
-		return err
+	if date, err := time.Parse(time.RFC3339, strings.TrimSpace(published)); err == nil {
+		newItem.date = date
 	}
 
-	if sortMode == settings.BottomUp {
-		for i := len(rssGot.Channel.Items) - 1; i >= 0; i-- {
-			rssGot.Channel.Items[i].print(cutOffDate, all, quiet)
+	return newItem
+}
+
+// parseFeed decodes an RSS 2.0 or Atom document.
+// The format, in any case, is detected from the root element when empty.
+func parseFeed(body []byte, format string) ([]item, error) {
+	format = strings.ToLower(format)
+
+	if format == "" {
+		root := struct {
+			XMLName xml.Name
+		}{}
+		if err := xml.Unmarshal(body, &root); err != nil {
+			return nil, err
 		}
-	} else {
-		for i := 0; i < len(rssGot.Channel.Items); i++ {
-			rssGot.Channel.Items[i].print(cutOffDate, all, quiet)
+
+		format = root.XMLName.Local
+		if format == "feed" {
+			format = "atom"
 		}
 	}
 
-	return nil
-}
-
-// Crude html parsing, good enough for the arch news
-// This is only displayed in the terminal so there should be no security
-// concerns.
-func parseNews(str string) string {
-	var (
-		buffer       bytes.Buffer
-		tagBuffer    bytes.Buffer
-		escapeBuffer bytes.Buffer
-		inTag        = false
-		inEscape     = false
-	)
+	switch format {
+	case "rss":
+		rssGot := rss{}
+		if err := xml.Unmarshal(body, &rssGot); err != nil {
+			return nil, err
+		}
 
-	for _, char := range str {
-		if inTag {
-			if char == '>' {
-				inTag = false
+		items := rssGot.Channel.Items
+		for i := range items {
+			pubDate := strings.TrimSpace(items[i].PubDate)
 
-				switch tagBuffer.String() {
-				case "code":
-					buffer.WriteString(text.CyanCode)
-				case "/code":
-					buffer.WriteString(text.ResetCode)
-				case "/p":
-					buffer.WriteRune('\n')
-				}
+			date, err := time.Parse(time.RFC1123Z, pubDate)
+			if err != nil {
+				date, err = time.Parse(time.RFC1123, pubDate)
+			}
 
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
 				continue
 			}
 
-			tagBuffer.WriteRune(char)
+			items[i].date = date
+		}
 
-			continue
+		return items, nil
+	case "atom":
+		atomGot := atom{}
+		if err := xml.Unmarshal(body, &atomGot); err != nil {
+			return nil, err
+		}
+
+		items := make([]item, 0, len(atomGot.Entries))
+		for i := range atomGot.Entries {
+			items = append(items, atomGot.Entries[i].toItem())
 		}
 
-		if inEscape {
-			if char == ';' {
-				inEscape = false
+		return items, nil
+	}
 
-				escapeBuffer.WriteRune(char)
-				s := html.UnescapeString(escapeBuffer.String())
-				buffer.WriteString(s)
+	return nil, errors.New(gotext.Get("unknown feed format: %s", format))
+}
 
-				continue
-			}
+func fetchFeed(ctx context.Context, client *http.Client, feed *settings.NewsFeed) ([]item, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", feed.URL, nil)
+	if err != nil {
+		return nil, err
+	}
 
-			escapeBuffer.WriteRune(char)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
 
-			continue
-		}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseFeed(body, feed.Format)
+}
+
+// fetchFeeds fetches every feed and merges their items from newest to oldest.
+// Unreachable feeds are skipped with a warning, an error is only returned when
+// no feed could be fetched.
+func fetchFeeds(ctx context.Context, client *http.Client, feeds []settings.NewsFeed, cutOffDate time.Time) ([]item, error) {
+	var (
+		items   []item
+		lastErr error
+		fetched = 0
+	)
 
-		if char == '<' {
-			inTag = true
+	for i := range feeds {
+		feed := &feeds[i]
 
-			tagBuffer.Reset()
+		feedItems, err := fetchFeed(ctx, client, feed)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", feed.Name, err)
+
+			if len(feeds) > 1 {
+				text.Warnln(gotext.Get("unable to fetch news feed %s: %s", text.Cyan(feed.Name), err))
+			}
 
 			continue
 		}
 
-		if char == '&' {
-			inEscape = true
+		fetched++
 
-			escapeBuffer.Reset()
-			escapeBuffer.WriteRune(char)
+		feedCutOff := cutOffDate
+		if feed.MaxAge > 0 {
+			feedCutOff = time.Now().AddDate(0, 0, -feed.MaxAge)
+		}
 
-			continue
+		for j := range feedItems {
+			feedItems[j].feed = feed.Name
+			feedItems[j].cutOff = feedCutOff
 		}
 
-		buffer.WriteRune(char)
+		items = append(items, feedItems...)
+	}
+
+	if fetched == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	if len(feeds) > 1 {
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].date.After(items[j].date)
+		})
+	}
+
+	return items, nil
+}
+
+// PrintNewsFeed prints the news of feeds. Only the items newer than the cut
+// off date of their feed are printed unless all is set.
+func PrintNewsFeed(ctx context.Context, client *http.Client, feeds []settings.NewsFeed,
+	cutOffDate time.Time, sortMode int, all, quiet bool) error {
+	items, err := fetchFeeds(ctx, client, feeds, cutOffDate)
+	if err != nil {
+		return err
+	}
+
+	showFeed := len(feeds) > 1
+
+	printItem := func(item *item) {
+		if all || !item.isOld() {
+			item.print(showFeed, quiet)
+		}
 	}
 
-	buffer.WriteString(text.ResetCode)
+	if sortMode == settings.BottomUp {
+		for i := len(items) - 1; i >= 0; i-- {
+			printItem(&items[i])
+		}
+	} else {
+		for i := 0; i < len(items); i++ {
+			printItem(&items[i])
+		}
+	}
 
-	return buffer.String()
+	return nil
 }
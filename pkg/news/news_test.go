@@ -11,8 +11,12 @@ import (
 	"github.com/bradleyjkemp/cupaloy"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/h2non/gock.v1"
+
+	"github.com/Jguer/yay/v11/pkg/settings"
 )
 
+var archFeed = []settings.NewsFeed{settings.DefaultNewsFeed}
+
 const lastNews = `
 <rss xmlns:atom="http://www.w3.org/2005/Atom" version="2.0">
    <channel>
@@ -135,7 +139,7 @@ func TestPrintNewsFeed(t *testing.T) {
 			r, w, _ := os.Pipe()
 			os.Stdout = w
 
-			err := PrintNewsFeed(context.TODO(), &http.Client{}, tt.args.cutOffDate, tt.args.sortMode, tt.args.all, tt.args.quiet)
+			err := PrintNewsFeed(context.TODO(), &http.Client{}, archFeed, tt.args.cutOffDate, tt.args.sortMode, tt.args.all, tt.args.quiet)
 			assert.NoError(t, err)
 
 			w.Close()
@@ -164,7 +168,131 @@ func TestPrintNewsFeedSameDay(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := PrintNewsFeed(context.TODO(), &http.Client{}, lastNewsTime, 0, false, false)
+	err := PrintNewsFeed(context.TODO(), &http.Client{}, archFeed, lastNewsTime, 0, false, false)
+	assert.NoError(t, err)
+
+	w.Close()
+	out, _ := io.ReadAll(r)
+	cupaloy.SnapshotT(t, out)
+	os.Stdout = rescueStdout
+}
+
+const atomNews = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Infra news</title>
+  <id>urn:example:infra</id>
+  <updated>2020-04-15T09:00:00Z</updated>
+  <entry>
+    <title>Mirror maintenance</title>
+    <link rel="alternate" href="https://example.org/news/mirror-maintenance"/>
+    <id>urn:example:infra:mirror-maintenance</id>
+    <published>2020-04-15T09:00:00Z</published>
+    <updated>2020-04-15T10:00:00Z</updated>
+    <author><name>Infra Team</name></author>
+    <summary>Mirror unavailable.</summary>
+    <content type="html">&lt;p&gt;The internal mirror is unavailable, use &lt;code&gt;pacman -Syu&lt;/code&gt; later.&lt;/p&gt;</content>
+  </entry>
+  <entry>
+    <title>Old announcement</title>
+    <link href="https://example.org/news/old"/>
+    <id>urn:example:infra:old</id>
+    <updated>2020-01-02T09:00:00Z</updated>
+    <summary>Nothing to see.</summary>
+  </entry>
+</feed>
+`
+
+func TestParseFeed(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		desc      string
+		body      string
+		format    string
+		wantTitle string
+		wantLen   int
+		wantErr   bool
+	}
+
+	testCases := []testCase{
+		{desc: "rss", body: lastNews, format: "rss", wantTitle: "zn_poly 0.9.2-2 update requires manual intervention", wantLen: 1},
+		{desc: "rss detected", body: sampleNews, wantTitle: "zn_poly 0.9.2-2 update requires manual intervention", wantLen: 10},
+		{desc: "atom", body: atomNews, format: "atom", wantTitle: "Mirror maintenance", wantLen: 2},
+		{desc: "atom detected", body: atomNews, wantTitle: "Mirror maintenance", wantLen: 2},
+		{desc: "rss upper case", body: lastNews, format: "RSS", wantTitle: "zn_poly 0.9.2-2 update requires manual intervention", wantLen: 1},
+		{desc: "atom capitalized", body: atomNews, format: "Atom", wantTitle: "Mirror maintenance", wantLen: 2},
+		{desc: "unknown format", body: atomNews, format: "json", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			items, err := parseFeed([]byte(tc.body), tc.format)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, items, tc.wantLen)
+			assert.Equal(t, tc.wantTitle, items[0].Title)
+			assert.False(t, items[0].date.IsZero())
+			assert.NotEmpty(t, items[0].id())
+		})
+	}
+}
+
+func TestParseFeedAtomEntry(t *testing.T) {
+	t.Parallel()
+
+	items, err := parseFeed([]byte(atomNews), "atom")
+	assert.NoError(t, err)
+
+	published, _ := time.Parse(time.RFC3339, "2020-04-15T09:00:00Z")
+	updated, _ := time.Parse(time.RFC3339, "2020-01-02T09:00:00Z")
+
+	assert.Equal(t, "https://example.org/news/mirror-maintenance", items[0].Link)
+	assert.Equal(t, "urn:example:infra:mirror-maintenance", items[0].GUID)
+	assert.Equal(t, "Infra Team", items[0].Creator)
+	assert.Contains(t, items[0].Description, "internal mirror")
+	assert.True(t, published.Equal(items[0].date))
+
+	assert.Equal(t, "Nothing to see.", items[1].Description)
+	assert.True(t, updated.Equal(items[1].date))
+}
+
+// GIVEN an RSS feed, an Atom feed and an unreachable feed
+// THEN the reachable feeds should be merged by date
+func TestPrintNewsFeedMultiple(t *testing.T) {
+	cutOffDate, _ := time.Parse(time.RFC3339, "2020-04-01T00:00:00Z")
+
+	feeds := []settings.NewsFeed{
+		settings.DefaultNewsFeed,
+		{Name: "infra", URL: "https://example.org/news.atom", Format: "atom"},
+		{Name: "broken", URL: "https://broken.example.org/news"},
+	}
+
+	gock.New("https://archlinux.org").
+		Get("/feeds/news").
+		Reply(200).
+		BodyString(lastNews)
+	gock.New("https://example.org").
+		Get("/news.atom").
+		Reply(200).
+		BodyString(atomNews)
+	gock.New("https://broken.example.org").
+		Get("/news").
+		Reply(500)
+
+	defer gock.Off()
+
+	rescueStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := PrintNewsFeed(context.TODO(), &http.Client{}, feeds, cutOffDate, 0, false, true)
 	assert.NoError(t, err)
 
 	w.Close()
@@ -172,3 +300,38 @@ func TestPrintNewsFeedSameDay(t *testing.T) {
 	cupaloy.SnapshotT(t, out)
 	os.Stdout = rescueStdout
 }
+
+func TestItemPrintControl(t *testing.T) {
+	entry := item{
+		Title:       "Screen \x1b]0;pwned\x07\u009b2Jcleared",
+		Description: "<p>Fine</p>",
+		feed:        "arch\x1b[2J",
+	}
+
+	rescueStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	entry.print(true, false)
+
+	w.Close()
+	out, _ := io.ReadAll(r)
+	os.Stdout = rescueStdout
+
+	assert.Contains(t, string(out), "Screen ]0;pwned2Jcleared")
+	assert.Contains(t, string(out), "[arch[2J]")
+	assert.NotContains(t, string(out), "\x07")
+	assert.NotContains(t, string(out), "\u009b")
+	assert.NotContains(t, string(out), "\x1b]")
+}
+
+func TestPrintNewsFeedUnreachable(t *testing.T) {
+	gock.New("https://archlinux.org").
+		Get("/feeds/news").
+		Reply(500)
+
+	defer gock.Off()
+
+	err := PrintNewsFeed(context.TODO(), &http.Client{}, archFeed, time.Time{}, 0, false, true)
+	assert.Error(t, err)
+}
@@ -0,0 +1,180 @@
+package news
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/settings"
+	"github.com/Jguer/yay/v11/pkg/stringset"
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+// ReadStore remembers the news items that have been read.
+// Items are stored by GUID along with their publication date.
+type ReadStore struct {
+	Read     map[string]time.Time
+	FilePath string
+}
+
+func NewReadStore(filePath string) *ReadStore {
+	return &ReadStore{
+		Read:     map[string]time.Time{},
+		FilePath: filePath,
+	}
+}
+
+// Load reads the read news file and populates the ReadStore.
+func (s *ReadStore) Load() error {
+	rfile, err := os.Open(s.FilePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to open news file '%s': %s", s.FilePath, err)
+	}
+
+	defer rfile.Close()
+
+	if err = json.NewDecoder(rfile).Decode(&s.Read); err != nil {
+		return fmt.Errorf("failed to read news '%s': %s", s.FilePath, err)
+	}
+
+	return nil
+}
+
+func (s *ReadStore) Save() error {
+	marshalledRead, err := json.MarshalIndent(s.Read, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.FilePath, marshalledRead, 0o644)
+}
+
+func (s *ReadStore) markRead(items []item) {
+	for i := range items {
+		s.Read[items[i].id()] = items[i].date
+	}
+}
+
+// unread returns the items newer than the cut off date of their feed that
+// have not been read.
+func (s *ReadStore) unread(items []item) []item {
+	unread := make([]item, 0)
+
+	for i := range items {
+		if _, ok := s.Read[items[i].id()]; ok {
+			continue
+		}
+
+		if items[i].isOld() {
+			continue
+		}
+
+		unread = append(unread, items[i])
+	}
+
+	return unread
+}
+
+func isPkgNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("@._+-", r)
+}
+
+// mentions returns the package names of pkgNames the item mentions in its
+// title or description, either by name or as name-version.
+func (item *item) mentions(pkgNames stringset.StringSet) []string {
+	plain := strings.NewReplacer(text.CyanCode, " ", text.ResetCode, " ").
+		Replace(text.ParseHTML(item.Description))
+
+	found := make(stringset.StringSet)
+
+	for _, word := range strings.FieldsFunc(strings.ToLower(item.Title+" "+plain),
+		func(r rune) bool { return !isPkgNameRune(r) }) {
+		word = strings.TrimRight(word, ".")
+
+		if pkgNames.Get(word) {
+			found.Set(word)
+			continue
+		}
+
+		// name-pkgver such as openssh-8.2p1
+		for i := strings.LastIndexByte(word, '-'); i > 0; i = strings.LastIndexByte(word[:i], '-') {
+			if i+1 < len(word) && unicode.IsDigit(rune(word[i+1])) && pkgNames.Get(word[:i]) {
+				found.Set(word[:i])
+				break
+			}
+		}
+	}
+
+	return found.ToSlice()
+}
+
+// CheckUnread shows the unread news before a system upgrade and asks the user
+// to confirm. Unread items mentioning one of pendingNames are flagged as
+// requiring manual intervention, which defaults the prompt to no. With
+// noConfirm the upgrade only stops on them when stop is set. Confirmed items
+// are marked as read.
+func CheckUnread(ctx context.Context, client *http.Client, feeds []settings.NewsFeed, store *ReadStore,
+	cutOffDate time.Time, pendingNames []string, sortMode int, stop, noConfirm bool) error {
+	items, err := fetchFeeds(ctx, client, feeds, cutOffDate)
+	if err != nil {
+		// an unreachable feed should not block upgrades
+		text.Warnln(gotext.Get("unable to check news: %s", err))
+
+		return nil
+	}
+
+	unread := store.unread(items)
+	if len(unread) == 0 {
+		return nil
+	}
+
+	text.OperationInfoln(gotext.Get("There are %d unread news items", len(unread)))
+
+	pending := stringset.FromSlice(pendingNames)
+	intervention := false
+
+	show := func(item *item) {
+		item.print(len(feeds) > 1, false)
+
+		if mentioned := item.mentions(pending); len(mentioned) > 0 {
+			intervention = true
+
+			text.Warnln(gotext.Get("Manual intervention may be required for: %s",
+				text.Cyan(strings.Join(mentioned, ", "))))
+		}
+
+		fmt.Println()
+	}
+
+	if sortMode == settings.BottomUp {
+		for i := len(unread) - 1; i >= 0; i-- {
+			show(&unread[i])
+		}
+	} else {
+		for i := range unread {
+			show(&unread[i])
+		}
+	}
+
+	// mentions are guessed from words, unattended upgrades go on unless told
+	proceed := !intervention || (noConfirm && !stop)
+	if !text.ContinueTask(gotext.Get("Proceed with upgrade?"), proceed, noConfirm) {
+		return errors.New(gotext.Get("aborting due to user"))
+	}
+
+	store.markRead(unread)
+
+	return store.Save()
+}
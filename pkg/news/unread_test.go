@@ -0,0 +1,153 @@
+package news
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/Jguer/yay/v11/pkg/stringset"
+)
+
+func sampleItems(t *testing.T) []item {
+	t.Helper()
+
+	items, err := parseFeed([]byte(sampleNews), "rss")
+	assert.NoError(t, err)
+
+	return items
+}
+
+func TestReadStoreUnread(t *testing.T) {
+	t.Parallel()
+
+	items := sampleItems(t)
+	store := NewReadStore("")
+	store.markRead(items[:1])
+
+	assert.Len(t, store.unread(items), len(items)-1)
+
+	cutOffDate, _ := time.Parse(time.RFC3339, "2020-03-01T00:00:00Z")
+	for i := range items {
+		items[i].cutOff = cutOffDate
+	}
+
+	unread := store.unread(items)
+
+	titles := make([]string, 0, len(unread))
+	for i := range unread {
+		titles = append(titles, unread[i].Title)
+	}
+
+	assert.Equal(t, []string{
+		"nss>=3.51.1-1 and lib32-nss>=3.51.1-1 updates require manual intervention",
+		"hplip 3.20.3-2 update requires manual intervention",
+		"firewalld>=0.8.1-2 update requires manual intervention",
+	}, titles)
+}
+
+func TestItemMentions(t *testing.T) {
+	t.Parallel()
+
+	items := sampleItems(t)
+
+	type testCase struct {
+		desc    string
+		item    item
+		pending []string
+		want    []string
+	}
+
+	testCases := []testCase{
+		{
+			desc:    "name in title",
+			item:    items[0],
+			pending: []string{"zn_poly", "linux"},
+			want:    []string{"zn_poly"},
+		},
+		{
+			desc:    "name with version constraint",
+			item:    items[1],
+			pending: []string{"nss", "lib32-nss", "lib32-glibc"},
+			want:    []string{"lib32-nss", "nss"},
+		},
+		{
+			desc:    "name with version",
+			item:    items[6],
+			pending: []string{"openssh", "openssl"},
+			want:    []string{"openssh"},
+		},
+		{
+			desc:    "not mentioned",
+			item:    items[4],
+			pending: []string{"pacman", "glibc"},
+			want:    []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got := tc.item.mentions(stringset.FromSlice(tc.pending))
+			sort.Strings(got)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestReadStoreSaveLoad(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "news.json")
+	items := sampleItems(t)
+
+	store := NewReadStore(filePath)
+	assert.NoError(t, store.Load())
+	store.markRead(items[:2])
+	assert.NoError(t, store.Save())
+
+	loaded := NewReadStore(filePath)
+	assert.NoError(t, loaded.Load())
+	assert.Len(t, loaded.Read, 2)
+	assert.Contains(t, loaded.Read, items[1].GUID)
+}
+
+func TestCheckUnread(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "news.json")
+
+	gock.New("https://archlinux.org").
+		Get("/feeds/news").
+		Times(3).
+		Reply(200).
+		BodyString(lastNews)
+
+	defer gock.Off()
+
+	// intervention stops unattended upgrades only when asked to
+	store := NewReadStore(filePath)
+	err := CheckUnread(context.TODO(), &http.Client{}, archFeed, store, time.Time{}, []string{"zn_poly"}, 0, true, true)
+	assert.Error(t, err)
+	assert.Empty(t, store.Read)
+
+	err = CheckUnread(context.TODO(), &http.Client{}, archFeed, store, time.Time{}, []string{"zn_poly"}, 0, false, true)
+	assert.NoError(t, err)
+	assert.Len(t, store.Read, 1)
+
+	store = NewReadStore(filePath)
+	err = CheckUnread(context.TODO(), &http.Client{}, archFeed, store, time.Time{}, []string{"linux"}, 0, true, true)
+	assert.NoError(t, err)
+	assert.Len(t, store.Read, 1)
+
+	loaded := NewReadStore(filePath)
+	assert.NoError(t, loaded.Load())
+	for guid, date := range store.Read {
+		assert.True(t, date.Equal(loaded.Read[guid]))
+	}
+}
@@ -0,0 +1,66 @@
+package patch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/settings/exe"
+)
+
+// ErrPatchFailed means a local patch no longer applies to the PKGBUILD
+// repository of Pkgbase.
+type ErrPatchFailed struct {
+	Pkgbase string
+	Patch   string
+	errOut  string
+}
+
+func (e ErrPatchFailed) Error() string {
+	return fmt.Sprintln(gotext.Get("patch %s no longer applies to %s, update or remove it:", e.Patch, e.Pkgbase),
+		"\n\t", e.errOut)
+}
+
+// Find returns the patches of pkgbase, the *.patch files of patchDir/pkgbase,
+// in the order they are applied: sorted by file name.
+func Find(patchDir, pkgbase string) ([]string, error) {
+	if patchDir == "" {
+		return nil, nil
+	}
+
+	patchDir, err := filepath.Abs(patchDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Glob sorts its matches and only fails on a malformed pattern
+	return filepath.Glob(filepath.Join(patchDir, filepath.Base(pkgbase), "*.patch"))
+}
+
+// Apply applies patches to the working tree of the repository in dir. HEAD is
+// left untouched so the diff review keeps tracking upstream.
+func Apply(ctx context.Context, cmdBuilder exe.GitCmdBuilder, dir, pkgbase string, patches []string) error {
+	for _, patch := range patches {
+		_, stderr, err := cmdBuilder.Capture(
+			cmdBuilder.BuildGitCmd(ctx, dir, "apply", "--whitespace=nowarn", patch))
+		if err != nil {
+			return ErrPatchFailed{Pkgbase: pkgbase, Patch: filepath.Base(patch), errOut: stderr}
+		}
+	}
+
+	return nil
+}
+
+// Reset discards the changes made to the tracked files of the repository in
+// dir, undoing previously applied patches.
+func Reset(ctx context.Context, cmdBuilder exe.GitCmdBuilder, dir string) error {
+	_, stderr, err := cmdBuilder.Capture(cmdBuilder.BuildGitCmd(ctx, dir, "reset", "--quiet", "--hard", "HEAD"))
+	if err != nil {
+		return errors.New(gotext.Get("error resetting %s: %s", dir, stderr))
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package patch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v11/pkg/settings/exe"
+)
+
+// testBuilder runs git as the current user.
+type testBuilder struct {
+	exe.OSRunner
+}
+
+func (b *testBuilder) BuildGitCmd(ctx context.Context, dir string, extraArgs ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, extraArgs...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=yay", "GIT_AUTHOR_EMAIL=yay@localhost",
+		"GIT_COMMITTER_NAME=yay", "GIT_COMMITTER_EMAIL=yay@localhost")
+
+	return cmd
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	out, err := (&testBuilder{}).BuildGitCmd(context.Background(), dir, args...).CombinedOutput()
+	assert.NoError(t, err, string(out))
+
+	return strings.TrimSpace(string(out))
+}
+
+const (
+	pkgbuild = "pkgname=foo\nbuild() {\n\t./configure\n}\n"
+
+	configurePatch = `--- a/PKGBUILD
++++ b/PKGBUILD
+@@ -1,4 +1,4 @@
+ pkgname=foo
+ build() {
+-	./configure
++	./configure --disable-tests
+ }
+`
+	stalePatch = `--- a/PKGBUILD
++++ b/PKGBUILD
+@@ -1,4 +1,4 @@
+ pkgname=foo
+ build() {
+-	./configure --prefix=/usr
++	./configure --prefix=/opt
+ }
+`
+)
+
+// setup returns a repository holding a PKGBUILD and a patch directory.
+func setup(t *testing.T, patches map[string]string) (dir, patchDir string) {
+	t.Helper()
+
+	dir = t.TempDir()
+	patchDir = t.TempDir()
+
+	git(t, dir, "init", "-q")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "PKGBUILD"), []byte(pkgbuild), 0o644))
+	git(t, dir, "add", "PKGBUILD")
+	git(t, dir, "commit", "-q", "-m", "init")
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(patchDir, "foo"), 0o755))
+
+	for name, content := range patches {
+		assert.NoError(t, os.WriteFile(filepath.Join(patchDir, "foo", name), []byte(content), 0o644))
+	}
+
+	return dir, patchDir
+}
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+
+	_, patchDir := setup(t, map[string]string{
+		"20-tests.patch":     configurePatch,
+		"10-configure.patch": configurePatch,
+		"notes.txt":          "",
+	})
+
+	patches, err := Find(patchDir, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(patchDir, "foo", "10-configure.patch"),
+		filepath.Join(patchDir, "foo", "20-tests.patch"),
+	}, patches)
+
+	patches, err = Find(patchDir, "bar")
+	assert.NoError(t, err)
+	assert.Empty(t, patches)
+
+	patches, err = Find("", "foo")
+	assert.NoError(t, err)
+	assert.Empty(t, patches)
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	dir, patchDir := setup(t, map[string]string{"configure.patch": configurePatch})
+	head := git(t, dir, "rev-parse", "HEAD")
+
+	patches, err := Find(patchDir, "foo")
+	assert.NoError(t, err)
+	assert.NoError(t, Apply(context.Background(), &testBuilder{}, dir, "foo", patches))
+
+	content, err := os.ReadFile(filepath.Join(dir, "PKGBUILD"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "./configure --disable-tests")
+	assert.Equal(t, head, git(t, dir, "rev-parse", "HEAD"))
+
+	assert.NoError(t, Reset(context.Background(), &testBuilder{}, dir))
+
+	content, err = os.ReadFile(filepath.Join(dir, "PKGBUILD"))
+	assert.NoError(t, err)
+	assert.Equal(t, pkgbuild, string(content))
+}
+
+func TestApplyStale(t *testing.T) {
+	t.Parallel()
+
+	dir, patchDir := setup(t, map[string]string{"stale.patch": stalePatch})
+
+	patches, err := Find(patchDir, "foo")
+	assert.NoError(t, err)
+
+	err = Apply(context.Background(), &testBuilder{}, dir, "foo", patches)
+
+	var errPatch ErrPatchFailed
+	if assert.True(t, errors.As(err, &errPatch), err) {
+		assert.Equal(t, "foo", errPatch.Pkgbase)
+		assert.Equal(t, "stale.patch", errPatch.Patch)
+	}
+}
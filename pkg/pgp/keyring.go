@@ -0,0 +1,383 @@
+package pgp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+// Keyring is the gpg keyring PKGBUILD signature keys are checked against and
+// imported to.
+type Keyring struct {
+	GpgBin   string
+	GpgFlags []string
+	// Home is the GNUPGHOME of a keyring dedicated to yay, the default keyring
+	// of the user is used when empty.
+	Home string
+	// Keyservers are tried in order until every key is imported, the
+	// keyserver gpg is configured with is used when empty.
+	Keyservers []string
+	// WKD looks missing keys up in the dedicated keyring by the email
+	// addresses PKGBUILDs give for them once the keyservers are exhausted.
+	WKD bool
+}
+
+// Key is a public key of the keyring.
+type Key struct {
+	Fingerprint string
+	UserIDs     []string
+	// Validity is the validity letter of gpg, "e" for expired and "r" for
+	// revoked keys.
+	Validity string
+	// Capabilities holds in upper case what the key and its valid subkeys
+	// can be used for, "S" for signing.
+	Capabilities string
+	Algorithm    int
+	Length       int
+	Expires      time.Time
+}
+
+// OpenPGP public key algorithms.
+const (
+	algoRSA         = 1
+	algoRSASign     = 3
+	algoDSA         = 17
+	algoElgamalSign = 20
+	minStrongLength = 2048
+)
+
+// Problem describes why signatures made with the key fail to verify or can't
+// be trusted, empty when the key is fine.
+func (k *Key) Problem() string {
+	switch {
+	case k.Validity == "r":
+		return gotext.Get("revoked")
+	case k.Validity == "e" && !k.Expires.IsZero():
+		return gotext.Get("expired on %s", k.Expires.Format("2006-01-02"))
+	case k.Validity == "e":
+		return gotext.Get("expired")
+	case !strings.Contains(k.Capabilities, "S"):
+		return gotext.Get("no valid signing key")
+	case k.Algorithm >= algoRSA && k.Algorithm <= algoRSASign && k.Length < minStrongLength:
+		return gotext.Get("weak %d bit RSA key", k.Length)
+	case k.Algorithm == algoDSA && k.Length < minStrongLength:
+		return gotext.Get("weak %d bit DSA key", k.Length)
+	case k.Algorithm == algoElgamalSign:
+		return gotext.Get("Elgamal signing key")
+	}
+
+	return ""
+}
+
+// prepare creates the dedicated keyring, gpg refuses a home readable by
+// others.
+func (k *Keyring) prepare() error {
+	if k.Home == "" {
+		return nil
+	}
+
+	return os.MkdirAll(k.Home, 0o700)
+}
+
+func (k *Keyring) command(args ...string) *exec.Cmd {
+	gpgArgs := make([]string, len(k.GpgFlags), len(k.GpgFlags)+len(args)+2)
+	copy(gpgArgs, k.GpgFlags)
+
+	if k.Home != "" {
+		gpgArgs = append(gpgArgs, "--homedir", k.Home)
+	}
+
+	gpgArgs = append(gpgArgs, args...)
+
+	return exec.Command(k.GpgBin, gpgArgs...)
+}
+
+func (k *Keyring) interactive(args ...string) *exec.Cmd {
+	cmd := k.command(args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	return cmd
+}
+
+// keyservers returns the keyservers to try in order, "" standing for the one
+// gpg is configured with.
+func (k *Keyring) keyservers() []string {
+	if len(k.Keyservers) == 0 {
+		return []string{""}
+	}
+
+	return k.Keyservers
+}
+
+func keyserverArgs(keyserver, command string, keys []string) []string {
+	args := make([]string, 0, len(keys)+3)
+	if keyserver != "" {
+		args = append(args, "--keyserver", keyserver)
+	}
+
+	args = append(args, command)
+
+	return append(args, keys...)
+}
+
+// Has reports whether key is in the keyring.
+func (k *Keyring) Has(key string) bool {
+	return k.command("--list-keys", key).Run() == nil
+}
+
+// Missing returns the keys not in the keyring.
+func (k *Keyring) Missing(keys []string) []string {
+	missing := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		if !k.Has(key) {
+			missing = append(missing, key)
+		}
+	}
+
+	return missing
+}
+
+// Import fetches keys from the keyservers in order, then from the Web Key
+// Directory of the addresses emails lists for each key when enabled.
+func (k *Keyring) Import(keys []string, emails map[string][]string) error {
+	if err := k.prepare(); err != nil {
+		return err
+	}
+
+	missing := keys
+
+	for _, keyserver := range k.keyservers() {
+		if keyserver == "" {
+			text.OperationInfoln(gotext.Get("Importing keys with gpg..."))
+		} else {
+			text.OperationInfoln(gotext.Get("Importing keys from %s...", keyserver))
+		}
+
+		// keys gpg failed to import are told apart below
+		_ = k.interactive(keyserverArgs(keyserver, "--recv-keys", missing)...).Run()
+
+		if missing = k.Missing(missing); len(missing) == 0 {
+			return nil
+		}
+	}
+
+	// the keys a WKD publishes are only kept in a keyring dedicated to yay
+	if k.WKD && k.Home != "" && len(emails) > 0 {
+		text.OperationInfoln(gotext.Get("Looking up keys in the Web Key Directory..."))
+
+		for _, key := range missing {
+			if err := k.locate(key, emails[normalizeKey(key)]); err != nil {
+				text.Warnln(err)
+			}
+		}
+
+		missing = k.Missing(missing)
+	}
+
+	if len(missing) > 0 {
+		return errors.New(gotext.Get("problem importing keys"))
+	}
+
+	return nil
+}
+
+// locate looks key up in the Web Key Directory of emails. Any other key the
+// directories publish for those addresses is deleted again.
+func (k *Keyring) locate(key string, emails []string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+
+	before, err := k.List(nil)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(before))
+	for i := range before {
+		known[before[i].Fingerprint] = true
+	}
+
+	for _, email := range emails {
+		_ = k.interactive("--auto-key-locate", "clear,nodefault,wkd", "--locate-external-keys", email).Run()
+	}
+
+	after, err := k.List(nil)
+	if err != nil {
+		return err
+	}
+
+	unwanted := []string{}
+
+	for i := range after {
+		fingerprint := after[i].Fingerprint
+		if !known[fingerprint] && !strings.HasSuffix(fingerprint, normalizeKey(key)) {
+			unwanted = append(unwanted, fingerprint)
+		}
+	}
+
+	if len(unwanted) == 0 {
+		return nil
+	}
+
+	return k.Delete(unwanted)
+}
+
+// Refresh updates keys from the first keyserver able to.
+func (k *Keyring) Refresh(keys []string) error {
+	if err := k.prepare(); err != nil {
+		return err
+	}
+
+	for _, keyserver := range k.keyservers() {
+		if keyserver != "" {
+			text.OperationInfoln(gotext.Get("Refreshing keys from %s...", keyserver))
+		}
+
+		if err := k.interactive(keyserverArgs(keyserver, "--refresh-keys", keys)...).Run(); err == nil {
+			return nil
+		}
+	}
+
+	return errors.New(gotext.Get("problem refreshing keys"))
+}
+
+// Delete removes keys from the keyring.
+func (k *Keyring) Delete(keys []string) error {
+	if err := k.prepare(); err != nil {
+		return err
+	}
+
+	if err := k.interactive(append([]string{"--batch", "--yes", "--delete-keys"}, keys...)...).Run(); err != nil {
+		return errors.New(gotext.Get("problem deleting keys"))
+	}
+
+	return nil
+}
+
+// List returns the keys of the keyring matching keys, all of them when none
+// are given. Keys not in the keyring are left out.
+func (k *Keyring) List(keys []string) ([]Key, error) {
+	if err := k.prepare(); err != nil {
+		return nil, err
+	}
+
+	cmd := k.command(append([]string{"--with-colons", "--fixed-list-mode", "--list-keys"}, keys...)...)
+
+	out, err := cmd.Output()
+	if err != nil {
+		// gpg fails when some of the keys are missing, yet lists the others
+		var exitErr *exec.ExitError
+		if len(keys) == 0 || !errors.As(err, &exitErr) {
+			return nil, err
+		}
+	}
+
+	return parseKeys(out), nil
+}
+
+// parseKeys reads the public keys of the colon listing of gpg.
+func parseKeys(out []byte) []Key {
+	keys := []Key{}
+	// primary is false once the subkeys of the last key are listed
+	primary := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 10 {
+			continue
+		}
+
+		switch fields[0] {
+		case "pub":
+			keys = append(keys, parsePublicKey(fields))
+			primary = true
+		case "sub":
+			primary = false
+		case "fpr":
+			if primary && keys[len(keys)-1].Fingerprint == "" {
+				keys[len(keys)-1].Fingerprint = fields[9]
+			}
+		case "uid":
+			if len(keys) > 0 {
+				key := &keys[len(keys)-1]
+				key.UserIDs = append(key.UserIDs, strings.ReplaceAll(fields[9], `\x3a`, ":"))
+			}
+		}
+	}
+
+	return keys
+}
+
+// parsePublicKey reads a pub record of the colon listing of gpg.
+func parsePublicKey(fields []string) Key {
+	key := Key{UserIDs: []string{}, Validity: fields[1]}
+
+	key.Length, _ = strconv.Atoi(fields[2])
+	key.Algorithm, _ = strconv.Atoi(fields[3])
+
+	if expires, err := strconv.ParseInt(fields[6], 10, 64); err == nil {
+		key.Expires = time.Unix(expires, 0).UTC()
+	}
+
+	if len(fields) > 11 {
+		key.Capabilities = strings.Map(func(r rune) rune {
+			if unicode.IsUpper(r) {
+				return r
+			}
+
+			return -1
+		}, fields[11])
+	}
+
+	return key
+}
+
+var (
+	emailRegexp = regexp.MustCompile(`<([^<>@\s]+@[^<>@\s]+\.[^<>@\s]+)>`)
+	keyIDRegexp = regexp.MustCompile(`\b(?:[0-9A-Fa-f]{40}|[0-9A-Fa-f]{16})\b`)
+)
+
+// SignerEmails returns the email addresses a PKGBUILD gives in the comments
+// next to its validpgpkeys, the user IDs of the signers, by key:
+//
+//	validpgpkeys=('ABAF11C65A2970B130ABE3C479BE3E4300411886') # Linus Torvalds <torvalds@kernel.org>
+func SignerEmails(pkgbuild string) map[string][]string {
+	content, err := os.ReadFile(pkgbuild)
+	if err != nil {
+		return nil
+	}
+
+	signers := make(map[string][]string)
+
+	for _, line := range strings.Split(string(content), "\n") {
+		hash := strings.Index(line, "#")
+		if hash < 0 {
+			continue
+		}
+
+		keys := keyIDRegexp.FindAllString(line[:hash], -1)
+		matches := emailRegexp.FindAllStringSubmatch(line[hash:], -1)
+
+		for _, key := range keys {
+			for _, match := range matches {
+				signers[normalizeKey(key)] = append(signers[normalizeKey(key)], match[1])
+			}
+		}
+	}
+
+	return signers
+}
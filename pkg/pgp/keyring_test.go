@@ -0,0 +1,158 @@
+package pgp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gosrc "github.com/Morganamilo/go-srcinfo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v11/pkg/dep"
+)
+
+func TestParseKeys(t *testing.T) {
+	t.Parallel()
+
+	out := []byte(`tru::1:1792364511:0:3:1:5
+pub:-:2048:1:1EB2638FF56C0C53:1309028783:::-:::scESC::::::23::0:
+fpr:::::::::487EACC08557AD082088DABA1EB2638FF56C0C53:
+uid:-::::1314623184::6324DBFBCF0D7E3DCCD7E494BAFBDCC1477761E9::Dave Reisner <d@falconindy.com>::::::::::0:
+sub:-:2048:1:1C39A7BD114BFFA5:1309028783::::::e::::::23:
+fpr:::::::::EA29135832AF0D2514EDB8481C39A7BD114BFFA5:
+pub:e:4096:1:8F0871F202119294:1427396541:1555583042::-:::sc::::::23::0:
+fpr:::::::::11E521D646982372EB577A1F8F0871F202119294:
+uid:-::::1427396541::C1D9A4A5DEB4A1E86A0ADE4B6E6E2AA2E5A1C4E3::Tom Stellard\x3a LLVM <tstellar@redhat.com>::::::::::0:
+`)
+
+	assert.Equal(t, []Key{
+		{
+			Fingerprint:  "487EACC08557AD082088DABA1EB2638FF56C0C53",
+			UserIDs:      []string{"Dave Reisner <d@falconindy.com>"},
+			Validity:     "-",
+			Capabilities: "ESC",
+			Algorithm:    1,
+			Length:       2048,
+		},
+		{
+			Fingerprint:  "11E521D646982372EB577A1F8F0871F202119294",
+			UserIDs:      []string{"Tom Stellard: LLVM <tstellar@redhat.com>"},
+			Validity:     "e",
+			Capabilities: "",
+			Algorithm:    1,
+			Length:       4096,
+			Expires:      time.Unix(1555583042, 0).UTC(),
+		},
+	}, parseKeys(out))
+}
+
+func TestKeyProblem(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		key  Key
+		want string
+	}{
+		{"usable", Key{Validity: "-", Capabilities: "ESC", Algorithm: 1, Length: 4096}, ""},
+		{"usable ed25519", Key{Validity: "u", Capabilities: "SC", Algorithm: 22, Length: 255}, ""},
+		{"revoked", Key{Validity: "r", Algorithm: 1, Length: 4096}, "revoked"},
+		{"expired", Key{Validity: "e", Algorithm: 1, Length: 4096, Expires: time.Unix(1555583042, 0).UTC()}, "expired on 2019-04-18"},
+		{"expired signing subkeys", Key{Validity: "-", Capabilities: "E", Algorithm: 1, Length: 4096}, "no valid signing key"},
+		{"weak rsa", Key{Validity: "-", Capabilities: "ESC", Algorithm: 1, Length: 1024}, "weak 1024 bit RSA key"},
+		{"weak dsa", Key{Validity: "-", Capabilities: "ESC", Algorithm: 17, Length: 1024}, "weak 1024 bit DSA key"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, tc.key.Problem())
+		})
+	}
+}
+
+func TestSignerEmails(t *testing.T) {
+	t.Parallel()
+
+	pkgbuild := filepath.Join(t.TempDir(), "PKGBUILD")
+	assert.NoError(t, os.WriteFile(pkgbuild, []byte(`# Maintainer: Jane Doe <jane@example.com>
+pkgname=foo
+source=("git+https://example.com/foo.git#tag=v1") # Upstream <upstream@example.com>
+validpgpkeys=('487eacc08557ad082088daba1eb2638ff56c0c53' # Alice <alice@example.com>
+              '11E521D646982372EB577A1F8F0871F202119294') # Bob <bob@example.com> <bob@example.org>
+validpgpkeys+=('8F0871F202119294')
+`), 0o644))
+
+	assert.Equal(t, map[string][]string{
+		"487EACC08557AD082088DABA1EB2638FF56C0C53": {"alice@example.com"},
+		"11E521D646982372EB577A1F8F0871F202119294": {"bob@example.com", "bob@example.org"},
+	}, SignerEmails(pkgbuild))
+	assert.Nil(t, SignerEmails(filepath.Join(t.TempDir(), "missing")))
+}
+
+func TestCheckPins(t *testing.T) {
+	t.Parallel()
+
+	bases := []dep.Base{{newPkg("foo")}, {newPkg("bar")}, {newPkg("baz")}}
+	srcinfos := map[string]*gosrc.Srcinfo{
+		"foo": makeSrcinfo("foo", "487eacc08557ad082088daba1eb2638ff56c0c53"),
+		"bar": makeSrcinfo("bar", "11E521D646982372EB577A1F8F0871F202119294"),
+		"baz": makeSrcinfo("baz"),
+	}
+
+	assert.NoError(t, CheckPins(bases, srcinfos, map[string][]string{
+		"foo": {"487E ACC0 8557 AD08 2088  DABA 1EB2 638F F56C 0C53"},
+	}))
+
+	err := CheckPins(bases, srcinfos, map[string][]string{
+		"bar": {"487EACC08557AD082088DABA1EB2638FF56C0C53"},
+		"baz": {"487EACC08557AD082088DABA1EB2638FF56C0C53"},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "11E521D646982372EB577A1F8F0871F202119294")
+	assert.Contains(t, err.Error(), "no longer lists")
+}
+
+func TestKeyringFallback(t *testing.T) {
+	server := startPgpKeyServer()
+	defer func() {
+		err := server.Shutdown(context.TODO())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}()
+
+	keyring := &Keyring{
+		GpgBin: "gpg",
+		Home:   filepath.Join(t.TempDir(), "gnupg"),
+		// the first keyserver does not exist
+		Keyservers: []string{"hkp://keyserver.invalid", "127.0.0.1"},
+	}
+
+	keys := []string{"487EACC08557AD082088DABA1EB2638FF56C0C53", "C52048C0C0748FEE227D47A2702353E0F7E48EDB"}
+
+	assert.NoError(t, keyring.Import(keys, nil))
+
+	info, err := os.Stat(keyring.Home)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+
+	listed, err := keyring.List([]string{keys[0], "11E521D646982372EB577A1F8F0871F202119294"})
+	assert.NoError(t, err)
+	assert.Len(t, listed, 1)
+	assert.Equal(t, keys[0], listed[0].Fingerprint)
+
+	assert.NoError(t, keyring.Delete(keys[:1]))
+	assert.Equal(t, keys[:1], keyring.Missing(keys))
+
+	listed, err = keyring.List(nil)
+	assert.NoError(t, err)
+	assert.Len(t, listed, 1)
+	assert.Equal(t, keys[1], listed[0].Fingerprint)
+
+	assert.Error(t, keyring.Import([]string{"THIS-SHOULD-FAIL"}, nil))
+}
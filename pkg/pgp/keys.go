@@ -4,14 +4,14 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"os"
-	"os/exec"
+	"sort"
 	"strings"
 
 	gosrc "github.com/Morganamilo/go-srcinfo"
 	"github.com/leonelquinteros/gotext"
 
 	"github.com/Jguer/yay/v11/pkg/dep"
+	"github.com/Jguer/yay/v11/pkg/multierror"
 	"github.com/Jguer/yay/v11/pkg/text"
 )
 
@@ -35,6 +35,31 @@ func (set pgpKeySet) set(key string, p dep.Base) {
 	set[upperKey] = append(set[upperKey], p)
 }
 
+// emails returns the addresses the PKGBUILDs requiring the keys of set give
+// for them, by key.
+func (set pgpKeySet) emails(lookup func(pkgbase string) map[string][]string) map[string][]string {
+	if lookup == nil {
+		return nil
+	}
+
+	emails := make(map[string][]string)
+
+	for key, bases := range set {
+		seen := make(map[string]bool)
+
+		for _, base := range bases {
+			for _, email := range lookup(base.Pkgbase())[normalizeKey(key)] {
+				if !seen[email] {
+					seen[email] = true
+					emails[normalizeKey(key)] = append(emails[normalizeKey(key)], email)
+				}
+			}
+		}
+	}
+
+	return emails
+}
+
 func (set pgpKeySet) get(key string) bool {
 	upperKey := strings.ToUpper(key)
 	_, exists := set[upperKey]
@@ -42,22 +67,75 @@ func (set pgpKeySet) get(key string) bool {
 	return exists
 }
 
+// CheckPins makes sure the PKGBUILDs of pinned package bases only list the
+// PGP keys they are pinned to, so a changed validpgpkeys is not trusted
+// blindly.
+func CheckPins(bases []dep.Base, srcinfos map[string]*gosrc.Srcinfo, pins map[string][]string) error {
+	var errs multierror.MultiError
+
+	for _, base := range bases {
+		pkgbase := base.Pkgbase()
+
+		pinned, ok := pins[pkgbase]
+		if !ok {
+			continue
+		}
+
+		allowed := make(map[string]bool, len(pinned))
+		for _, key := range pinned {
+			allowed[normalizeKey(key)] = true
+		}
+
+		keys := srcinfos[pkgbase].ValidPGPKeys
+		if len(keys) == 0 && len(pinned) > 0 {
+			errs.Add(errors.New(gotext.Get("%s no longer lists the PGP keys it is pinned to", text.Cyan(pkgbase))))
+		}
+
+		for _, key := range keys {
+			if !allowed[normalizeKey(key)] {
+				errs.Add(errors.New(gotext.Get("%s lists PGP key %s it is not pinned to", text.Cyan(pkgbase), text.Cyan(key))))
+			}
+		}
+	}
+
+	return errs.Return()
+}
+
+func normalizeKey(key string) string {
+	return strings.ToUpper(strings.ReplaceAll(key, " ", ""))
+}
+
 // CheckPgpKeys iterates through the keys listed in the PKGBUILDs and if needed,
-// asks the user whether yay should try to import them.
-func CheckPgpKeys(bases []dep.Base, srcinfos map[string]*gosrc.Srcinfo,
-	gpgBin, gpgFlags string, noConfirm bool) error {
+// asks the user whether yay should try to import them. Expired, revoked and
+// weak keys are then reported, offering to refresh them. The package bases
+// requiring each key are recorded in store and emails, when not nil, returns
+// by key the addresses the keys of a package base are looked up by in the Web
+// Key Directory.
+func CheckPgpKeys(bases []dep.Base, srcinfos map[string]*gosrc.Srcinfo, keyring *Keyring,
+	store *Store, emails func(pkgbase string) map[string][]string, noConfirm bool) error {
+	if err := keyring.prepare(); err != nil {
+		return err
+	}
+
 	// Let's check the keys individually, and then we can offer to import
 	// the problematic ones.
 	problematic := make(pgpKeySet)
-
-	args := append(strings.Fields(gpgFlags), "--list-keys")
+	required := make(pgpKeySet)
 
 	// Mapping all the keys.
 	for _, base := range bases {
 		pkg := base.Pkgbase()
 		srcinfo := srcinfos[pkg]
 
+		if store != nil {
+			if err := store.Require(pkg, srcinfo.ValidPGPKeys); err != nil {
+				text.Warnln(err)
+			}
+		}
+
 		for _, key := range srcinfo.ValidPGPKeys {
+			required.set(key, base)
+
 			// If key already marked as problematic, indicate the current
 			// PKGBUILD requires it.
 			if problematic.get(key) {
@@ -65,46 +143,117 @@ func CheckPgpKeys(bases []dep.Base, srcinfos map[string]*gosrc.Srcinfo,
 				continue
 			}
 
-			cmd := exec.Command(gpgBin, append(args, key)...)
-			if err := cmd.Run(); err != nil {
+			if !keyring.Has(key) {
 				problematic.set(key, base)
 			}
 		}
 	}
 
-	// No key issues!
-	if len(problematic) == 0 {
-		return nil
+	if len(problematic) > 0 {
+		str, err := formatKeysToImport(problematic)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println()
+		fmt.Println(str)
+
+		if text.ContinueTask(gotext.Get("Import?"), true, noConfirm) {
+			if err := keyring.Import(problematic.toSlice(), problematic.emails(emails)); err != nil {
+				return err
+			}
+		}
 	}
 
-	str, err := formatKeysToImport(problematic)
+	checkUnusableKeys(keyring, required, noConfirm)
+
+	return nil
+}
+
+// unusableKeys returns why the keys of set in the keyring can't be used to
+// verify signatures, by key.
+func unusableKeys(keyring *Keyring, set pgpKeySet) map[string]string {
+	unusable := make(map[string]string)
+
+	listed, err := keyring.List(set.toSlice())
 	if err != nil {
-		return err
+		return unusable
+	}
+
+	for key := range set {
+		for i := range listed {
+			// validpgpkeys may hold long key IDs rather than fingerprints
+			if !strings.HasSuffix(listed[i].Fingerprint, key) {
+				continue
+			}
+
+			if problem := listed[i].Problem(); problem != "" {
+				unusable[key] = problem
+			}
+
+			break
+		}
+	}
+
+	return unusable
+}
+
+// checkUnusableKeys warns about expired, revoked and weak keys before the
+// build starts instead of letting makepkg fail, and offers to refresh them as
+// their expiry may have been extended since they were imported.
+func checkUnusableKeys(keyring *Keyring, set pgpKeySet, noConfirm bool) {
+	unusable := unusableKeys(keyring, set)
+	if len(unusable) == 0 {
+		return
 	}
 
 	fmt.Println()
-	fmt.Println(str)
+	fmt.Println(formatUnusableKeys(set, unusable))
 
-	if text.ContinueTask(gotext.Get("Import?"), true, noConfirm) {
-		return importKeys(problematic.toSlice(), gpgBin, gpgFlags)
+	if !text.ContinueTask(gotext.Get("Refresh?"), true, noConfirm) {
+		return
 	}
 
-	return nil
+	keys := sortedKeys(unusable)
+	if err := keyring.Refresh(keys); err != nil {
+		text.Warnln(err)
+	}
+
+	unusable = unusableKeys(keyring, set)
+	for _, key := range sortedKeys(unusable) {
+		text.Warnln(gotext.Get("%s is still unusable: %s", text.Cyan(key), unusable[key]))
+	}
 }
 
-// importKeys tries to import the list of keys specified in its argument.
-func importKeys(keys []string, gpgBin, gpgFlags string) error {
-	args := append(strings.Fields(gpgFlags), "--recv-keys")
-	cmd := exec.Command(gpgBin, append(args, keys...)...)
-	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+func sortedKeys(unusable map[string]string) []string {
+	keys := make([]string, 0, len(unusable))
+	for key := range unusable {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
 
-	text.OperationInfoln(gotext.Get("Importing keys with gpg..."))
+	return keys
+}
+
+// formatUnusableKeys lists the unusable keys of set along with why and the
+// package bases requiring them.
+func formatUnusableKeys(set pgpKeySet, unusable map[string]string) string {
+	var buffer bytes.Buffer
 
-	if err := cmd.Run(); err != nil {
-		return errors.New(gotext.Get("problem importing keys"))
+	buffer.WriteString(text.SprintOperationInfo(gotext.Get("PGP keys are unusable, the build may fail:")))
+
+	for _, key := range sortedKeys(unusable) {
+		pkglist := make([]string, 0, len(set[key]))
+		for _, base := range set[key] {
+			pkglist = append(pkglist, base.String())
+		}
+
+		buffer.WriteString("\n" + text.SprintWarn(gotext.Get("%s (%s), required by: %s",
+			text.Cyan(key), unusable[key], text.Cyan(strings.Join(pkglist, "  ")))))
 	}
 
-	return nil
+	return buffer.String()
 }
 
 // formatKeysToImport receives a set of keys and returns a string containing the
@@ -127,7 +127,9 @@ func TestImportKeys(t *testing.T) {
 	}
 
 	for _, tt := range casetests {
-		err := importKeys(tt.keys, "gpg", fmt.Sprintf("--homedir %s --keyserver 127.0.0.1", keyringDir))
+		keyring := &Keyring{GpgBin: "gpg", GpgFlags: []string{"--keyserver", "127.0.0.1"}, Home: keyringDir}
+
+		err := keyring.Import(tt.keys, nil)
 		if !tt.wantError {
 			if err != nil {
 				t.Fatalf("Got error %q, want no error", err)
@@ -247,8 +249,9 @@ func TestCheckPgpKeys(t *testing.T) {
 			r, w, _ := os.Pipe()
 			os.Stdout = w
 
-			err := CheckPgpKeys([]dep.Base{tt.pkgs}, tt.srcinfos, "gpg",
-				fmt.Sprintf("--homedir %s --keyserver 127.0.0.1", keyringDir), true)
+			keyring := &Keyring{GpgBin: "gpg", GpgFlags: []string{"--keyserver", "127.0.0.1"}, Home: keyringDir}
+
+			err := CheckPgpKeys([]dep.Base{tt.pkgs}, tt.srcinfos, keyring, nil, nil, true)
 			if !tt.wantError {
 				if err != nil {
 					t.Fatalf("Got error %q, want no error", err)
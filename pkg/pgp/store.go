@@ -0,0 +1,103 @@
+package pgp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Store records the package bases requiring each PGP key checked by yay.
+// Example:
+//
+//	"487EACC08557AD082088DABA1EB2638FF56C0C53": ["cower", "auracle-git"]
+type Store struct {
+	Keys     map[string][]string
+	FilePath string
+}
+
+func NewStore(filePath string) *Store {
+	return &Store{
+		Keys:     map[string][]string{},
+		FilePath: filePath,
+	}
+}
+
+// Require records that pkgbase requires keys and saves the store if anything
+// changed.
+func (s *Store) Require(pkgbase string, keys []string) error {
+	changed := false
+
+	for _, key := range keys {
+		key = strings.ToUpper(key)
+		pkgbases := s.Keys[key]
+
+		i := sort.SearchStrings(pkgbases, pkgbase)
+		if i < len(pkgbases) && pkgbases[i] == pkgbase {
+			continue
+		}
+
+		pkgbases = append(pkgbases, "")
+		copy(pkgbases[i+1:], pkgbases[i:])
+		pkgbases[i] = pkgbase
+		s.Keys[key] = pkgbases
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return s.Save()
+}
+
+// Forget removes keys from the store and saves it.
+func (s *Store) Forget(keys []string) error {
+	for _, key := range keys {
+		delete(s.Keys, strings.ToUpper(key))
+	}
+
+	return s.Save()
+}
+
+// Fingerprints returns the recorded keys, sorted.
+func (s *Store) Fingerprints() []string {
+	keys := make([]string, 0, len(s.Keys))
+	for key := range s.Keys {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (s *Store) Save() error {
+	marshalledKeys, err := json.MarshalIndent(s.Keys, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.FilePath, marshalledKeys, 0o644)
+}
+
+// Load reads the key file and populates the Store.
+func (s *Store) Load() error {
+	kfile, err := os.Open(s.FilePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to open pgp key file '%s': %s", s.FilePath, err)
+	}
+
+	defer kfile.Close()
+
+	if err = json.NewDecoder(kfile).Decode(&s.Keys); err != nil {
+		return fmt.Errorf("failed to read pgp keys '%s': %s", s.FilePath, err)
+	}
+
+	return nil
+}
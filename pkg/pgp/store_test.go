@@ -0,0 +1,35 @@
+package pgp
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore_RequireForget(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "pgpkeys.json")
+	store := NewStore(filePath)
+
+	assert.NoError(t, store.Require("foo", []string{"aaaa", "BBBB"}))
+	assert.NoError(t, store.Require("bar", []string{"AAAA"}))
+	assert.NoError(t, store.Require("foo", []string{"AAAA"}))
+
+	loaded := NewStore(filePath)
+	assert.NoError(t, loaded.Load())
+	assert.Equal(t, map[string][]string{
+		"AAAA": {"bar", "foo"},
+		"BBBB": {"foo"},
+	}, loaded.Keys)
+	assert.Equal(t, []string{"AAAA", "BBBB"}, loaded.Fingerprints())
+
+	assert.NoError(t, loaded.Forget([]string{"aaaa"}))
+
+	reloaded := NewStore(filePath)
+	assert.NoError(t, reloaded.Load())
+	assert.Equal(t, []string{"BBBB"}, reloaded.Fingerprints())
+
+	assert.NoError(t, NewStore(filepath.Join(t.TempDir(), "missing.json")).Load())
+}
@@ -0,0 +1,101 @@
+package pin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Pin records what a package was built from: the historical AUR revision, or
+// the local directory built with yay -B.
+// Example:
+//
+//	"yay": {
+//		"pkgbase": "yay",
+//		"version": "10.0.0-1",
+//		"commit": "c1171d41467c68ffd3c46748182a16366aaaf87b"
+//	}
+type Pin struct {
+	Pkgbase string `json:"pkgbase"`
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+	Dir     string `json:"dir,omitempty"`
+}
+
+// Store holds the pins of packages by package name.
+// Pinned packages are held back from AUR upgrades until the pin is released.
+type Store struct {
+	Pins     map[string]Pin
+	FilePath string
+}
+
+func NewStore(filePath string) *Store {
+	return &Store{
+		Pins:     map[string]Pin{},
+		FilePath: filePath,
+	}
+}
+
+// Get returns the pin for pkgName if one exists.
+func (s *Store) Get(pkgName string) (Pin, bool) {
+	p, ok := s.Pins[pkgName]
+
+	return p, ok
+}
+
+// Set pins each of pkgNames to the given revision and saves the store.
+func (s *Store) Set(pkgNames []string, p Pin) error {
+	for _, pkgName := range pkgNames {
+		s.Pins[pkgName] = p
+	}
+
+	return s.Save()
+}
+
+// Release removes the pins of pkgNames and returns the names that were pinned.
+func (s *Store) Release(pkgNames []string) ([]string, error) {
+	released := make([]string, 0)
+
+	for _, pkgName := range pkgNames {
+		if _, ok := s.Pins[pkgName]; ok {
+			delete(s.Pins, pkgName)
+
+			released = append(released, pkgName)
+		}
+	}
+
+	if len(released) == 0 {
+		return released, nil
+	}
+
+	return released, s.Save()
+}
+
+func (s *Store) Save() error {
+	marshalledPins, err := json.MarshalIndent(s.Pins, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.FilePath, marshalledPins, 0o644)
+}
+
+// Load reads the pin file and populates the Store.
+func (s *Store) Load() error {
+	pfile, err := os.Open(s.FilePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to open pin file '%s': %s", s.FilePath, err)
+	}
+
+	defer pfile.Close()
+
+	if err = json.NewDecoder(pfile).Decode(&s.Pins); err != nil {
+		return fmt.Errorf("failed to read pins '%s': %s", s.FilePath, err)
+	}
+
+	return nil
+}
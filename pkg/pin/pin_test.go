@@ -0,0 +1,81 @@
+package pin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore_SaveLoad(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "pins.json")
+	store := NewStore(filePath)
+
+	p := Pin{Pkgbase: "yay", Version: "10.0.0-1", Commit: "c1171d41467c68ffd3c46748182a16366aaaf87b"}
+	assert.NoError(t, store.Set([]string{"yay", "yay-debug"}, p))
+
+	loaded := NewStore(filePath)
+	assert.NoError(t, loaded.Load())
+
+	got, ok := loaded.Get("yay")
+	assert.True(t, ok)
+	assert.Equal(t, p, got)
+	assert.Len(t, loaded.Pins, 2)
+}
+
+func TestStore_LoadDir(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "origins.json")
+	assert.NoError(t, os.WriteFile(filePath, []byte(`{
+	"foo": {"pkgbase": "foo", "version": "1.0.0-1", "dir": "/home/user/src/foo"}
+}`), 0o644))
+
+	store := NewStore(filePath)
+	assert.NoError(t, store.Load())
+
+	got, ok := store.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, Pin{Pkgbase: "foo", Version: "1.0.0-1", Dir: "/home/user/src/foo"}, got)
+}
+
+func TestStore_Release(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "pins.json")
+	store := NewStore(filePath)
+
+	assert.NoError(t, store.Set([]string{"yay"}, Pin{Pkgbase: "yay", Version: "10.0.0-1"}))
+
+	released, err := store.Release([]string{"yay", "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"yay"}, released)
+
+	_, ok := store.Get("yay")
+	assert.False(t, ok)
+
+	loaded := NewStore(filePath)
+	assert.NoError(t, loaded.Load())
+	assert.Empty(t, loaded.Pins)
+}
+
+func TestStore_LoadMissing(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NoError(t, store.Load())
+	assert.Empty(t, store.Pins)
+}
+
+func TestStore_LoadInvalid(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "pins.json")
+	assert.NoError(t, os.WriteFile(filePath, []byte("{"), 0o644))
+
+	store := NewStore(filePath)
+	assert.Error(t, store.Load())
+}
@@ -0,0 +1,223 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gosrc "github.com/Morganamilo/go-srcinfo"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/db"
+	"github.com/Jguer/yay/v11/pkg/settings/exe"
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+// branch is the only branch accepted by the AUR.
+const branch = "master"
+
+// RemoteURL returns the git remote of pkgbase on the AUR at aurURL, which
+// maintainers push to over SSH.
+func RemoteURL(aurURL, pkgbase string) (string, error) {
+	u, err := url.Parse(aurURL)
+	if err != nil {
+		return "", err
+	}
+
+	if u.Host == "" {
+		return "", errors.New(gotext.Get("invalid AUR URL: %s", aurURL))
+	}
+
+	return "ssh://aur@" + u.Host + "/" + pkgbase + ".git", nil
+}
+
+// Publisher validates a PKGBUILD directory and pushes it to its AUR remote.
+type Publisher struct {
+	CmdBuilder exe.ICmdBuilder
+	AURURL     string
+	// Remote overrides the remote derived from AURURL.
+	Remote string
+	DryRun bool
+}
+
+// Publish validates the PKGBUILD in dir, commits its changes and pushes them
+// to the AUR. Nothing is written, committed or pushed in dry run mode.
+func (p *Publisher) Publish(ctx context.Context, dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return errors.New(gotext.Get("%s is not a git repository", dir))
+	}
+
+	srcinfo, generated, stale, err := p.generateSrcinfo(ctx, dir)
+	if err != nil {
+		return err
+	}
+
+	remote := p.Remote
+	if remote == "" {
+		if remote, err = RemoteURL(p.AURURL, srcinfo.Pkgbase); err != nil {
+			return err
+		}
+	}
+
+	text.OperationInfoln(gotext.Get("Verifying sources: %s", text.Cyan(srcinfo.Pkgbase)))
+
+	if errMake := p.CmdBuilder.Show(p.CmdBuilder.BuildMakepkgCmd(ctx, dir, "--verifysource")); errMake != nil {
+		return errors.New(gotext.Get("error verifying sources of %s", srcinfo.Pkgbase))
+	}
+
+	if err = p.checkVersion(ctx, dir, remote, srcinfo); err != nil {
+		return err
+	}
+
+	// the .SRCINFO is only touched once every check passed
+	if stale {
+		if err = p.writeSrcinfo(dir, srcinfo.Pkgbase, generated); err != nil {
+			return err
+		}
+	}
+
+	return p.push(ctx, dir, remote, srcinfo)
+}
+
+// generateSrcinfo generates the .SRCINFO of the PKGBUILD in dir in memory.
+// stale reports whether the .SRCINFO of dir does not match it.
+func (p *Publisher) generateSrcinfo(ctx context.Context,
+	dir string) (srcinfo *gosrc.Srcinfo, generated string, stale bool, err error) {
+	stdout, stderr, err := p.CmdBuilder.Capture(p.CmdBuilder.BuildMakepkgCmd(ctx, dir, "--printsrcinfo"))
+	if err != nil {
+		return nil, "", false, errors.New(gotext.Get("unable to generate .SRCINFO for %s: %s", dir, stderr))
+	}
+
+	generated = stdout + "\n"
+
+	srcinfo, err = gosrc.Parse(generated)
+	if err != nil {
+		return nil, "", false, errors.New(gotext.Get("failed to parse generated .SRCINFO: %s", err))
+	}
+
+	current, err := os.ReadFile(filepath.Join(dir, ".SRCINFO"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, "", false, err
+	}
+
+	return srcinfo, generated, string(current) != generated, nil
+}
+
+// writeSrcinfo replaces the .SRCINFO of dir with generated.
+func (p *Publisher) writeSrcinfo(dir, pkgbase, generated string) error {
+	if p.DryRun {
+		text.Warnln(gotext.Get(".SRCINFO does not match the PKGBUILD and would be regenerated"))
+
+		return nil
+	}
+
+	text.OperationInfoln(gotext.Get("Regenerating .SRCINFO: %s", text.Cyan(pkgbase)))
+
+	return os.WriteFile(filepath.Join(dir, ".SRCINFO"), []byte(generated), 0o644)
+}
+
+// checkVersion makes sure the version of srcinfo is newer than the version
+// on the remote, if the remote already holds the package.
+func (p *Publisher) checkVersion(ctx context.Context, dir, remote string, srcinfo *gosrc.Srcinfo) error {
+	refs, stderr, err := p.CmdBuilder.Capture(p.CmdBuilder.BuildGitCmd(ctx, dir, "ls-remote", remote, "refs/heads/"+branch))
+	if err != nil {
+		return errors.New(gotext.Get("unable to reach %s: %s", remote, stderr))
+	}
+
+	if refs == "" {
+		text.OperationInfoln(gotext.Get("%s is a new package", text.Cyan(srcinfo.Pkgbase)))
+
+		return nil
+	}
+
+	if _, stderr, err = p.CmdBuilder.Capture(p.CmdBuilder.BuildGitCmd(ctx, dir, "fetch", remote, branch)); err != nil {
+		return errors.New(gotext.Get("error fetching %s: %s", remote, stderr))
+	}
+
+	remoteSrcinfo, _, err := p.CmdBuilder.Capture(p.CmdBuilder.BuildGitCmd(ctx, dir, "show", "FETCH_HEAD:.SRCINFO"))
+	if err != nil {
+		return errors.New(gotext.Get("unable to read the .SRCINFO of %s", remote))
+	}
+
+	published, err := gosrc.Parse(remoteSrcinfo + "\n")
+	if err != nil {
+		return errors.New(gotext.Get("failed to parse the .SRCINFO of %s: %s", remote, err))
+	}
+
+	if db.VerCmp(srcinfo.Version(), published.Version()) <= 0 {
+		return errors.New(gotext.Get("%s is not newer than the published %s, bump pkgrel or pkgver",
+			srcinfo.Version(), published.Version()))
+	}
+
+	return nil
+}
+
+// stageArgs returns the git add invocations staging the changes of dir:
+// tracked files, the PKGBUILD and the .SRCINFO. Downloaded sources are left
+// out, other new files have to be staged by hand.
+func stageArgs(dir string, dryRun bool) [][]string {
+	paths := []string{"PKGBUILD"}
+	if _, err := os.Stat(filepath.Join(dir, ".SRCINFO")); err == nil {
+		paths = append(paths, ".SRCINFO")
+	}
+
+	update := []string{"add", "--update"}
+	add := []string{"add"}
+
+	if dryRun {
+		update = append(update, "--dry-run")
+		add = append(add, "--dry-run")
+	}
+
+	return [][]string{update, append(append(add, "--"), paths...)}
+}
+
+// push commits the changes of dir and pushes them to remote.
+func (p *Publisher) push(ctx context.Context, dir, remote string, srcinfo *gosrc.Srcinfo) error {
+	message := gotext.Get("Update to %s", srcinfo.Version())
+
+	if p.DryRun {
+		text.Infoln(gotext.Get("Would commit \"%s\":", message))
+
+		for _, args := range stageArgs(dir, true) {
+			stdout, stderr, err := p.CmdBuilder.Capture(p.CmdBuilder.BuildGitCmd(ctx, dir, args...))
+			if err != nil {
+				return errors.New(gotext.Get("error staging the changes of %s: %s", dir, stderr))
+			}
+
+			for _, line := range strings.Split(stdout, "\n") {
+				if line != "" {
+					text.Infoln("  " + line)
+				}
+			}
+		}
+
+		text.Infoln(gotext.Get("Would push %s to %s", text.Cyan(srcinfo.Pkgbase), remote))
+
+		return nil
+	}
+
+	for _, args := range stageArgs(dir, false) {
+		if _, stderr, err := p.CmdBuilder.Capture(p.CmdBuilder.BuildGitCmd(ctx, dir, args...)); err != nil {
+			return errors.New(gotext.Get("error staging the changes of %s: %s", dir, stderr))
+		}
+	}
+
+	// diff --quiet exits with 1 when there are staged changes
+	if _, _, err := p.CmdBuilder.Capture(p.CmdBuilder.BuildGitCmd(ctx, dir, "diff", "--cached", "--quiet")); err != nil {
+		if errCommit := p.CmdBuilder.Show(p.CmdBuilder.BuildGitCmd(ctx, dir, "commit", "-m", message)); errCommit != nil {
+			return errors.New(gotext.Get("error committing the changes of %s", dir))
+		}
+	}
+
+	if err := p.CmdBuilder.Show(p.CmdBuilder.BuildGitCmd(ctx, dir, "push", remote, "HEAD:"+branch)); err != nil {
+		return errors.New(gotext.Get("error pushing %s to %s", srcinfo.Pkgbase, remote))
+	}
+
+	text.OperationInfoln(gotext.Get("Published %s to %s", text.Cyan(srcinfo.Pkgbase+"-"+srcinfo.Version()), remote))
+
+	return nil
+}
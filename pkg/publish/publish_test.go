@@ -0,0 +1,146 @@
+package publish
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v11/pkg/settings/exe"
+)
+
+// testBuilder runs git as the current user and fakes makepkg, printing
+// srcinfo for --printsrcinfo and succeeding otherwise.
+type testBuilder struct {
+	exe.ICmdBuilder
+	runner  exe.OSRunner
+	srcinfo string
+}
+
+func (b *testBuilder) BuildGitCmd(ctx context.Context, dir string, extraArgs ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, extraArgs...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=yay", "GIT_AUTHOR_EMAIL=yay@localhost",
+		"GIT_COMMITTER_NAME=yay", "GIT_COMMITTER_EMAIL=yay@localhost")
+
+	return cmd
+}
+
+func (b *testBuilder) BuildMakepkgCmd(ctx context.Context, dir string, extraArgs ...string) *exec.Cmd {
+	if len(extraArgs) > 0 && extraArgs[0] == "--printsrcinfo" {
+		return exec.CommandContext(ctx, "printf", "%s", b.srcinfo)
+	}
+
+	return exec.CommandContext(ctx, "true")
+}
+
+func (b *testBuilder) Show(cmd *exec.Cmd) error {
+	return cmd.Run()
+}
+
+func (b *testBuilder) Capture(cmd *exec.Cmd) (stdout, stderr string, err error) {
+	return b.runner.Capture(cmd)
+}
+
+func srcinfo(pkgver, pkgrel string) string {
+	return "pkgbase = foo\n" +
+		"\tpkgver = " + pkgver + "\n" +
+		"\tpkgrel = " + pkgrel + "\n" +
+		"\tarch = any\n\n" +
+		"pkgname = foo\n"
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	out, err := (&testBuilder{}).BuildGitCmd(context.Background(), dir, args...).CombinedOutput()
+	assert.NoError(t, err, string(out))
+
+	return strings.TrimSpace(string(out))
+}
+
+// setup returns a bare remote and a clone of it holding a PKGBUILD.
+func setup(t *testing.T) (remote, dir string) {
+	t.Helper()
+
+	remote = t.TempDir()
+	dir = t.TempDir()
+
+	git(t, remote, "init", "--bare", "-q")
+	git(t, dir, "init", "-q")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "PKGBUILD"), []byte("pkgname=foo\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "foo-1.0.tar.gz"), []byte("source"), 0o644))
+
+	return remote, dir
+}
+
+func TestRemoteURL(t *testing.T) {
+	t.Parallel()
+
+	got, err := RemoteURL("https://aur.archlinux.org", "yay")
+	assert.NoError(t, err)
+	assert.Equal(t, "ssh://aur@aur.archlinux.org/yay.git", got)
+
+	_, err = RemoteURL("aur", "yay")
+	assert.Error(t, err)
+}
+
+func TestPublish(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	t.Parallel()
+
+	remote, dir := setup(t)
+	builder := &testBuilder{srcinfo: srcinfo("1.0", "1")}
+	publisher := &Publisher{CmdBuilder: builder, Remote: remote}
+
+	assert.NoError(t, publisher.Publish(context.Background(), dir))
+	assert.Equal(t, srcinfo("1.0", "1"), git(t, remote, "show", "master:.SRCINFO")+"\n")
+	assert.Equal(t, "Update to 1.0-1", git(t, remote, "log", "-1", "--format=%s", "master"))
+	assert.Equal(t, ".SRCINFO\nPKGBUILD", git(t, remote, "ls-tree", "--name-only", "master"))
+
+	// publishing the same version again is refused
+	assert.Error(t, publisher.Publish(context.Background(), dir))
+
+	// a refused version leaves the .SRCINFO untouched
+	builder.srcinfo = srcinfo("0.9", "1")
+	assert.Error(t, publisher.Publish(context.Background(), dir))
+
+	current, err := os.ReadFile(filepath.Join(dir, ".SRCINFO"))
+	assert.NoError(t, err)
+	assert.Equal(t, srcinfo("1.0", "1"), string(current))
+
+	builder.srcinfo = srcinfo("1.0", "2")
+	assert.NoError(t, publisher.Publish(context.Background(), dir))
+	assert.Equal(t, "Update to 1.0-2", git(t, remote, "log", "-1", "--format=%s", "master"))
+}
+
+func TestPublishDryRun(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	t.Parallel()
+
+	remote, dir := setup(t)
+	publisher := &Publisher{CmdBuilder: &testBuilder{srcinfo: srcinfo("1.0", "1")}, Remote: remote, DryRun: true}
+
+	assert.NoError(t, publisher.Publish(context.Background(), dir))
+
+	_, err := os.Stat(filepath.Join(dir, ".SRCINFO"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Empty(t, git(t, remote, "branch", "--list"))
+}
+
+func TestPublishNotARepository(t *testing.T) {
+	t.Parallel()
+
+	publisher := &Publisher{CmdBuilder: &testBuilder{srcinfo: srcinfo("1.0", "1")}, Remote: t.TempDir()}
+	assert.Error(t, publisher.Publish(context.Background(), t.TempDir()))
+}
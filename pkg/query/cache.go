@@ -0,0 +1,215 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jguer/aur"
+)
+
+type cacheEntry struct {
+	Time time.Time `json:"time"`
+	Pkgs []Pkg     `json:"pkgs"`
+}
+
+// call is an in-flight request shared by identical concurrent requests.
+type call struct {
+	wg   sync.WaitGroup
+	pkgs []Pkg
+	err  error
+}
+
+// CachedClient caches the responses of an AUR client on disk for ttl.
+// Info responses are cached per package and search responses per query.
+// Identical concurrent requests are coalesced into a single request.
+type CachedClient struct {
+	client  aur.ClientInterface
+	path    string
+	ttl     time.Duration
+	refresh bool
+	created time.Time
+
+	mux      sync.Mutex
+	loaded   bool
+	entries  map[string]cacheEntry
+	inflight map[string]*call
+}
+
+// NewCachedClient wraps client with a cache stored in cachePath.
+// When refresh is set, responses cached before the creation of the client are
+// ignored. A ttl of zero or less disables the cache.
+func NewCachedClient(client aur.ClientInterface, cachePath string, ttl time.Duration, refresh bool) *CachedClient {
+	return &CachedClient{
+		client:   client,
+		path:     cachePath,
+		ttl:      ttl,
+		refresh:  refresh,
+		created:  time.Now(),
+		entries:  map[string]cacheEntry{},
+		inflight: map[string]*call{},
+	}
+}
+
+func (c *CachedClient) fresh(entry cacheEntry) bool {
+	if time.Since(entry.Time) >= c.ttl {
+		return false
+	}
+
+	return !c.refresh || !entry.Time.Before(c.created)
+}
+
+// lookup returns the fresh entry stored for key. c.mux must be held.
+func (c *CachedClient) lookup(key string) (cacheEntry, bool) {
+	if c.ttl <= 0 {
+		return cacheEntry{}, false
+	}
+
+	if !c.loaded {
+		c.loaded = true
+
+		if content, err := os.ReadFile(c.path); err == nil {
+			_ = json.Unmarshal(content, &c.entries)
+		}
+	}
+
+	entry, ok := c.entries[key]
+	if !ok || !c.fresh(entry) {
+		return cacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+// save writes the fresh entries to disk. c.mux must be held.
+func (c *CachedClient) save() {
+	if c.ttl <= 0 {
+		return
+	}
+
+	for key, entry := range c.entries {
+		if time.Since(entry.Time) >= c.ttl {
+			delete(c.entries, key)
+		}
+	}
+
+	content, err := json.Marshal(c.entries)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return
+	}
+
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0o644); err != nil {
+		return
+	}
+
+	_ = os.Rename(tmpPath, c.path)
+}
+
+// do runs fn once for all the concurrent callers using key.
+func (c *CachedClient) do(key string, fn func() ([]Pkg, error)) ([]Pkg, error) {
+	c.mux.Lock()
+	if inflight, ok := c.inflight[key]; ok {
+		c.mux.Unlock()
+		inflight.wg.Wait()
+
+		return append([]Pkg(nil), inflight.pkgs...), inflight.err
+	}
+
+	newCall := &call{}
+	newCall.wg.Add(1)
+	c.inflight[key] = newCall
+	c.mux.Unlock()
+
+	newCall.pkgs, newCall.err = fn()
+	newCall.wg.Done()
+
+	c.mux.Lock()
+	delete(c.inflight, key)
+	c.mux.Unlock()
+
+	return append([]Pkg(nil), newCall.pkgs...), newCall.err
+}
+
+// Info returns the packages named pkgs, only querying the ones not cached.
+func (c *CachedClient) Info(ctx context.Context, pkgs []string, reqEditors ...aur.RequestEditorFn) ([]Pkg, error) {
+	results := make([]Pkg, 0, len(pkgs))
+	missing := make([]string, 0, len(pkgs))
+
+	c.mux.Lock()
+	for _, name := range pkgs {
+		if entry, ok := c.lookup("info:" + name); ok {
+			results = append(results, entry.Pkgs...)
+		} else {
+			missing = append(missing, name)
+		}
+	}
+	c.mux.Unlock()
+
+	if len(missing) == 0 {
+		return results, nil
+	}
+
+	sort.Strings(missing)
+
+	fetched, err := c.do("info:"+strings.Join(missing, ","), func() ([]Pkg, error) {
+		return c.client.Info(ctx, missing, reqEditors...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	found := make(map[string][]Pkg, len(fetched))
+
+	for i := range fetched {
+		found[fetched[i].Name] = append(found[fetched[i].Name], fetched[i])
+	}
+
+	c.mux.Lock()
+	for _, name := range missing {
+		// packages missing from the AUR are cached too
+		c.entries["info:"+name] = cacheEntry{Time: now, Pkgs: found[name]}
+	}
+	c.save()
+	c.mux.Unlock()
+
+	return append(results, fetched...), nil
+}
+
+// Search returns the packages matching query.
+func (c *CachedClient) Search(ctx context.Context, query string, by aur.By,
+	reqEditors ...aur.RequestEditorFn) ([]Pkg, error) {
+	key := "search:" + by.String() + ":" + query
+
+	c.mux.Lock()
+	entry, ok := c.lookup(key)
+	c.mux.Unlock()
+
+	if ok {
+		return append([]Pkg(nil), entry.Pkgs...), nil
+	}
+
+	pkgs, err := c.do(key, func() ([]Pkg, error) {
+		return c.client.Search(ctx, query, by, reqEditors...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.mux.Lock()
+	c.entries[key] = cacheEntry{Time: time.Now(), Pkgs: pkgs}
+	c.save()
+	c.mux.Unlock()
+
+	return pkgs, nil
+}
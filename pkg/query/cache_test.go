@@ -0,0 +1,165 @@
+package query
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jguer/aur"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingClient struct {
+	mux      sync.Mutex
+	requests [][]string
+	release  chan struct{}
+}
+
+func (c *countingClient) Search(ctx context.Context, query string, by aur.By,
+	reqEditors ...aur.RequestEditorFn) ([]aur.Pkg, error) {
+	c.mux.Lock()
+	c.requests = append(c.requests, []string{by.String(), query})
+	c.mux.Unlock()
+
+	if c.release != nil {
+		<-c.release
+	}
+
+	return []aur.Pkg{{Name: query + "-git"}, {Name: query + "-bin"}}, nil
+}
+
+func (c *countingClient) Info(ctx context.Context, pkgs []string, reqEditors ...aur.RequestEditorFn) ([]aur.Pkg, error) {
+	c.mux.Lock()
+	c.requests = append(c.requests, append([]string(nil), pkgs...))
+	c.mux.Unlock()
+
+	results := make([]aur.Pkg, 0, len(pkgs))
+	for _, name := range pkgs {
+		if name != "missing" {
+			results = append(results, aur.Pkg{Name: name, Version: "1.0-1"})
+		}
+	}
+
+	return results, nil
+}
+
+func pkgNames(pkgs []aur.Pkg) []string {
+	names := make([]string, 0, len(pkgs))
+	for i := range pkgs {
+		names = append(names, pkgs[i].Name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func TestCachedClientInfo(t *testing.T) {
+	t.Parallel()
+
+	cachePath := filepath.Join(t.TempDir(), "aur-rpc.json")
+	client := &countingClient{}
+	cached := NewCachedClient(client, cachePath, time.Minute, false)
+
+	pkgs, err := cached.Info(context.Background(), []string{"yay", "missing"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"yay"}, pkgNames(pkgs))
+
+	// only the package not seen yet is requested
+	pkgs, err = cached.Info(context.Background(), []string{"yay", "missing", "paru"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"paru", "yay"}, pkgNames(pkgs))
+	assert.Equal(t, [][]string{{"missing", "yay"}, {"paru"}}, client.requests)
+
+	// a new session reads the cache from disk
+	reloaded := NewCachedClient(client, cachePath, time.Minute, false)
+	pkgs, err = reloaded.Info(context.Background(), []string{"paru", "yay"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"paru", "yay"}, pkgNames(pkgs))
+	assert.Len(t, client.requests, 2)
+
+	// refresh ignores the responses cached before the session
+	refreshed := NewCachedClient(client, cachePath, time.Minute, true)
+	_, err = refreshed.Info(context.Background(), []string{"yay"})
+	assert.NoError(t, err)
+	_, err = refreshed.Info(context.Background(), []string{"yay"})
+	assert.NoError(t, err)
+	assert.Len(t, client.requests, 3)
+}
+
+func TestCachedClientSearch(t *testing.T) {
+	t.Parallel()
+
+	cachePath := filepath.Join(t.TempDir(), "aur-rpc.json")
+	client := &countingClient{}
+	cached := NewCachedClient(client, cachePath, time.Minute, false)
+
+	for i := 0; i < 2; i++ {
+		pkgs, err := cached.Search(context.Background(), "yay", aur.NameDesc)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"yay-bin", "yay-git"}, pkgNames(pkgs))
+	}
+
+	_, err := cached.Search(context.Background(), "yay", aur.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"name-desc", "yay"}, {"name", "yay"}}, client.requests)
+}
+
+func TestCachedClientDisabled(t *testing.T) {
+	t.Parallel()
+
+	cachePath := filepath.Join(t.TempDir(), "aur-rpc.json")
+	client := &countingClient{}
+	cached := NewCachedClient(client, cachePath, 0, false)
+
+	for i := 0; i < 2; i++ {
+		_, err := cached.Info(context.Background(), []string{"yay"})
+		assert.NoError(t, err)
+	}
+
+	assert.Len(t, client.requests, 2)
+	assert.NoFileExists(t, cachePath)
+}
+
+func TestCachedClientCoalesce(t *testing.T) {
+	t.Parallel()
+
+	client := &countingClient{release: make(chan struct{})}
+	cached := NewCachedClient(client, filepath.Join(t.TempDir(), "aur-rpc.json"), time.Minute, false)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			pkgs, err := cached.Search(context.Background(), "yay", aur.NameDesc)
+			assert.NoError(t, err)
+			assert.Len(t, pkgs, 2)
+		}()
+	}
+
+	// wait for the first request to be in flight before releasing it
+	for {
+		client.mux.Lock()
+		started := len(client.requests)
+		client.mux.Unlock()
+
+		if started > 0 {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(client.release)
+	wg.Wait()
+
+	assert.Len(t, client.requests, 1)
+}
@@ -0,0 +1,165 @@
+package query
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Relevance weights of the different kinds of matches of a search term.
+const (
+	scoreExactName    = 100
+	scorePrefixName   = 60
+	scoreTokenName    = 50
+	scoreInName       = 40
+	scoreFuzzyName    = 30
+	scoreTokenDesc    = 20
+	scoreInDesc       = 10
+	scoreFuzzyDesc    = 5
+	scoreFuzzyPerTypo = 10
+	scorePopularity   = 5
+)
+
+// tokenize splits s in lowercase words on anything but letters and digits.
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// maxTypos is the number of typos tolerated in a term of length n.
+func maxTypos(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance returns the optimal string alignment distance between a and b,
+// a Levenshtein distance also counting adjacent transpositions as one edit.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// three rows are enough to look back for transpositions
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = minInt(cur[j], prev2[j-2]+1)
+			}
+		}
+
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// fuzzyScore returns the score of the closest word of words within the typos
+// tolerated for term, or zero when none is close enough.
+func fuzzyScore(term string, words []string, score int) float64 {
+	limit := maxTypos(len([]rune(term)))
+	if limit == 0 {
+		return 0
+	}
+
+	best := limit + 1
+
+	for _, word := range words {
+		if d := editDistance(term, word); d < best {
+			best = d
+		}
+	}
+
+	if best > limit {
+		return 0
+	}
+
+	return math.Max(float64(score-(best-1)*scoreFuzzyPerTypo), 1)
+}
+
+func containsWord(words []string, term string) bool {
+	for _, word := range words {
+		if word == term {
+			return true
+		}
+	}
+
+	return false
+}
+
+// termScore returns how well a single lowercase term matches a package.
+func termScore(term, name string, nameTokens, descTokens []string, desc string) float64 {
+	switch {
+	case name == term:
+		return scoreExactName
+	case strings.HasPrefix(name, term):
+		return scorePrefixName
+	case containsWord(nameTokens, term):
+		return scoreTokenName
+	case strings.Contains(name, term):
+		return scoreInName
+	case containsWord(descTokens, term):
+		return scoreTokenDesc
+	case strings.Contains(desc, term):
+		return scoreInDesc
+	}
+
+	if score := fuzzyScore(term, append(nameTokens, name), scoreFuzzyName); score > 0 {
+		return score
+	}
+
+	return fuzzyScore(term, descTokens, scoreFuzzyDesc)
+}
+
+// Score ranks a package by relevance to the search terms. Every term must
+// match the name or description, exactly, as a prefix, as a word or with a
+// few typos, otherwise the score is zero. Popularity breaks ties between
+// packages matching equally well.
+func Score(terms []string, name, description string, popularity float64) float64 {
+	name = strings.ToLower(name)
+	desc := strings.ToLower(description)
+	nameTokens := tokenize(name)
+	descTokens := tokenize(desc)
+
+	total := 0.0
+
+	for _, term := range terms {
+		score := termScore(strings.ToLower(term), name, nameTokens, descTokens, desc)
+		if score == 0 {
+			return 0
+		}
+
+		total += score
+	}
+
+	return total + scorePopularity*math.Log1p(math.Max(popularity, 0))
+}
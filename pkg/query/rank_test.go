@@ -0,0 +1,92 @@
+package query
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditDistance(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		a, b string
+		want int
+	}{
+		{"firefox", "firefox", 0},
+		{"firefix", "firefox", 1},
+		{"frefox", "firefox", 1},
+		{"fierfox", "firefox", 1},
+		{"fierfix", "firefox", 2},
+		{"", "yay", 3},
+		{"çava", "cava", 1},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, editDistance(tc.a, tc.b), "%s %s", tc.a, tc.b)
+		assert.Equal(t, tc.want, editDistance(tc.b, tc.a), "%s %s", tc.b, tc.a)
+	}
+}
+
+func TestScoreMatches(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc        string
+		terms       []string
+		name        string
+		description string
+		match       bool
+	}{
+		{"exact name", []string{"yay"}, "yay", "AUR helper", true},
+		{"case insensitive", []string{"YAY"}, "yay-bin", "AUR helper", true},
+		{"description word", []string{"helper"}, "paru", "Feature packed AUR helper", true},
+		{"name typo", []string{"firefix"}, "firefox", "Standalone web browser", true},
+		{"description typo", []string{"browsr"}, "firefox", "Standalone web browser", true},
+		{"short terms need exact matches", []string{"yoy"}, "yay", "AUR helper", false},
+		{"all terms must match", []string{"yay", "browser"}, "yay", "AUR helper", false},
+		{"too many typos", []string{"fyrefix"}, "firefox", "Standalone web browser", false},
+	}
+
+	for _, tc := range testCases {
+		score := Score(tc.terms, tc.name, tc.description, 0)
+		assert.Equal(t, tc.match, score > 0, tc.desc)
+	}
+}
+
+func TestScoreRanking(t *testing.T) {
+	t.Parallel()
+
+	type pkg struct {
+		name        string
+		description string
+		popularity  float64
+	}
+
+	pkgs := []pkg{
+		{"python-yaml", "Python bindings for YAML, using fast libYAML library", 0.5},
+		{"yay-bin", "Yet another yogurt. Pacman wrapper and AUR helper written in go. Pre-compiled.", 5},
+		{"yay", "Yet another yogurt. Pacman wrapper and AUR helper written in go.", 20},
+		{"yaycache", "Cache cleaner", 0},
+		{"ruby-yay", "A tool to show off your programs", 0},
+		{"yay-git", "Yet another yogurt. Pacman wrapper and AUR helper written in go.", 1},
+	}
+
+	score := func(p pkg) float64 {
+		return Score([]string{"yay"}, p.name, p.description, p.popularity)
+	}
+
+	sort.SliceStable(pkgs, func(i, j int) bool {
+		return score(pkgs[i]) > score(pkgs[j])
+	})
+
+	names := make([]string, 0, len(pkgs))
+	for _, p := range pkgs {
+		if score(p) > 0 {
+			names = append(names, p.name)
+		}
+	}
+
+	assert.Equal(t, []string{"yay", "yay-bin", "yay-git", "yaycache", "ruby-yay"}, names)
+}
@@ -3,8 +3,12 @@ package settings
 import (
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Jguer/yay/v11/pkg/metadata"
+	"github.com/Jguer/yay/v11/pkg/query"
 	"github.com/Jguer/yay/v11/pkg/settings/parser"
+	"github.com/Jguer/yay/v11/pkg/sources"
 )
 
 func (c *Configuration) ParseCommandLine(a *parser.Arguments) error {
@@ -17,6 +21,18 @@ func (c *Configuration) ParseCommandLine(a *parser.Arguments) error {
 	// Reload CmdBuilder
 	c.Runtime.CmdBuilder = c.CmdBuilder(nil)
 
+	if len(c.PKGBUILDSources) != 0 {
+		srcs := make([]sources.Source, 0, len(c.PKGBUILDSources))
+		for _, source := range c.PKGBUILDSources {
+			srcs = append(srcs, sources.Source{Name: source.Name, URL: source.URL, Priority: source.Priority})
+		}
+
+		c.Runtime.SourceBases = sources.NewResolved()
+		c.Runtime.Sources = sources.NewClient(c.Runtime.QueryClient, c.Runtime.CmdBuilder,
+			c.Runtime.SourcesPath, srcs, c.Runtime.SourceBases)
+		c.Runtime.QueryClient = c.Runtime.Sources
+	}
+
 	return nil
 }
 
@@ -29,6 +45,13 @@ func (c *Configuration) extractYayOptions(a *parser.Arguments) {
 
 	c.Runtime.AURClient.BaseURL = strings.TrimRight(c.AURURL, "/") + "/rpc.php?"
 	c.AURURL = strings.TrimRight(c.AURURL, "/")
+
+	c.Runtime.QueryClient = query.NewCachedClient(c.Runtime.AURClient, c.Runtime.AURCachePath,
+		time.Duration(c.AURCacheTTL)*time.Second, c.RefreshAUR)
+	if c.AURMetadata || c.Offline {
+		c.Runtime.QueryClient = metadata.NewClient(c.Runtime.QueryClient, c.Runtime.HTTPClient,
+			c.AURURL, c.Runtime.MetadataPath, c.MetadataInterval, c.Offline)
+	}
 }
 
 func (c *Configuration) handleOption(option, value string) bool {
@@ -45,6 +68,14 @@ func (c *Configuration) handleOption(option, value string) bool {
 		c.Devel = true
 	case "nodevel":
 		c.Devel = false
+	case "checknews":
+		c.CheckNews = true
+	case "nochecknews":
+		c.CheckNews = false
+	case "newsstop":
+		c.NewsStop = true
+	case "nonewsstop":
+		c.NewsStop = false
 	case "timeupdate":
 		c.TimeUpdate = true
 	case "notimeupdate":
@@ -53,6 +84,24 @@ func (c *Configuration) handleOption(option, value string) bool {
 		c.SortMode = TopDown
 	case "bottomup":
 		c.SortMode = BottomUp
+	case "aurmetadata":
+		c.AURMetadata = true
+	case "noaurmetadata":
+		c.AURMetadata = false
+	case "offline":
+		c.Offline = true
+	case "refresh-aur":
+		c.RefreshAUR = true
+	case "aurcachettl":
+		n, err := strconv.Atoi(value)
+		if err == nil {
+			c.AURCacheTTL = n
+		}
+	case "metadatainterval":
+		n, err := strconv.Atoi(value)
+		if err == nil {
+			c.MetadataInterval = n
+		}
 	case "completioninterval":
 		n, err := strconv.Atoi(value)
 		if err == nil {
@@ -108,6 +157,8 @@ func (c *Configuration) handleOption(option, value string) bool {
 		c.GitFlags = value
 	case "builddir":
 		c.BuildDir = value
+	case "patchdir":
+		c.PatchDir = value
 	case "editor":
 		c.Editor = value
 	case "editorflags":
@@ -133,6 +184,16 @@ func (c *Configuration) handleOption(option, value string) bool {
 		if err == nil && n > 0 {
 			c.RequestSplitN = n
 		}
+	case "gitretries":
+		n, err := strconv.Atoi(value)
+		if err == nil && n >= 0 {
+			c.GitRetries = n
+		}
+	case "gitretrybackoff":
+		n, err := strconv.Atoi(value)
+		if err == nil && n >= 0 {
+			c.GitRetryBackoff = n
+		}
 	case "sudoloop":
 		c.SudoLoop = true
 	case "nosudoloop":
@@ -145,6 +206,18 @@ func (c *Configuration) handleOption(option, value string) bool {
 		c.PGPFetch = true
 	case "nopgpfetch":
 		c.PGPFetch = false
+	case "pgpkeyring":
+		c.PGPKeyring = true
+	case "nopgpkeyring":
+		c.PGPKeyring = false
+	case "pgphome":
+		c.PGPHome = value
+	case "pgpkeyservers":
+		c.PGPKeyservers = strings.Fields(value)
+	case "pgpwkd":
+		c.PGPWKD = true
+	case "nopgpwkd":
+		c.PGPWKD = false
 	case "upgrademenu":
 		c.UpgradeMenu = true
 	case "noupgrademenu":
@@ -179,6 +252,29 @@ func (c *Configuration) handleOption(option, value string) bool {
 		c.RemoveMake = "no"
 	case "askremovemake":
 		c.RemoveMake = "ask"
+	case "recovercheckout":
+		c.RecoverCheckout = value
+	case "sourcecachedir":
+		c.SourceCacheDir = value
+	case "sourcecachesize":
+		n, err := strconv.Atoi(value)
+		if err == nil && n >= 0 {
+			c.SourceCacheSize = n
+		}
+	case "downloadjobs":
+		n, err := strconv.Atoi(value)
+		if err == nil && n >= 0 {
+			c.DownloadJobs = n
+		}
+	case "downloadprogress":
+		c.DownloadProgress = value
+	case "diskcheck":
+		c.DiskCheck = value
+	case "diskmultiplier":
+		n, err := strconv.ParseFloat(value, 64)
+		if err == nil && n > 0 {
+			c.DiskMultiplier = n
+		}
 	default:
 		return false
 	}
@@ -10,13 +10,20 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/leonelquinteros/gotext"
+	"golang.org/x/term"
 
 	"github.com/Jguer/aur"
 
+	"github.com/Jguer/yay/v11/pkg/download"
+	"github.com/Jguer/yay/v11/pkg/pin"
 	"github.com/Jguer/yay/v11/pkg/settings/exe"
+	"github.com/Jguer/yay/v11/pkg/settings/makepkgconf"
 	"github.com/Jguer/yay/v11/pkg/settings/parser"
+	"github.com/Jguer/yay/v11/pkg/srccache"
+	"github.com/Jguer/yay/v11/pkg/stringset"
 	"github.com/Jguer/yay/v11/pkg/vcs"
 )
 
@@ -32,50 +39,105 @@ var HideMenus = false
 // NoConfirm indicates if user input should be skipped.
 var NoConfirm = false
 
+// NewsFeed describes a news feed shown by -Pw and checked before sysupgrade.
+type NewsFeed struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Format is rss or atom, it is detected from the document when empty.
+	Format string `json:"format"`
+	// MaxAge hides items older than this many days. When zero, items older
+	// than the last build of the native packages are hidden instead.
+	MaxAge int `json:"maxage"`
+}
+
+// DefaultNewsFeed is the Arch Linux news feed.
+var DefaultNewsFeed = NewsFeed{
+	Name:   "archlinux",
+	URL:    "https://archlinux.org/feeds/news",
+	Format: "rss",
+}
+
+// PKGBUILDSource describes a repository of PKGBUILDs used alongside the AUR.
+type PKGBUILDSource struct {
+	Name string `json:"name"`
+	// URL is a git repository, or a local directory when it is an absolute path.
+	URL string `json:"url"`
+	// Priority is relative to the AUR, whose priority is zero. Packages of
+	// sources with a positive priority replace the AUR ones.
+	Priority int `json:"priority"`
+}
+
 // Configuration stores yay's config.
 type Configuration struct {
-	AURURL             string   `json:"aururl"`
-	BuildDir           string   `json:"buildDir"`
-	Editor             string   `json:"editor"`
-	EditorFlags        string   `json:"editorflags"`
-	MakepkgBin         string   `json:"makepkgbin"`
-	MakepkgConf        string   `json:"makepkgconf"`
-	PacmanBin          string   `json:"pacmanbin"`
-	PacmanConf         string   `json:"pacmanconf"`
-	ReDownload         string   `json:"redownload"`
-	ReBuild            string   `json:"rebuild"`
-	AnswerClean        string   `json:"answerclean"`
-	AnswerDiff         string   `json:"answerdiff"`
-	AnswerEdit         string   `json:"answeredit"`
-	AnswerUpgrade      string   `json:"answerupgrade"`
-	GitBin             string   `json:"gitbin"`
-	GpgBin             string   `json:"gpgbin"`
-	GpgFlags           string   `json:"gpgflags"`
-	MFlags             string   `json:"mflags"`
-	SortBy             string   `json:"sortby"`
-	SearchBy           string   `json:"searchby"`
-	GitFlags           string   `json:"gitflags"`
-	RemoveMake         string   `json:"removemake"`
-	SudoBin            string   `json:"sudobin"`
-	SudoFlags          string   `json:"sudoflags"`
-	RequestSplitN      int      `json:"requestsplitn"`
-	SearchMode         int      `json:"-"`
-	SortMode           int      `json:"sortmode"`
-	CompletionInterval int      `json:"completionrefreshtime"`
-	SudoLoop           bool     `json:"sudoloop"`
-	TimeUpdate         bool     `json:"timeupdate"`
-	Devel              bool     `json:"devel"`
-	CleanAfter         bool     `json:"cleanAfter"`
-	Provides           bool     `json:"provides"`
-	PGPFetch           bool     `json:"pgpfetch"`
-	UpgradeMenu        bool     `json:"upgrademenu"`
-	CleanMenu          bool     `json:"cleanmenu"`
-	DiffMenu           bool     `json:"diffmenu"`
-	EditMenu           bool     `json:"editmenu"`
-	CombinedUpgrade    bool     `json:"combinedupgrade"`
-	UseAsk             bool     `json:"useask"`
-	BatchInstall       bool     `json:"batchinstall"`
-	Runtime            *Runtime `json:"-"`
+	AURURL             string                `json:"aururl"`
+	BuildDir           string                `json:"buildDir"`
+	PatchDir           string                `json:"patchdir"`
+	SourceCacheDir     string                `json:"sourcecachedir"`
+	PGPHome            string                `json:"pgphome"`
+	Editor             string                `json:"editor"`
+	EditorFlags        string                `json:"editorflags"`
+	MakepkgBin         string                `json:"makepkgbin"`
+	MakepkgConf        string                `json:"makepkgconf"`
+	PacmanBin          string                `json:"pacmanbin"`
+	PacmanConf         string                `json:"pacmanconf"`
+	ReDownload         string                `json:"redownload"`
+	ReBuild            string                `json:"rebuild"`
+	AnswerClean        string                `json:"answerclean"`
+	AnswerDiff         string                `json:"answerdiff"`
+	AnswerEdit         string                `json:"answeredit"`
+	AnswerUpgrade      string                `json:"answerupgrade"`
+	GitBin             string                `json:"gitbin"`
+	GpgBin             string                `json:"gpgbin"`
+	GpgFlags           string                `json:"gpgflags"`
+	MFlags             string                `json:"mflags"`
+	SortBy             string                `json:"sortby"`
+	SearchBy           string                `json:"searchby"`
+	GitFlags           string                `json:"gitflags"`
+	RemoveMake         string                `json:"removemake"`
+	RecoverCheckout    string                `json:"recovercheckout"`
+	DiskCheck          string                `json:"diskcheck"`
+	DownloadProgress   string                `json:"downloadprogress"`
+	SudoBin            string                `json:"sudobin"`
+	SudoFlags          string                `json:"sudoflags"`
+	RequestSplitN      int                   `json:"requestsplitn"`
+	GitRetries         int                   `json:"gitretries"`
+	GitRetryBackoff    int                   `json:"gitretrybackoff"`
+	DiskMultiplier     float64               `json:"diskmultiplier"`
+	SourceCacheSize    int                   `json:"sourcecachesize"`
+	DownloadJobs       int                   `json:"downloadjobs"`
+	SearchMode         int                   `json:"-"`
+	SortMode           int                   `json:"sortmode"`
+	CompletionInterval int                   `json:"completionrefreshtime"`
+	MetadataInterval   int                   `json:"metadatarefreshtime"`
+	AURCacheTTL        int                   `json:"aurcachettl"`
+	SudoLoop           bool                  `json:"sudoloop"`
+	TimeUpdate         bool                  `json:"timeupdate"`
+	Devel              bool                  `json:"devel"`
+	CleanAfter         bool                  `json:"cleanAfter"`
+	Provides           bool                  `json:"provides"`
+	PGPFetch           bool                  `json:"pgpfetch"`
+	PGPKeyring         bool                  `json:"pgpkeyring"`
+	PGPWKD             bool                  `json:"pgpwkd"`
+	UpgradeMenu        bool                  `json:"upgrademenu"`
+	CleanMenu          bool                  `json:"cleanmenu"`
+	DiffMenu           bool                  `json:"diffmenu"`
+	EditMenu           bool                  `json:"editmenu"`
+	CombinedUpgrade    bool                  `json:"combinedupgrade"`
+	UseAsk             bool                  `json:"useask"`
+	BatchInstall       bool                  `json:"batchinstall"`
+	CheckNews          bool                  `json:"checknews"`
+	NewsStop           bool                  `json:"newsstop"`
+	AURMetadata        bool                  `json:"aurmetadata"`
+	Offline            bool                  `json:"-"`
+	RefreshAUR         bool                  `json:"-"`
+	NewsFeeds          []NewsFeed            `json:"newsfeeds"`
+	PKGBUILDSources    []PKGBUILDSource      `json:"pkgbuildsources"`
+	ABSURLs            download.ABSURLs      `json:"absurls"`
+	AURMirrors         []download.AURMirror  `json:"aurmirrors"`
+	PGPKeyservers      []string              `json:"pgpkeyservers"`
+	PGPPins            map[string][]string   `json:"pgppins"`
+	MakepkgOverrides   []exe.MakepkgOverride `json:"makepkgoverrides"`
+	Runtime            *Runtime              `json:"-"`
 }
 
 // SaveConfig writes yay config to file.
@@ -110,6 +172,9 @@ func (c *Configuration) Save(configPath string) error {
 func (c *Configuration) expandEnv() {
 	c.AURURL = os.ExpandEnv(c.AURURL)
 	c.BuildDir = os.ExpandEnv(c.BuildDir)
+	c.PatchDir = os.ExpandEnv(c.PatchDir)
+	c.SourceCacheDir = os.ExpandEnv(c.SourceCacheDir)
+	c.PGPHome = os.ExpandEnv(c.PGPHome)
 	c.Editor = os.ExpandEnv(c.Editor)
 	c.EditorFlags = os.ExpandEnv(c.EditorFlags)
 	c.MakepkgBin = os.ExpandEnv(c.MakepkgBin)
@@ -132,6 +197,14 @@ func (c *Configuration) expandEnv() {
 	c.AnswerEdit = os.ExpandEnv(c.AnswerEdit)
 	c.AnswerUpgrade = os.ExpandEnv(c.AnswerUpgrade)
 	c.RemoveMake = os.ExpandEnv(c.RemoveMake)
+
+	for i := range c.PKGBUILDSources {
+		c.PKGBUILDSources[i].URL = os.ExpandEnv(c.PKGBUILDSources[i].URL)
+	}
+
+	for i := range c.MakepkgOverrides {
+		c.MakepkgOverrides[i].MakepkgConf = os.ExpandEnv(c.MakepkgOverrides[i].MakepkgConf)
+	}
 }
 
 func (c *Configuration) String() string {
@@ -180,12 +253,16 @@ func DefaultConfig() *Configuration {
 		MakepkgConf:        "",
 		PacmanBin:          "pacman",
 		PGPFetch:           true,
+		PGPKeyring:         false,
+		PGPWKD:             false,
 		PacmanConf:         "/etc/pacman.conf",
 		GpgFlags:           "",
 		MFlags:             "",
 		GitFlags:           "",
 		SortMode:           BottomUp,
 		CompletionInterval: 7,
+		MetadataInterval:   1,
+		AURCacheTTL:        300,
 		SortBy:             "votes",
 		SearchBy:           "name-desc",
 		SudoLoop:           false,
@@ -195,6 +272,8 @@ func DefaultConfig() *Configuration {
 		SudoFlags:          "",
 		TimeUpdate:         false,
 		RequestSplitN:      150,
+		GitRetries:         3,
+		GitRetryBackoff:    1,
 		ReDownload:         "no",
 		ReBuild:            "no",
 		BatchInstall:       false,
@@ -203,6 +282,12 @@ func DefaultConfig() *Configuration {
 		AnswerEdit:         "",
 		AnswerUpgrade:      "",
 		RemoveMake:         "ask",
+		RecoverCheckout:    "ask",
+		DiskCheck:          "warn",
+		DiskMultiplier:     2,
+		SourceCacheSize:    10240,
+		DownloadJobs:       0,
+		DownloadProgress:   "auto",
 		Provides:           true,
 		UpgradeMenu:        true,
 		CleanMenu:          true,
@@ -210,6 +295,17 @@ func DefaultConfig() *Configuration {
 		EditMenu:           false,
 		UseAsk:             false,
 		CombinedUpgrade:    false,
+		CheckNews:          true,
+		NewsStop:           false,
+		PKGBUILDSources:    []PKGBUILDSource{},
+		AURMirrors:         []download.AURMirror{},
+		PGPKeyservers:      []string{},
+		PGPPins:            map[string][]string{},
+		MakepkgOverrides:   []exe.MakepkgOverride{},
+		ABSURLs: download.ABSURLs{
+			Templates: download.DefaultABSTemplates(),
+			Repos:     download.DefaultABSRepos(),
+		},
 	}
 }
 
@@ -218,10 +314,21 @@ func NewConfig(version string) (*Configuration, error) {
 
 	cacheHome := getCacheHome()
 	newConfig.BuildDir = cacheHome
+	newConfig.SourceCacheDir = filepath.Join(cacheHome, sourceCacheDirName)
+	newConfig.PGPHome = filepath.Join(getDataHome(cacheHome), pgpHomeDirName)
 
 	configPath := getConfigPath()
+	if configPath != "" {
+		newConfig.PatchDir = filepath.Join(filepath.Dir(configPath), patchDirName)
+	}
+
 	newConfig.load(configPath)
 
+	// set after loading as decoding merges into the existing slice elements
+	if len(newConfig.NewsFeeds) == 0 {
+		newConfig.NewsFeeds = []NewsFeed{DefaultNewsFeed}
+	}
+
 	if aurdest := os.Getenv("AURDEST"); aurdest != "" {
 		newConfig.BuildDir = aurdest
 	}
@@ -237,11 +344,21 @@ func NewConfig(version string) (*Configuration, error) {
 		Mode:           parser.ModeAny,
 		SaveConfig:     false,
 		CompletionPath: filepath.Join(cacheHome, completionFileName),
+		NewsReadPath:   filepath.Join(cacheHome, newsFileName),
+		MetadataPath:   filepath.Join(cacheHome, metadataFileName),
+		AURCachePath:   filepath.Join(cacheHome, aurCacheFileName),
+		SourcesPath:    filepath.Join(cacheHome, sourcesDirName),
+		PGPKeysPath:    filepath.Join(cacheHome, pgpKeysFileName),
 		CmdBuilder:     newConfig.CmdBuilder(nil),
 		PacmanConf:     nil,
 		VCSStore:       nil,
+		PinStore:       nil,
+		OriginStore:    nil,
+		LocalBuildDirs: map[string]string{},
+		KeptEdits:      make(stringset.StringSet),
 		HTTPClient:     &http.Client{},
 		AURClient:      nil,
+		QueryClient:    nil,
 	}
 
 	var errAUR error
@@ -260,7 +377,19 @@ func NewConfig(version string) (*Configuration, error) {
 	newConfig.Runtime.VCSStore = vcs.NewInfoStore(
 		filepath.Join(cacheHome, vcsFileName), newConfig.Runtime.CmdBuilder)
 
-	err := newConfig.Runtime.VCSStore.Load()
+	if err := newConfig.Runtime.VCSStore.Load(); err != nil {
+		return newConfig, err
+	}
+
+	newConfig.Runtime.PinStore = pin.NewStore(filepath.Join(cacheHome, pinFileName))
+
+	if err := newConfig.Runtime.PinStore.Load(); err != nil {
+		return newConfig, err
+	}
+
+	newConfig.Runtime.OriginStore = pin.NewStore(filepath.Join(cacheHome, originFileName))
+
+	err := newConfig.Runtime.OriginStore.Load()
 
 	return newConfig, err
 }
@@ -284,16 +413,103 @@ func (c *Configuration) load(configPath string) {
 	}
 }
 
+// FetchOptions returns how PKGBUILD repositories are fetched from the AUR and
+// the ABS.
+func (c *Configuration) FetchOptions() download.FetchOptions {
+	return download.FetchOptions{
+		Retries: c.GitRetries,
+		Backoff: time.Duration(c.GitRetryBackoff) * time.Second,
+		Mirrors: c.AURMirrors,
+		Jobs:    c.DownloadJobs,
+		Live:    c.LiveProgress(),
+	}
+}
+
+// LiveProgress reports whether downloads show a progress display redrawn in
+// place, by default only when printing to a terminal.
+func (c *Configuration) LiveProgress() bool {
+	switch c.DownloadProgress {
+	case "live":
+		return true
+	case "plain":
+		return false
+	}
+
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// PGPHomeDir returns the GNUPGHOME of the keyring dedicated to PKGBUILD
+// signature keys, empty when the default keyring of the user is used.
+func (c *Configuration) PGPHomeDir() string {
+	if !c.PGPKeyring {
+		return ""
+	}
+
+	return c.PGPHome
+}
+
+// SourceCache returns the cache of downloaded sources, nil when it is
+// disabled by setting its size to 0.
+func (c *Configuration) SourceCache() *srccache.Cache {
+	if c.SourceCacheSize <= 0 || c.SourceCacheDir == "" {
+		return nil
+	}
+
+	return &srccache.Cache{Dir: c.SourceCacheDir, MaxSize: int64(c.SourceCacheSize) << 20}
+}
+
+// LoadMakepkgConf reads the makepkg configuration in effect into the runtime.
+func (c *Configuration) LoadMakepkgConf() error {
+	conf, err := makepkgconf.Load(c.MakepkgConf, os.Environ())
+	if err != nil {
+		return err
+	}
+
+	c.Runtime.MakepkgConf = conf
+
+	return nil
+}
+
+// MakepkgConfFor returns the makepkg configuration used to build pkgbase,
+// accounting for its MakepkgOverrides. It is nil if the configuration could
+// not be read.
+func (c *Configuration) MakepkgConfFor(pkgbase string) *makepkgconf.Config {
+	override := exe.MergeOverrides(c.MakepkgOverrides, pkgbase)
+	if override.MakepkgConf == "" && len(override.Env) == 0 {
+		return c.Runtime.MakepkgConf
+	}
+
+	confPath := c.MakepkgConf
+	if override.MakepkgConf != "" {
+		confPath = override.MakepkgConf
+	}
+
+	conf, err := makepkgconf.Load(confPath, append(os.Environ(), override.Environ()...))
+	if err != nil {
+		return nil
+	}
+
+	return conf
+}
+
 func (c *Configuration) CmdBuilder(runner exe.Runner) exe.ICmdBuilder {
 	if runner == nil {
 		runner = &exe.OSRunner{}
 	}
 
+	makepkgEnv := []string{}
+	// makepkg verifies sources against the dedicated keyring
+	if home := c.PGPHomeDir(); home != "" {
+		makepkgEnv = append(makepkgEnv, "GNUPGHOME="+home)
+	}
+
 	return &exe.CmdBuilder{
 		GitBin:           c.GitBin,
 		GitFlags:         strings.Fields(c.GitFlags),
 		MakepkgFlags:     strings.Fields(c.MFlags),
 		MakepkgConfPath:  c.MakepkgConf,
+		MakepkgOverrides: c.MakepkgOverrides,
+		MakepkgEnv:       makepkgEnv,
 		MakepkgBin:       c.MakepkgBin,
 		SudoBin:          c.SudoBin,
 		SudoFlags:        strings.Fields(c.SudoFlags),
@@ -11,8 +11,41 @@ const configFileName string = "config.json"
 // vcsFileName holds the name of the vcs file.
 const vcsFileName string = "vcs.json"
 
+// pinFileName holds the name of the file storing pinned AUR packages.
+const pinFileName string = "pins.json"
+
+// originFileName holds the name of the file storing the origin of locally built packages.
+const originFileName string = "origins.json"
+
 const completionFileName string = "completion.cache"
 
+// metadataFileName holds the name of the AUR metadata dump.
+const metadataFileName string = "packages-meta-ext-v1.json.gz"
+
+// aurCacheFileName holds the name of the AUR RPC response cache.
+const aurCacheFileName string = "aur-rpc.json"
+
+// sourcesDirName holds the name of the directory caching PKGBUILD sources.
+const sourcesDirName string = "pkgbuild-sources"
+
+// newsFileName holds the name of the file storing read news.
+const newsFileName string = "news.json"
+
+// sourceCacheDirName holds the name of the directory sharing downloaded sources
+// between packages.
+const sourceCacheDirName string = "source-cache"
+
+// pgpHomeDirName holds the name of the GNUPGHOME of the keyring dedicated to
+// PKGBUILD signature keys.
+const pgpHomeDirName string = "gnupg"
+
+// pgpKeysFileName holds the name of the file storing the package bases
+// requiring each PGP key.
+const pgpKeysFileName string = "pgpkeys.json"
+
+// patchDirName holds the name of the directory holding local PKGBUILD patches.
+const patchDirName string = "patches"
+
 func getConfigPath() string {
 	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
 		configDir := filepath.Join(configHome, "yay")
@@ -55,6 +88,20 @@ func getCacheHome() string {
 	return os.TempDir()
 }
 
+// getDataHome returns the directory holding data yay keeps, fallback when
+// neither XDG_DATA_HOME nor HOME are set. The directory is not created.
+func getDataHome(fallback string) string {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "yay")
+	}
+
+	if home := os.Getenv("HOME"); home != "" {
+		return filepath.Join(home, ".local", "share", "yay")
+	}
+
+	return fallback
+}
+
 func initDir(dir string) error {
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		if err = os.MkdirAll(dir, 0o755); err != nil {
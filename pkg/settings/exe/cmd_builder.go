@@ -29,6 +29,7 @@ type ICmdBuilder interface {
 	Runner
 	BuildGitCmd(ctx context.Context, dir string, extraArgs ...string) *exec.Cmd
 	BuildMakepkgCmd(ctx context.Context, dir string, extraArgs ...string) *exec.Cmd
+	BuildMakepkgBaseCmd(ctx context.Context, pkgbase, dir string, extraArgs ...string) *exec.Cmd
 	BuildPacmanCmd(ctx context.Context, args *parser.Arguments, mode parser.TargetMode, noConfirm bool) *exec.Cmd
 	AddMakepkgFlag(string)
 	SetPacmanDBPath(string)
@@ -40,6 +41,9 @@ type CmdBuilder struct {
 	GitFlags         []string
 	MakepkgFlags     []string
 	MakepkgConfPath  string
+	MakepkgOverrides []MakepkgOverride
+	// MakepkgEnv holds KEY=value variables set for every makepkg command.
+	MakepkgEnv       []string
 	MakepkgBin       string
 	SudoBin          string
 	SudoFlags        []string
@@ -76,13 +80,32 @@ func (c *CmdBuilder) AddMakepkgFlag(flag string) {
 }
 
 func (c *CmdBuilder) BuildMakepkgCmd(ctx context.Context, dir string, extraArgs ...string) *exec.Cmd {
-	args := make([]string, len(c.MakepkgFlags), len(c.MakepkgFlags)+len(extraArgs))
+	return c.buildMakepkgCmd(ctx, dir, &MakepkgOverride{}, extraArgs)
+}
+
+// BuildMakepkgBaseCmd builds a makepkg command for pkgbase, applying the
+// MakepkgOverrides matching it.
+func (c *CmdBuilder) BuildMakepkgBaseCmd(ctx context.Context, pkgbase, dir string, extraArgs ...string) *exec.Cmd {
+	override := MergeOverrides(c.MakepkgOverrides, pkgbase)
+
+	return c.buildMakepkgCmd(ctx, dir, &override, extraArgs)
+}
+
+func (c *CmdBuilder) buildMakepkgCmd(ctx context.Context, dir string,
+	override *MakepkgOverride, extraArgs []string) *exec.Cmd {
+	overrideArgs := override.args()
+
+	args := make([]string, len(c.MakepkgFlags), len(c.MakepkgFlags)+len(overrideArgs)+len(extraArgs)+2)
 	copy(args, c.MakepkgFlags)
 
-	if c.MakepkgConfPath != "" {
+	if override.MakepkgConf != "" {
+		args = append(args, "--config", override.MakepkgConf)
+	} else if c.MakepkgConfPath != "" {
 		args = append(args, "--config", c.MakepkgConfPath)
 	}
 
+	args = append(args, overrideArgs...)
+
 	if len(extraArgs) > 0 {
 		args = append(args, extraArgs...)
 	}
@@ -90,7 +113,15 @@ func (c *CmdBuilder) BuildMakepkgCmd(ctx context.Context, dir string, extraArgs
 	cmd := exec.CommandContext(ctx, c.MakepkgBin, args...)
 	cmd.Dir = dir
 
-	cmd = c.deElevateCommand(ctx, cmd)
+	env := make([]string, len(c.MakepkgEnv), len(c.MakepkgEnv)+len(override.Env))
+	copy(env, c.MakepkgEnv)
+	env = append(env, override.Environ()...)
+
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	cmd = c.deElevateCommand(ctx, cmd, env...)
 
 	return cmd
 }
@@ -100,7 +131,8 @@ func (c *CmdBuilder) SetPacmanDBPath(dbPath string) {
 }
 
 // deElevateCommand, `systemd-run` code based on pikaur.
-func (c *CmdBuilder) deElevateCommand(ctx context.Context, cmd *exec.Cmd) *exec.Cmd {
+// env holds the variables set on cmd that have to be passed through systemd-run.
+func (c *CmdBuilder) deElevateCommand(ctx context.Context, cmd *exec.Cmd, env ...string) *exec.Cmd {
 	if os.Geteuid() != 0 {
 		return cmd
 	}
@@ -139,6 +171,10 @@ func (c *CmdBuilder) deElevateCommand(ctx context.Context, cmd *exec.Cmd) *exec.
 		}
 	}
 
+	for _, envVar := range env {
+		cmdArgs = append(cmdArgs, "-E", envVar)
+	}
+
 	path, _ := exec.LookPath(cmd.Args[0])
 
 	cmdArgs = append(cmdArgs, path)
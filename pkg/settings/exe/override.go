@@ -0,0 +1,95 @@
+package exe
+
+import (
+	"path"
+	"sort"
+)
+
+// MakepkgOverride changes how makepkg builds the package bases matching
+// Pattern, a shell glob such as "linux-*".
+// Example:
+//
+//	{
+//		"pattern": "chromium*",
+//		"flags": ["--skipinteg"],
+//		"env": {"MAKEFLAGS": "-j4"},
+//		"nocheck": true,
+//		"makepkgconf": "/etc/makepkg-clang.conf"
+//	}
+type MakepkgOverride struct {
+	Pattern      string            `json:"pattern"`
+	Flags        []string          `json:"flags"`
+	Env          map[string]string `json:"env"`
+	NoCheck      bool              `json:"nocheck"`
+	IgnoreArch   bool              `json:"ignorearch"`
+	SkipPGPCheck bool              `json:"skippgpcheck"`
+	MakepkgConf  string            `json:"makepkgconf"`
+}
+
+// Matches reports whether the override applies to pkgbase.
+func (o *MakepkgOverride) Matches(pkgbase string) bool {
+	matched, err := path.Match(o.Pattern, pkgbase)
+
+	return err == nil && matched
+}
+
+// MergeOverrides combines the overrides matching pkgbase in order: flags add
+// up, later environment variables and makepkg.conf replace earlier ones.
+func MergeOverrides(overrides []MakepkgOverride, pkgbase string) MakepkgOverride {
+	merged := MakepkgOverride{Pattern: pkgbase, Env: map[string]string{}}
+
+	for i := range overrides {
+		o := &overrides[i]
+		if !o.Matches(pkgbase) {
+			continue
+		}
+
+		merged.Flags = append(merged.Flags, o.Flags...)
+		merged.NoCheck = merged.NoCheck || o.NoCheck
+		merged.IgnoreArch = merged.IgnoreArch || o.IgnoreArch
+		merged.SkipPGPCheck = merged.SkipPGPCheck || o.SkipPGPCheck
+
+		for key, value := range o.Env {
+			merged.Env[key] = value
+		}
+
+		if o.MakepkgConf != "" {
+			merged.MakepkgConf = o.MakepkgConf
+		}
+	}
+
+	return merged
+}
+
+// args returns the makepkg arguments of the override.
+func (o *MakepkgOverride) args() []string {
+	args := make([]string, 0, len(o.Flags)+3)
+	args = append(args, o.Flags...)
+
+	if o.NoCheck {
+		args = append(args, "--nocheck")
+	}
+
+	if o.IgnoreArch {
+		args = append(args, "--ignorearch")
+	}
+
+	if o.SkipPGPCheck {
+		args = append(args, "--skippgpcheck")
+	}
+
+	return args
+}
+
+// Environ returns the environment variables of the override as KEY=value,
+// sorted by name.
+func (o *MakepkgOverride) Environ() []string {
+	env := make([]string, 0, len(o.Env))
+	for key, value := range o.Env {
+		env = append(env, key+"="+value)
+	}
+
+	sort.Strings(env)
+
+	return env
+}
@@ -0,0 +1,278 @@
+// Package makepkgconf reads makepkg.conf the way makepkg does, so yay knows
+// where packages, sources and logs go without spawning makepkg.
+package makepkgconf
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultPath is the system makepkg.conf used when no other is given.
+const DefaultPath = "/etc/makepkg.conf"
+
+// envOverrides are the settings makepkg takes from the environment over the
+// configuration files.
+var envOverrides = []string{
+	"PKGDEST", "SRCDEST", "SRCPKGDEST", "LOGDEST", "BUILDDIR",
+	"PKGEXT", "SRCEXT", "GPGKEY", "PACKAGER", "CARCH",
+}
+
+// Config holds the effective makepkg build settings.
+type Config struct {
+	Files      []string `json:"files"`
+	PkgDest    string   `json:"PKGDEST"`
+	SrcDest    string   `json:"SRCDEST"`
+	SrcPkgDest string   `json:"SRCPKGDEST"`
+	LogDest    string   `json:"LOGDEST"`
+	BuildDir   string   `json:"BUILDDIR"`
+	PkgExt     string   `json:"PKGEXT"`
+	SrcExt     string   `json:"SRCEXT"`
+	CArch      string   `json:"CARCH"`
+	CHost      string   `json:"CHOST"`
+	Packager   string   `json:"PACKAGER"`
+	GPGKey     string   `json:"GPGKEY"`
+	MakeFlags  string   `json:"MAKEFLAGS"`
+	CFlags     string   `json:"CFLAGS"`
+	CXXFlags   string   `json:"CXXFLAGS"`
+	LDFlags    string   `json:"LDFLAGS"`
+	BuildEnv   []string `json:"BUILDENV"`
+	Options    []string `json:"OPTIONS"`
+}
+
+// Load reads the makepkg configuration like makepkg would: confPath (or
+// $MAKEPKG_CONF, or DefaultPath when both are empty), its .d drop-in
+// directory, the user configuration when confPath is DefaultPath, then the
+// environment overrides. environ is a list of KEY=value, as os.Environ.
+func Load(confPath string, environ []string) (*Config, error) {
+	env := envMap(environ)
+	sh := newShell(environ)
+	files := []string{}
+
+	if confPath == "" {
+		confPath = env["MAKEPKG_CONF"]
+	}
+
+	if confPath == "" {
+		confPath = DefaultPath
+	}
+
+	if err := runFile(sh, confPath); err != nil {
+		return nil, err
+	}
+
+	files = append(files, confPath)
+
+	dropIns, err := filepath.Glob(confPath + ".d/*.conf")
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(dropIns)
+
+	for _, path := range dropIns {
+		if err := runFile(sh, path); err != nil {
+			return nil, err
+		}
+
+		files = append(files, path)
+	}
+
+	// like makepkg, user overrides only apply on top of the system file
+	if confPath == DefaultPath {
+		if path := userConfPath(env); path != "" {
+			if err := runFile(sh, path); err != nil {
+				return nil, err
+			}
+
+			files = append(files, path)
+		}
+	}
+
+	for _, name := range envOverrides {
+		if value := env[name]; value != "" {
+			sh.vars[name] = value
+		}
+	}
+
+	return newConfig(sh, files), nil
+}
+
+func newConfig(sh *shell, files []string) *Config {
+	return &Config{
+		Files:      files,
+		PkgDest:    sh.vars["PKGDEST"],
+		SrcDest:    sh.vars["SRCDEST"],
+		SrcPkgDest: sh.vars["SRCPKGDEST"],
+		LogDest:    sh.vars["LOGDEST"],
+		BuildDir:   sh.vars["BUILDDIR"],
+		PkgExt:     sh.vars["PKGEXT"],
+		SrcExt:     sh.vars["SRCEXT"],
+		CArch:      sh.vars["CARCH"],
+		CHost:      sh.vars["CHOST"],
+		Packager:   sh.vars["PACKAGER"],
+		GPGKey:     sh.vars["GPGKEY"],
+		MakeFlags:  sh.vars["MAKEFLAGS"],
+		CFlags:     sh.vars["CFLAGS"],
+		CXXFlags:   sh.vars["CXXFLAGS"],
+		LDFlags:    sh.vars["LDFLAGS"],
+		BuildEnv:   sh.arrays["BUILDENV"],
+		Options:    sh.arrays["OPTIONS"],
+	}
+}
+
+func envMap(environ []string) map[string]string {
+	env := make(map[string]string, len(environ))
+
+	for _, kv := range environ {
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+
+	return env
+}
+
+// userConfPath returns the user makepkg.conf in use, or "" if there is none.
+func userConfPath(env map[string]string) string {
+	configHome := env["XDG_CONFIG_HOME"]
+	if configHome == "" && env["HOME"] != "" {
+		configHome = filepath.Join(env["HOME"], ".config")
+	}
+
+	candidates := []string{}
+	if configHome != "" {
+		candidates = append(candidates, filepath.Join(configHome, "pacman", "makepkg.conf"))
+	}
+
+	if env["HOME"] != "" {
+		candidates = append(candidates, filepath.Join(env["HOME"], ".makepkg.conf"))
+	}
+
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return ""
+}
+
+func runFile(sh *shell, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	sh.run(string(content))
+
+	return nil
+}
+
+func resolve(dir, startdir string) string {
+	if dir == "" {
+		return startdir
+	}
+
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+
+	return filepath.Join(startdir, dir)
+}
+
+// PackageDir returns where makepkg writes the packages built in startdir.
+func (c *Config) PackageDir(startdir string) string {
+	return resolve(c.PkgDest, startdir)
+}
+
+// SourceDir returns where makepkg downloads the sources of startdir.
+func (c *Config) SourceDir(startdir string) string {
+	return resolve(c.SrcDest, startdir)
+}
+
+// WorkDir returns the directory holding src/ and pkg/ while building pkgbase
+// from startdir.
+func (c *Config) WorkDir(startdir, pkgbase string) string {
+	if c.BuildDir == "" {
+		return startdir
+	}
+
+	return filepath.Join(resolve(c.BuildDir, startdir), pkgbase)
+}
+
+// Exact reports whether the settings used to name packages were fully
+// evaluated, so paths derived from them can be trusted.
+func (c *Config) Exact() bool {
+	for _, value := range []string{c.PkgDest, c.PkgExt, c.CArch} {
+		if strings.ContainsAny(value, "$`(") {
+			return false
+		}
+	}
+
+	return c.PkgExt != "" && c.CArch != ""
+}
+
+// PKGBUILD holds the PKGBUILD variables naming its packages.
+type PKGBUILD struct {
+	Pkgnames []string
+	Arch     []string
+	Version  string
+}
+
+// ReadPKGBUILD reads the PKGBUILD at path, with Version formatted as
+// [epoch:]pkgver-pkgrel. ok is false when the variables can't be determined
+// statically.
+func ReadPKGBUILD(path string) (info *PKGBUILD, ok bool, err error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	sh := newShell(nil)
+	sh.run(string(content))
+
+	pkgver, pkgrel, epoch := sh.vars["pkgver"], sh.vars["pkgrel"], sh.vars["epoch"]
+	if pkgver == "" || pkgrel == "" || sh.vars["pkgname"] == "" {
+		return nil, false, nil
+	}
+
+	info = &PKGBUILD{Pkgnames: sh.arrays["pkgname"], Arch: sh.arrays["arch"], Version: pkgver + "-" + pkgrel}
+	if info.Pkgnames == nil {
+		info.Pkgnames = []string{sh.vars["pkgname"]}
+	}
+
+	values := []string{pkgver, pkgrel, epoch}
+	values = append(values, info.Pkgnames...)
+	values = append(values, info.Arch...)
+
+	for _, value := range values {
+		if strings.ContainsAny(value, "$`( ") {
+			return nil, false, nil
+		}
+	}
+
+	if epoch != "" && epoch != "0" {
+		info.Version = epoch + ":" + info.Version
+	}
+
+	return info, true, nil
+}
+
+// OptionEnabled reports whether the makepkg option name is on, given the
+// options array of the PKGBUILD which takes precedence over OPTIONS.
+func (c *Config) OptionEnabled(name string, pkgOptions []string) bool {
+	for _, options := range [][]string{pkgOptions, c.Options} {
+		for i := len(options) - 1; i >= 0; i-- {
+			switch options[i] {
+			case name:
+				return true
+			case "!" + name:
+				return false
+			}
+		}
+	}
+
+	return false
+}
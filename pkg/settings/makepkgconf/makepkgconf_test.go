@@ -0,0 +1,217 @@
+package makepkgconf
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestShell(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		src    string
+		vars   map[string]string
+		arrays map[string][]string
+	}{
+		{
+			name: "scalars",
+			src: `# comment
+CARCH="x86_64"
+CHOST='x86_64-pc-linux-gnu' # trailing
+export PKGEXT=.pkg.tar.zst
+PACKAGER="John \"Doe\" <john@example.com>"`,
+			vars: map[string]string{
+				"CARCH":    "x86_64",
+				"CHOST":    "x86_64-pc-linux-gnu",
+				"PKGEXT":   ".pkg.tar.zst",
+				"PACKAGER": `John "Doe" <john@example.com>`,
+			},
+		},
+		{
+			name: "expansion",
+			src: `CFLAGS="-O2 -pipe"
+CXXFLAGS="$CFLAGS -Wp,-D_GLIBCXX_ASSERTIONS"
+PKGDEST=${HOME}/packages
+SRCDEST=${NOPE:-/tmp/src}
+MAKEFLAGS="-j$(nproc)"
+LOGDEST=$(date)
+BUILDDIR=${HOME%/*}`,
+			vars: map[string]string{
+				"CXXFLAGS":  "-O2 -pipe -Wp,-D_GLIBCXX_ASSERTIONS",
+				"PKGDEST":   "/home/user/packages",
+				"SRCDEST":   "/tmp/src",
+				"MAKEFLAGS": "-j" + strconv.Itoa(runtime.NumCPU()),
+				"LOGDEST":   "$(date)",
+				"BUILDDIR":  "${HOME%/*}",
+			},
+		},
+		{
+			name: "arrays",
+			src: `BUILDENV=(!distcc color !ccache check !sign)
+OPTIONS=(strip docs
+         !libtool # comment
+         'emptydirs')
+OPTIONS+=(lto)
+CFLAGS=foo
+CFLAGS+=" bar"`,
+			vars: map[string]string{"CFLAGS": "foo bar"},
+			arrays: map[string][]string{
+				"BUILDENV": {"!distcc", "color", "!ccache", "check", "!sign"},
+				"OPTIONS":  {"strip", "docs", "!libtool", "emptydirs", "lto"},
+			},
+		},
+		{
+			name: "functions",
+			src: `pkgver=1.0
+pkgver() {
+  pkgver=2.0
+}
+build() { pkgver=3.0; }
+pkgrel=1`,
+			vars: map[string]string{"pkgver": "1.0", "pkgrel": "1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sh := newShell([]string{"HOME=/home/user"})
+			sh.run(tc.src)
+
+			for name, value := range tc.vars {
+				assert.Equal(t, value, sh.vars[name], name)
+			}
+
+			for name, values := range tc.arrays {
+				assert.Equal(t, values, sh.arrays[name], name)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	home := filepath.Join(dir, "home")
+	conf := filepath.Join(dir, "makepkg.conf")
+
+	writeFile(t, conf, "CARCH=x86_64\nPKGEXT='.pkg.tar.zst'\nPKGDEST=/var/pkg\nOPTIONS=(strip)\n")
+	writeFile(t, conf+".d/rust.conf", "OPTIONS+=(lto)\n")
+	writeFile(t, filepath.Join(home, ".config", "pacman", "makepkg.conf"), "PKGDEST=~/pkg\n")
+
+	environ := []string{"HOME=" + home, "SRCDEST=/var/src"}
+
+	config, err := Load(conf, environ)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{conf, conf + ".d/rust.conf"}, config.Files)
+	assert.Equal(t, "/var/pkg", config.PkgDest)
+	assert.Equal(t, "/var/src", config.SrcDest)
+	assert.Equal(t, []string{"strip", "lto"}, config.Options)
+	assert.True(t, config.Exact())
+
+	config, err = Load("", append(environ, "MAKEPKG_CONF="+conf, "PKGDEST=/env/pkg"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/env/pkg", config.PkgDest)
+
+	_, err = Load(filepath.Join(dir, "missing.conf"), environ)
+	assert.Error(t, err)
+}
+
+func TestUserConfPath(t *testing.T) {
+	t.Parallel()
+
+	home := t.TempDir()
+
+	assert.Equal(t, "", userConfPath(map[string]string{"HOME": home}))
+
+	legacy := filepath.Join(home, ".makepkg.conf")
+	writeFile(t, legacy, "")
+	assert.Equal(t, legacy, userConfPath(map[string]string{"HOME": home}))
+
+	xdg := filepath.Join(home, "xdg", "pacman", "makepkg.conf")
+	writeFile(t, xdg, "")
+	assert.Equal(t, xdg, userConfPath(map[string]string{"HOME": home, "XDG_CONFIG_HOME": filepath.Join(home, "xdg")}))
+}
+
+func TestConfigDirs(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{PkgDest: "pkgs", SrcDest: "/srv/src", BuildDir: "/tmp/build"}
+
+	assert.Equal(t, "/aur/foo/pkgs", config.PackageDir("/aur/foo"))
+	assert.Equal(t, "/srv/src", config.SourceDir("/aur/foo"))
+	assert.Equal(t, "/tmp/build/foo", config.WorkDir("/aur/foo", "foo"))
+	assert.Equal(t, "/aur/foo", (&Config{}).WorkDir("/aur/foo", "foo"))
+	assert.False(t, config.Exact())
+}
+
+func TestOptionEnabled(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{Options: []string{"strip", "!debug", "lto"}}
+
+	assert.True(t, config.OptionEnabled("strip", nil))
+	assert.False(t, config.OptionEnabled("debug", nil))
+	assert.True(t, config.OptionEnabled("debug", []string{"!debug", "debug"}))
+	assert.False(t, config.OptionEnabled("lto", []string{"!lto"}))
+	assert.False(t, config.OptionEnabled("docs", nil))
+}
+
+func TestReadPKGBUILD(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		content string
+		want    *PKGBUILD
+	}{
+		{
+			name:    "plain",
+			content: "pkgname=foo\npkgver=1.2.3\npkgrel=2\narch=(any)\n",
+			want:    &PKGBUILD{Pkgnames: []string{"foo"}, Arch: []string{"any"}, Version: "1.2.3-2"},
+		},
+		{
+			name:    "split",
+			content: "pkgbase=foo\npkgname=(foo foo-docs)\nepoch=1\npkgver=1.2.3\npkgrel=2\narch=('x86_64')\n",
+			want:    &PKGBUILD{Pkgnames: []string{"foo", "foo-docs"}, Arch: []string{"x86_64"}, Version: "1:1.2.3-2"},
+		},
+		{
+			name:    "variable",
+			content: "pkgname=foo\n_ver=1.2\npkgver=${_ver}\npkgrel=1\n",
+			want:    &PKGBUILD{Pkgnames: []string{"foo"}, Version: "1.2-1"},
+		},
+		{name: "substitution", content: "pkgname=foo\n_ver=1-2\npkgver=${_ver/-/.}\npkgrel=1\n"},
+		{name: "missing", content: "pkgname=foo\n"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "PKGBUILD")
+			writeFile(t, path, tc.content)
+
+			info, ok, err := ReadPKGBUILD(path)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want != nil, ok)
+			assert.Equal(t, tc.want, info)
+		})
+	}
+}
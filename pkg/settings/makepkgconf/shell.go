@@ -0,0 +1,324 @@
+package makepkgconf
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// shell evaluates the subset of bash used by makepkg.conf and PKGBUILD
+// variables: scalar and array assignments, optionally exported or appended
+// to, with quoting and parameter expansion. Other statements are skipped, as
+// are command substitutions except $(nproc).
+type shell struct {
+	vars   map[string]string
+	arrays map[string][]string
+
+	src string
+	pos int
+}
+
+func newShell(environ []string) *shell {
+	sh := &shell{vars: make(map[string]string), arrays: make(map[string][]string)}
+
+	for _, kv := range environ {
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			sh.vars[kv[:i]] = kv[i+1:]
+		}
+	}
+
+	return sh
+}
+
+func (sh *shell) peek() byte {
+	if sh.pos >= len(sh.src) {
+		return 0
+	}
+
+	return sh.src[sh.pos]
+}
+
+func (sh *shell) eof() bool {
+	return sh.pos >= len(sh.src)
+}
+
+func (sh *shell) skipLine() {
+	for !sh.eof() && sh.src[sh.pos] != '\n' {
+		sh.pos++
+	}
+}
+
+// skipFunction skips a function definition, assuming its body ends with a
+// line starting with a closing brace as is the convention in PKGBUILDs.
+func (sh *shell) skipFunction() {
+	start := sh.pos
+	sh.skipLine()
+
+	if strings.Contains(sh.src[start:sh.pos], "}") {
+		return
+	}
+
+	for !sh.eof() {
+		sh.pos++
+
+		if sh.peek() == '}' {
+			sh.skipLine()
+			return
+		}
+
+		sh.skipLine()
+	}
+}
+
+func (sh *shell) skipBlanks(newlines bool) {
+	for !sh.eof() {
+		switch c := sh.peek(); {
+		case c == ' ' || c == '\t' || (newlines && (c == '\n' || c == '\r')):
+			sh.pos++
+		case c == '\\' && sh.pos+1 < len(sh.src) && sh.src[sh.pos+1] == '\n':
+			sh.pos += 2
+		case c == '#' && newlines:
+			sh.skipLine()
+		default:
+			return
+		}
+	}
+}
+
+func isNameChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+func isName(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isNameChar(s[i], i == 0) {
+			return false
+		}
+	}
+
+	return s != ""
+}
+
+func (sh *shell) readName() string {
+	start := sh.pos
+	for !sh.eof() && isNameChar(sh.peek(), sh.pos == start) {
+		sh.pos++
+	}
+
+	return sh.src[start:sh.pos]
+}
+
+// run evaluates src, adding its assignments to the state of sh.
+func (sh *shell) run(src string) {
+	sh.src, sh.pos = src, 0
+
+	for {
+		sh.skipBlanks(true)
+
+		if sh.eof() {
+			return
+		}
+
+		sh.statement()
+	}
+}
+
+func (sh *shell) statement() {
+	name := sh.readName()
+	if name == "export" && (sh.peek() == ' ' || sh.peek() == '\t') {
+		sh.skipBlanks(false)
+		name = sh.readName()
+	}
+
+	appendValue := false
+	if strings.HasPrefix(sh.src[sh.pos:], "+=") {
+		appendValue = true
+		sh.pos++
+	}
+
+	if name != "" && strings.HasPrefix(strings.TrimLeft(sh.src[sh.pos:], " \t"), "()") {
+		sh.skipFunction()
+		return
+	}
+
+	if name == "" || sh.peek() != '=' {
+		sh.skipLine()
+		return
+	}
+
+	sh.pos++
+
+	if sh.peek() == '(' {
+		sh.pos++
+		values := sh.readArray()
+
+		if !appendValue {
+			sh.arrays[name] = nil
+		}
+
+		sh.arrays[name] = append(sh.arrays[name], values...)
+		sh.vars[name] = strings.Join(sh.arrays[name], " ")
+	} else {
+		value, _ := sh.readWord()
+
+		if appendValue {
+			value = sh.vars[name] + value
+		}
+
+		sh.vars[name] = value
+		delete(sh.arrays, name)
+	}
+
+	// ignore whatever follows on the line, like a comment
+	sh.skipLine()
+}
+
+func (sh *shell) readArray() []string {
+	values := []string{}
+
+	for {
+		sh.skipBlanks(true)
+
+		if sh.eof() {
+			return values
+		}
+
+		if sh.peek() == ')' {
+			sh.pos++
+			return values
+		}
+
+		if word, ok := sh.readWord(); ok {
+			values = append(values, word)
+		} else {
+			// not a word, avoid looping forever on it
+			sh.pos++
+		}
+	}
+}
+
+// readWord reads and expands one word, returning false when there is none.
+func (sh *shell) readWord() (string, bool) {
+	var buf strings.Builder
+
+	start := sh.pos
+
+	for !sh.eof() {
+		switch c := sh.peek(); c {
+		case ' ', '\t', '\n', '\r', ';', ')', '(':
+			return buf.String(), sh.pos > start
+		case '\'':
+			sh.pos++
+			end := strings.IndexByte(sh.src[sh.pos:], '\'')
+
+			if end < 0 {
+				end = len(sh.src) - sh.pos
+			}
+
+			buf.WriteString(sh.src[sh.pos : sh.pos+end])
+			sh.pos += end + 1
+		case '"':
+			sh.pos++
+			sh.readDoubleQuoted(&buf)
+		case '\\':
+			sh.pos++
+
+			if !sh.eof() {
+				if sh.peek() != '\n' {
+					buf.WriteByte(sh.peek())
+				}
+
+				sh.pos++
+			}
+		case '$':
+			buf.WriteString(sh.expand())
+		default:
+			buf.WriteByte(c)
+			sh.pos++
+		}
+	}
+
+	return buf.String(), sh.pos > start
+}
+
+func (sh *shell) readDoubleQuoted(buf *strings.Builder) {
+	for !sh.eof() {
+		switch c := sh.peek(); c {
+		case '"':
+			sh.pos++
+			return
+		case '\\':
+			sh.pos++
+
+			if sh.eof() {
+				return
+			}
+
+			if next := sh.peek(); next != '"' && next != '\\' && next != '$' && next != '`' {
+				buf.WriteByte('\\')
+			}
+
+			if sh.peek() != '\n' {
+				buf.WriteByte(sh.peek())
+			}
+
+			sh.pos++
+		case '$':
+			buf.WriteString(sh.expand())
+		default:
+			buf.WriteByte(c)
+			sh.pos++
+		}
+	}
+}
+
+// expand evaluates the expansion starting with $ at the current position.
+func (sh *shell) expand() string {
+	sh.pos++
+
+	switch sh.peek() {
+	case '{':
+		end := strings.IndexByte(sh.src[sh.pos:], '}')
+		if end < 0 {
+			return "$"
+		}
+
+		expr := sh.src[sh.pos+1 : sh.pos+end]
+		sh.pos += end + 1
+
+		if i := strings.Index(expr, ":-"); i > 0 {
+			if value := sh.vars[expr[:i]]; value != "" {
+				return value
+			}
+
+			return expr[i+2:]
+		}
+
+		if isName(expr) {
+			return sh.vars[expr]
+		}
+
+		// keep what can't be evaluated so callers can tell
+		return "${" + expr + "}"
+	case '(':
+		end := strings.IndexByte(sh.src[sh.pos:], ')')
+		if end < 0 {
+			return "$"
+		}
+
+		cmd := sh.src[sh.pos : sh.pos+end+1]
+		sh.pos += end + 1
+
+		if strings.TrimSpace(cmd[1:len(cmd)-1]) == "nproc" {
+			return strconv.Itoa(runtime.NumCPU())
+		}
+
+		return "$" + cmd
+	default:
+		if name := sh.readName(); name != "" {
+			return sh.vars[name]
+		}
+
+		return "$"
+	}
+}
@@ -152,7 +152,7 @@ func (a *Arguments) NeedRoot(mode TargetMode) bool {
 		}
 
 		return true
-	case "U", "upgrade":
+	case "U", "upgrade", "B", "build":
 		return true
 	default:
 		return false
@@ -315,6 +315,7 @@ func isArg(arg string) bool {
 	case "Y", "yay":
 	case "P", "show":
 	case "G", "getpkgbuild":
+	case "B", "build":
 	case "b", "dbpath":
 	case "r", "root":
 	case "v", "verbose":
@@ -379,9 +380,19 @@ func isArg(arg string) bool {
 	case "nodevel":
 	case "timeupdate":
 	case "notimeupdate":
+	case "checknews":
+	case "nochecknews":
+	case "newsstop":
+	case "nonewsstop":
 	case "topdown":
 	case "bottomup":
 	case "completioninterval":
+	case "aurmetadata":
+	case "noaurmetadata":
+	case "metadatainterval":
+	case "offline":
+	case "aurcachettl":
+	case "refresh-aur":
 	case "sortby":
 	case "searchby":
 	case "redownload":
@@ -405,6 +416,7 @@ func isArg(arg string) bool {
 	case "mflags":
 	case "gitflags":
 	case "builddir":
+	case "patchdir":
 	case "editor":
 	case "editorflags":
 	case "makepkg":
@@ -416,12 +428,18 @@ func isArg(arg string) bool {
 	case "sudo":
 	case "sudoflags":
 	case "requestsplitn":
+	case "gitretries":
+	case "gitretrybackoff":
 	case "sudoloop":
 	case "nosudoloop":
 	case "provides":
 	case "noprovides":
 	case "pgpfetch":
 	case "nopgpfetch":
+	case "pgpkeyring":
+	case "nopgpkeyring":
+	case "pgpwkd":
+	case "nopgpwkd":
 	case "upgrademenu":
 	case "noupgrademenu":
 	case "cleanmenu":
@@ -439,11 +457,30 @@ func isArg(arg string) bool {
 	case "removemake":
 	case "noremovemake":
 	case "askremovemake":
+	case "recovercheckout":
+	case "diskcheck":
+	case "diskmultiplier":
+	case "sourcecachedir":
+	case "sourcecachesize":
+	case "downloadjobs":
+	case "downloadprogress":
+	case "pgphome":
+	case "pgpkeyservers":
 	case "complete":
 	case "stats":
 	case "news":
 	case "gendb":
+	case "publish":
+	case "dry-run":
+	case "keys":
+	case "delete":
 	case "currentconfig":
+	case "aur-version":
+	case "aur-commit":
+	case "format":
+	case "comments":
+	case "required-by":
+	case "json":
 	default:
 		return false
 	}
@@ -465,6 +502,7 @@ func isOp(op string) bool {
 	case "Y", "yay":
 	case "P", "show":
 	case "G", "getpkgbuild":
+	case "B", "build":
 	default:
 		return false
 	}
@@ -518,6 +556,7 @@ func hasParam(arg string) bool {
 	case "gpgflags":
 	case "gitflags":
 	case "builddir":
+	case "patchdir":
 	case "editor":
 	case "editorflags":
 	case "makepkg":
@@ -528,13 +567,29 @@ func hasParam(arg string) bool {
 	case "sudo":
 	case "sudoflags":
 	case "requestsplitn":
+	case "gitretries":
+	case "gitretrybackoff":
 	case "answerclean":
 	case "answerdiff":
 	case "answeredit":
 	case "answerupgrade":
+	case "recovercheckout":
+	case "diskcheck":
+	case "diskmultiplier":
+	case "sourcecachedir":
+	case "sourcecachesize":
+	case "downloadjobs":
+	case "downloadprogress":
+	case "pgphome":
+	case "pgpkeyservers":
 	case "completioninterval":
+	case "metadatainterval":
+	case "aurcachettl":
 	case "sortby":
 	case "searchby":
+	case "aur-version":
+	case "aur-commit":
+	case "format":
 	default:
 		return false
 	}
@@ -7,8 +7,12 @@ import (
 
 	"github.com/Jguer/aur"
 
+	"github.com/Jguer/yay/v11/pkg/pin"
 	"github.com/Jguer/yay/v11/pkg/settings/exe"
+	"github.com/Jguer/yay/v11/pkg/settings/makepkgconf"
 	"github.com/Jguer/yay/v11/pkg/settings/parser"
+	"github.com/Jguer/yay/v11/pkg/sources"
+	"github.com/Jguer/yay/v11/pkg/stringset"
 	"github.com/Jguer/yay/v11/pkg/vcs"
 )
 
@@ -16,10 +20,26 @@ type Runtime struct {
 	Mode           parser.TargetMode
 	SaveConfig     bool
 	CompletionPath string
+	NewsReadPath   string
+	MetadataPath   string
+	AURCachePath   string
+	SourcesPath    string
+	PGPKeysPath    string
 	ConfigPath     string
 	PacmanConf     *pacmanconf.Config
+	MakepkgConf    *makepkgconf.Config
 	VCSStore       *vcs.InfoStore
+	PinStore       *pin.Store
+	OriginStore    *pin.Store
 	CmdBuilder     exe.ICmdBuilder
 	HTTPClient     *http.Client
 	AURClient      *aur.Client
+	QueryClient    aur.ClientInterface
+	Sources        *sources.Client
+	SourceBases    *sources.Resolved
+	// LocalBuildDirs maps the package bases given to yay -B to their directory.
+	LocalBuildDirs map[string]string
+	// KeptEdits holds the package bases whose local edits were reapplied after
+	// recovering their checkout, they are not reset before building.
+	KeptEdits stringset.StringSet
 }
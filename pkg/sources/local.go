@@ -0,0 +1,94 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/Jguer/aur"
+	gosrc "github.com/Morganamilo/go-srcinfo"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/settings/exe"
+)
+
+// ReadSrcinfo parses the .SRCINFO of the PKGBUILD in dir, generating it with
+// makepkg --printsrcinfo if it is missing.
+func ReadSrcinfo(ctx context.Context, cmdBuilder exe.ICmdBuilder, dir string) (*gosrc.Srcinfo, error) {
+	path := filepath.Join(dir, ".SRCINFO")
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, errStat := os.Stat(filepath.Join(dir, "PKGBUILD")); errStat != nil {
+			return nil, errors.New(gotext.Get("no PKGBUILD found in %s", dir))
+		}
+
+		stdout, stderr, errMake := cmdBuilder.Capture(cmdBuilder.BuildMakepkgCmd(ctx, dir, "--printsrcinfo"))
+		if errMake != nil {
+			return nil, errors.New(gotext.Get("unable to generate .SRCINFO for %s: %s", dir, stderr))
+		}
+
+		if errWrite := os.WriteFile(path, []byte(stdout+"\n"), 0o644); errWrite != nil {
+			return nil, errWrite
+		}
+	}
+
+	srcinfo, err := gosrc.ParseFile(path)
+	if err != nil {
+		return nil, errors.New(gotext.Get("failed to parse %s: %s", path, err))
+	}
+
+	return srcinfo, nil
+}
+
+// Local answers queries for the packages of local PKGBUILD directories and
+// forwards everything else to Fallback.
+type Local struct {
+	Fallback aur.ClientInterface
+
+	pkgs map[string]aur.Pkg
+}
+
+func NewLocal(fallback aur.ClientInterface, srcinfos []*gosrc.Srcinfo) *Local {
+	local := &Local{Fallback: fallback, pkgs: make(map[string]aur.Pkg)}
+
+	for _, srcinfo := range srcinfos {
+		for _, pkg := range ToAURPkgs(srcinfo) {
+			local.pkgs[pkg.Name] = pkg
+		}
+	}
+
+	return local
+}
+
+// Info returns the local packages named in pkgs and looks the others up in
+// Fallback.
+func (l *Local) Info(ctx context.Context, pkgs []string, reqEditors ...aur.RequestEditorFn) ([]aur.Pkg, error) {
+	results := make([]aur.Pkg, 0, len(pkgs))
+	toQuery := make([]string, 0, len(pkgs))
+
+	for _, name := range pkgs {
+		if pkg, ok := l.pkgs[name]; ok {
+			results = append(results, pkg)
+
+			continue
+		}
+
+		toQuery = append(toQuery, name)
+	}
+
+	if len(toQuery) == 0 {
+		return results, nil
+	}
+
+	aurPkgs, err := l.Fallback.Info(ctx, toQuery, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(results, aurPkgs...), nil
+}
+
+func (l *Local) Search(ctx context.Context, query string, by aur.By, reqEditors ...aur.RequestEditorFn) ([]aur.Pkg, error) {
+	return l.Fallback.Search(ctx, query, by, reqEditors...)
+}
@@ -0,0 +1,437 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Jguer/aur"
+	gosrc "github.com/Morganamilo/go-srcinfo"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/download"
+	"github.com/Jguer/yay/v11/pkg/metadata"
+	"github.com/Jguer/yay/v11/pkg/settings/exe"
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+// Source is a git repository or local directory holding one subdirectory with
+// a PKGBUILD and .SRCINFO per package base.
+//
+// Priority is relative to the AUR, whose priority is zero: packages of sources
+// with a positive priority replace the AUR ones, the others are only used for
+// packages missing from the AUR. Between sources the highest priority wins.
+type Source struct {
+	Name     string
+	URL      string
+	Priority int
+}
+
+// IsLocal reports whether the source is a local directory rather than a git
+// repository.
+func (s *Source) IsLocal() bool {
+	return filepath.IsAbs(s.URL)
+}
+
+type base struct {
+	source *Source
+	dir    string
+}
+
+type entry struct {
+	pkg    aur.Pkg
+	source *Source
+}
+
+// Resolved records the source each package base was last returned from by a
+// Client, so it is downloaded from where its dependencies were resolved.
+type Resolved struct {
+	mux   sync.Mutex
+	bases map[string]string
+}
+
+func NewResolved() *Resolved {
+	return &Resolved{bases: make(map[string]string)}
+}
+
+// set remembers which source, if any, the package base was last returned
+// from.
+func (r *Resolved) set(pkgbase string, source *Source) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if source == nil {
+		delete(r.bases, pkgbase)
+		return
+	}
+
+	r.bases[pkgbase] = source.Name
+}
+
+// Repository returns the name of the source pkgbase was last returned from by
+// Info or Search. ok is false for AUR packages.
+func (r *Resolved) Repository(pkgbase string) (name string, ok bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	name, ok = r.bases[pkgbase]
+
+	return name, ok
+}
+
+// Client answers AUR search and info queries with the packages of the
+// configured sources merged with the results of Fallback. Git sources are
+// cloned or pulled in CacheDir on the first query. The source of every
+// returned package base is recorded in Resolved.
+type Client struct {
+	Fallback   aur.ClientInterface
+	CmdBuilder exe.GitCmdBuilder
+	CacheDir   string
+	Sources    []Source
+	Resolved   *Resolved
+
+	once   sync.Once
+	pkgs   []*entry
+	byName map[string]*entry
+	bases  map[string]*base
+}
+
+// NewClient creates a Client for sources, ordered by decreasing priority.
+// Sources without a name or URL, or named like the AUR, are skipped.
+func NewClient(fallback aur.ClientInterface, cmdBuilder exe.GitCmdBuilder, cacheDir string,
+	sources []Source, resolved *Resolved) *Client {
+	valid := make([]Source, 0, len(sources))
+
+	for _, source := range sources {
+		if source.Name == "" || source.URL == "" || source.Name == "aur" || strings.Contains(source.Name, "/") {
+			text.Warnln(gotext.Get("invalid PKGBUILD source %q, skipping", source.Name))
+			continue
+		}
+
+		valid = append(valid, source)
+	}
+
+	sort.SliceStable(valid, func(i, j int) bool {
+		return valid[i].Priority > valid[j].Priority
+	})
+
+	return &Client{
+		Fallback:   fallback,
+		CmdBuilder: cmdBuilder,
+		CacheDir:   cacheDir,
+		Sources:    valid,
+		Resolved:   resolved,
+	}
+}
+
+// sync returns the directory holding the package bases of source, cloning or
+// pulling it first if it is a git repository.
+func (c *Client) sync(ctx context.Context, source *Source) (string, error) {
+	if source.IsLocal() {
+		return source.URL, nil
+	}
+
+	dest := filepath.Join(c.CacheDir, source.Name)
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return "", err
+	}
+
+	if _, err := download.PKGBUILDRepo(ctx, c.CmdBuilder, source.URL, "checkout", dest, false); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dest, "checkout"), nil
+}
+
+// read indexes the package bases found in dir, skipping the ones already
+// provided by a source of higher priority.
+func (c *Client) read(source *Source, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, dirEntry := range entries {
+		if !dirEntry.IsDir() || strings.HasPrefix(dirEntry.Name(), ".") {
+			continue
+		}
+
+		baseDir := filepath.Join(dir, dirEntry.Name())
+		srcinfoPath := filepath.Join(baseDir, ".SRCINFO")
+
+		if _, err := os.Stat(srcinfoPath); err != nil {
+			continue
+		}
+
+		srcinfo, err := gosrc.ParseFile(srcinfoPath)
+		if err != nil {
+			text.Warnln(gotext.Get("%s: unable to parse %s: %s", source.Name, dirEntry.Name(), err))
+			continue
+		}
+
+		if _, ok := c.bases[srcinfo.Pkgbase]; ok {
+			continue
+		}
+
+		c.bases[srcinfo.Pkgbase] = &base{source: source, dir: baseDir}
+
+		for _, pkg := range ToAURPkgs(srcinfo) {
+			if _, ok := c.byName[pkg.Name]; ok {
+				continue
+			}
+
+			e := &entry{pkg: pkg, source: source}
+			c.pkgs = append(c.pkgs, e)
+			c.byName[pkg.Name] = e
+		}
+	}
+
+	return nil
+}
+
+// load syncs and indexes every source, only once per Client. Sources that
+// can not be read are skipped with a warning.
+func (c *Client) load(ctx context.Context) {
+	c.once.Do(func() {
+		c.byName = make(map[string]*entry)
+		c.bases = make(map[string]*base)
+
+		for i := range c.Sources {
+			source := &c.Sources[i]
+
+			dir, err := c.sync(ctx, source)
+			if err == nil {
+				err = c.read(source, dir)
+			}
+
+			if err != nil {
+				text.Warnln(gotext.Get("unable to read PKGBUILD source %s: %s", source.Name, err))
+			}
+		}
+	})
+}
+
+// Info returns the packages named pkgs.
+func (c *Client) Info(ctx context.Context, pkgs []string, reqEditors ...aur.RequestEditorFn) ([]aur.Pkg, error) {
+	c.load(ctx)
+
+	results := make([]aur.Pkg, 0, len(pkgs))
+	toQuery := make([]string, 0, len(pkgs))
+
+	for _, name := range pkgs {
+		if e, ok := c.byName[name]; ok && e.source.Priority > 0 {
+			c.Resolved.set(e.pkg.PackageBase, e.source)
+			results = append(results, e.pkg)
+
+			continue
+		}
+
+		toQuery = append(toQuery, name)
+	}
+
+	if len(toQuery) == 0 {
+		return results, nil
+	}
+
+	aurPkgs, err := c.Fallback.Info(ctx, toQuery, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(aurPkgs))
+
+	for i := range aurPkgs {
+		found[aurPkgs[i].Name] = true
+		c.Resolved.set(aurPkgs[i].PackageBase, nil)
+	}
+
+	results = append(results, aurPkgs...)
+
+	for _, name := range toQuery {
+		if e, ok := c.byName[name]; ok && !found[name] {
+			c.Resolved.set(e.pkg.PackageBase, e.source)
+			results = append(results, e.pkg)
+		}
+	}
+
+	return results, nil
+}
+
+// Search returns the packages of Fallback and of the sources matching query.
+func (c *Client) Search(ctx context.Context, query string, by aur.By, reqEditors ...aur.RequestEditorFn) ([]aur.Pkg, error) {
+	c.load(ctx)
+
+	aurPkgs, err := c.Fallback.Search(ctx, query, by, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]aur.Pkg, 0, len(aurPkgs))
+	found := make(map[string]bool, len(aurPkgs))
+
+	for i := range aurPkgs {
+		if e, ok := c.byName[aurPkgs[i].Name]; ok && e.source.Priority > 0 {
+			continue
+		}
+
+		found[aurPkgs[i].Name] = true
+		results = append(results, aurPkgs[i])
+	}
+
+	query = strings.ToLower(query)
+
+	for _, e := range c.pkgs {
+		if found[e.pkg.Name] || !metadata.Matches(&e.pkg, query, by) {
+			continue
+		}
+
+		c.Resolved.set(e.pkg.PackageBase, e.source)
+		results = append(results, e.pkg)
+	}
+
+	return results, nil
+}
+
+// PKGBUILD returns the PKGBUILD of pkgbase.
+func (c *Client) PKGBUILD(ctx context.Context, pkgbase string) ([]byte, error) {
+	c.load(ctx)
+
+	b, ok := c.bases[pkgbase]
+	if !ok {
+		return nil, errors.New(gotext.Get("%s is not part of a PKGBUILD source", pkgbase))
+	}
+
+	return os.ReadFile(filepath.Join(b.dir, "PKGBUILD"))
+}
+
+// Download copies pkgbase to a git repository in CacheDir and clones or pulls
+// it to dest/pkgbase, so that it can be reviewed and built like AUR packages.
+// It returns whether a new clone was made.
+func (c *Client) Download(ctx context.Context, pkgbase, dest string, force bool) (bool, error) {
+	c.load(ctx)
+
+	b, ok := c.bases[pkgbase]
+	if !ok {
+		return false, errors.New(gotext.Get("%s is not part of a PKGBUILD source", pkgbase))
+	}
+
+	repo := filepath.Join(c.CacheDir, b.source.Name, "pkgbuilds", pkgbase)
+
+	created, err := c.export(ctx, b, pkgbase, repo)
+	if err != nil {
+		return false, err
+	}
+
+	// the existing clone may come from the AUR or from a previous export
+	if !created && !force {
+		stdout, _, errURL := c.CmdBuilder.Capture(
+			c.CmdBuilder.BuildGitCmd(ctx, filepath.Join(dest, pkgbase), "config", "--get", "remote.origin.url"))
+		force = errURL == nil && strings.TrimSpace(stdout) != repo
+	}
+
+	return download.PKGBUILDRepo(ctx, c.CmdBuilder, repo, pkgbase, dest, created || force)
+}
+
+// export updates the git repository repo with the files of b, committing any
+// change. It returns whether the repository was created.
+func (c *Client) export(ctx context.Context, b *base, pkgbase, repo string) (bool, error) {
+	created := false
+
+	if _, err := os.Stat(filepath.Join(repo, ".git")); os.IsNotExist(err) {
+		if err = os.MkdirAll(repo, 0o755); err != nil {
+			return false, err
+		}
+
+		if err = c.git(ctx, repo, "init", "--quiet"); err != nil {
+			return false, err
+		}
+
+		created = true
+	}
+
+	if err := replaceTree(b.dir, repo); err != nil {
+		return false, err
+	}
+
+	if err := c.git(ctx, repo, "add", "--all"); err != nil {
+		return false, err
+	}
+
+	stdout, stderr, err := c.CmdBuilder.Capture(c.CmdBuilder.BuildGitCmd(ctx, repo, "status", "--porcelain"))
+	if err != nil {
+		return false, errors.New(stderr)
+	}
+
+	if strings.TrimSpace(stdout) == "" {
+		return created, nil
+	}
+
+	cmd := c.CmdBuilder.BuildGitCmd(ctx, repo, "commit", "--quiet", "--no-gpg-sign",
+		"--message", gotext.Get("Update %s from %s", pkgbase, b.source.Name))
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+
+	cmd.Env = append(cmd.Env, "GIT_AUTHOR_NAME=yay", "GIT_AUTHOR_EMAIL=yay@localhost",
+		"GIT_COMMITTER_NAME=yay", "GIT_COMMITTER_EMAIL=yay@localhost")
+
+	if _, stderr, err = c.CmdBuilder.Capture(cmd); err != nil {
+		return false, errors.New(gotext.Get("error committing %s: %s", pkgbase, stderr))
+	}
+
+	return created, nil
+}
+
+func (c *Client) git(ctx context.Context, dir string, args ...string) error {
+	_, stderr, err := c.CmdBuilder.Capture(c.CmdBuilder.BuildGitCmd(ctx, dir, args...))
+	if err != nil {
+		return errors.New(gotext.Get("error running git %s in %s: %s", args[0], dir, stderr))
+	}
+
+	return nil
+}
+
+// ToAURPkgs converts the split packages of srcinfo to the AUR RPC format.
+func ToAURPkgs(srcinfo *gosrc.Srcinfo) []aur.Pkg {
+	pkgs := make([]aur.Pkg, 0, len(srcinfo.Packages))
+
+	for _, pkg := range srcinfo.SplitPackages() {
+		pkgs = append(pkgs, aur.Pkg{
+			Name:         pkg.Pkgname,
+			PackageBase:  srcinfo.Pkgbase,
+			Version:      srcinfo.Version(),
+			Description:  pkg.Pkgdesc,
+			URL:          pkg.URL,
+			License:      pkg.License,
+			Groups:       pkg.Groups,
+			Depends:      archValues(pkg.Depends),
+			MakeDepends:  archValues(srcinfo.MakeDepends),
+			CheckDepends: archValues(srcinfo.CheckDepends),
+			OptDepends:   archValues(pkg.OptDepends),
+			Provides:     archValues(pkg.Provides),
+			Conflicts:    archValues(pkg.Conflicts),
+			Replaces:     archValues(pkg.Replaces),
+		})
+	}
+
+	return pkgs
+}
+
+// archValues returns the values of every architecture, like the AUR RPC.
+func archValues(values []gosrc.ArchString) []string {
+	result := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+
+	for _, value := range values {
+		if !seen[value.Value] {
+			seen[value.Value] = true
+			result = append(result, value.Value)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,202 @@
+package sources
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jguer/aur"
+	gosrc "github.com/Morganamilo/go-srcinfo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v11/pkg/settings/exe"
+)
+
+// localGitBuilder runs git as the current user, without de-elevation.
+type localGitBuilder struct {
+	exe.OSRunner
+}
+
+func (g *localGitBuilder) BuildGitCmd(ctx context.Context, dir string, extraArgs ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, "git", append([]string{"-C", dir}, extraArgs...)...)
+}
+
+type mockFallback struct {
+	pkgs []aur.Pkg
+}
+
+func (m *mockFallback) Search(ctx context.Context, query string, by aur.By,
+	reqEditors ...aur.RequestEditorFn) ([]aur.Pkg, error) {
+	return m.pkgs, nil
+}
+
+func (m *mockFallback) Info(ctx context.Context, pkgs []string, reqEditors ...aur.RequestEditorFn) ([]aur.Pkg, error) {
+	results := make([]aur.Pkg, 0)
+
+	for _, pkg := range m.pkgs {
+		for _, name := range pkgs {
+			if pkg.Name == name {
+				results = append(results, pkg)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// writeBase writes a package base with a single package to dir.
+func writeBase(t *testing.T, dir, pkgbase, pkgver, pkgdesc string) {
+	t.Helper()
+
+	baseDir := filepath.Join(dir, pkgbase)
+	assert.NoError(t, os.MkdirAll(baseDir, 0o755))
+
+	srcinfo := "pkgbase = " + pkgbase + "\n" +
+		"\tpkgdesc = " + pkgdesc + "\n" +
+		"\tpkgver = " + pkgver + "\n" +
+		"\tpkgrel = 1\n" +
+		"\tarch = x86_64\n" +
+		"\tmakedepends = go\n" +
+		"\tdepends = glibc\n" +
+		"\tdepends_x86_64 = lib32-glibc\n\n" +
+		"pkgname = " + pkgbase + "\n"
+
+	assert.NoError(t, os.WriteFile(filepath.Join(baseDir, ".SRCINFO"), []byte(srcinfo), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(baseDir, "PKGBUILD"),
+		[]byte("pkgname="+pkgbase+"\npkgver="+pkgver+"\n"), 0o644))
+}
+
+func names(pkgs []aur.Pkg) []string {
+	result := make([]string, 0, len(pkgs))
+	for i := range pkgs {
+		result = append(result, pkgs[i].Name+"@"+pkgs[i].Version)
+	}
+
+	return result
+}
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+
+	high := t.TempDir()
+	low := t.TempDir()
+
+	writeBase(t, high, "foo", "2.0", "Internal foo")
+	writeBase(t, low, "foo", "3.0", "Shadowed foo")
+	writeBase(t, low, "bar", "1.0", "Internal bar")
+	writeBase(t, low, "baz", "1.0", "Internal baz")
+	assert.NoError(t, os.MkdirAll(filepath.Join(low, "notapackage"), 0o755))
+
+	fallback := &mockFallback{pkgs: []aur.Pkg{
+		{Name: "foo", PackageBase: "foo", Version: "1.0-1", Description: "AUR foo"},
+		{Name: "baz", PackageBase: "baz", Version: "5.0-1", Description: "AUR baz"},
+	}}
+
+	return NewClient(fallback, &localGitBuilder{}, t.TempDir(), []Source{
+		{Name: "low", URL: low, Priority: -1},
+		{Name: "high", URL: high, Priority: 1},
+		{Name: "", URL: low},
+	}, NewResolved())
+}
+
+func TestClientInfo(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t)
+	assert.Len(t, client.Sources, 2)
+	assert.Equal(t, "high", client.Sources[0].Name)
+
+	got, err := client.Info(context.Background(), []string{"foo", "bar", "baz", "qux"})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"foo@2.0-1", "bar@1.0-1", "baz@5.0-1"}, names(got))
+
+	for i := range got {
+		if got[i].Name == "foo" {
+			assert.Equal(t, []string{"glibc", "lib32-glibc"}, got[i].Depends)
+			assert.Equal(t, []string{"go"}, got[i].MakeDepends)
+		}
+	}
+
+	repo, ok := client.Resolved.Repository("foo")
+	assert.True(t, ok)
+	assert.Equal(t, "high", repo)
+
+	repo, ok = client.Resolved.Repository("bar")
+	assert.True(t, ok)
+	assert.Equal(t, "low", repo)
+
+	_, ok = client.Resolved.Repository("baz")
+	assert.False(t, ok)
+}
+
+func TestClientSearch(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t)
+
+	got, err := client.Search(context.Background(), "internal", aur.NameDesc)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"baz@5.0-1", "foo@2.0-1", "bar@1.0-1"}, names(got))
+}
+
+func TestClientDownload(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	client := newTestClient(t)
+	dest := t.TempDir()
+
+	_, err := client.Info(context.Background(), []string{"bar"})
+	assert.NoError(t, err)
+
+	cloned, err := client.Download(context.Background(), "bar", dest, false)
+	assert.NoError(t, err)
+	assert.True(t, cloned)
+
+	pkgbuild, err := os.ReadFile(filepath.Join(dest, "bar", "PKGBUILD"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(pkgbuild), "pkgver=1.0")
+
+	writeBase(t, client.Sources[1].URL, "bar", "1.1", "Internal bar")
+
+	cloned, err = client.Download(context.Background(), "bar", dest, false)
+	assert.NoError(t, err)
+	assert.False(t, cloned)
+
+	pkgbuild, err = os.ReadFile(filepath.Join(dest, "bar", "PKGBUILD"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(pkgbuild), "pkgver=1.1")
+
+	out, err := exec.Command("git", "-C", filepath.Join(dest, "bar"), "log", "--format=%s").Output()
+	assert.NoError(t, err)
+	assert.Len(t, strings.Split(strings.TrimSpace(string(out)), "\n"), 2)
+
+	_, err = client.Download(context.Background(), "qux", dest, false)
+	assert.Error(t, err)
+}
+
+func TestLocalInfo(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeBase(t, dir, "foo", "9.0", "Local foo")
+
+	srcinfo, err := ReadSrcinfo(context.Background(), nil, filepath.Join(dir, "foo"))
+	assert.NoError(t, err)
+
+	local := NewLocal(&mockFallback{pkgs: []aur.Pkg{
+		{Name: "foo", PackageBase: "foo", Version: "1.0-1"},
+		{Name: "baz", PackageBase: "baz", Version: "5.0-1"},
+	}}, []*gosrc.Srcinfo{srcinfo})
+
+	got, err := local.Info(context.Background(), []string{"foo", "baz", "qux"})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"foo@9.0-1", "baz@5.0-1"}, names(got))
+
+	_, err = ReadSrcinfo(context.Background(), nil, t.TempDir())
+	assert.Error(t, err)
+}
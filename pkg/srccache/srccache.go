@@ -0,0 +1,386 @@
+// Package srccache shares downloaded PKGBUILD sources between packages and
+// rebuilds. Files are stored under the checksum .SRCINFO lists for them, so a
+// tarball used by several PKGBUILDs is downloaded once.
+package srccache
+
+import (
+	"crypto/md5"  // nolint:gosec // only used to match makepkg checksums
+	"crypto/sha1" // nolint:gosec // only used to match makepkg checksums
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gosrc "github.com/Morganamilo/go-srcinfo"
+)
+
+// algorithm is a checksum makepkg may list, strongest first.
+type algorithm struct {
+	name string
+	new  func() hash.Hash
+	sums func(*gosrc.Srcinfo) []gosrc.ArchString
+}
+
+var algorithms = []algorithm{
+	{"sha512", sha512.New, func(s *gosrc.Srcinfo) []gosrc.ArchString { return s.SHA512Sums }},
+	{"sha384", sha512.New384, func(s *gosrc.Srcinfo) []gosrc.ArchString { return s.SHA384Sums }},
+	{"sha256", sha256.New, func(s *gosrc.Srcinfo) []gosrc.ArchString { return s.SHA256Sums }},
+	{"sha224", sha256.New224, func(s *gosrc.Srcinfo) []gosrc.ArchString { return s.SHA224Sums }},
+	{"sha1", sha1.New, func(s *gosrc.Srcinfo) []gosrc.ArchString { return s.SHA1Sums }},
+	{"md5", md5.New, func(s *gosrc.Srcinfo) []gosrc.ArchString { return s.MD5Sums }},
+}
+
+// Entry is a downloaded source file along with its checksum.
+type Entry struct {
+	File      string
+	Algorithm string
+	Sum       string
+}
+
+// Entries returns the sources of srcinfo for carch that can be cached:
+// files downloaded over http, https or ftp with a checksum other than SKIP.
+func Entries(srcinfo *gosrc.Srcinfo, carch string) []Entry {
+	entries := []Entry{}
+	archs := []string{""}
+
+	if carch != "" {
+		archs = append(archs, carch)
+	}
+
+	for _, arch := range archs {
+		sources := forArch(srcinfo.Source, arch)
+
+		for i, source := range sources {
+			file, ok := fileName(source)
+			if !ok {
+				continue
+			}
+
+			for _, algo := range algorithms {
+				sums := forArch(algo.sums(srcinfo), arch)
+				if i >= len(sums) || sums[i] == "SKIP" {
+					continue
+				}
+
+				entries = append(entries, Entry{File: file, Algorithm: algo.name, Sum: strings.ToLower(sums[i])})
+
+				break
+			}
+		}
+	}
+
+	return entries
+}
+
+func forArch(values []gosrc.ArchString, arch string) []string {
+	matching := []string{}
+
+	for _, value := range values {
+		if value.Arch == arch {
+			matching = append(matching, value.Value)
+		}
+	}
+
+	return matching
+}
+
+// fileName returns the name makepkg saves source under, and whether it is a
+// plain download.
+func fileName(source string) (string, bool) {
+	url := source
+	name := ""
+
+	if i := strings.Index(source, "::"); i >= 0 {
+		name, url = source[:i], source[i+2:]
+	}
+
+	i := strings.Index(url, "://")
+	if i < 0 {
+		return "", false
+	}
+
+	switch url[:i] {
+	case "http", "https", "ftp":
+	default:
+		return "", false
+	}
+
+	if name == "" {
+		name = path.Base(url)
+	}
+
+	if name == "" || name == "." || name == "/" || strings.Contains(name, "/") {
+		return "", false
+	}
+
+	return name, true
+}
+
+// Cache is a directory of source files named after their checksum. Files are
+// copied in and out of the cache rather than linked, so evicting them frees
+// their space and refreshing their age leaves build directories alone.
+type Cache struct {
+	Dir     string
+	MaxSize int64
+
+	mux   sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func (c *Cache) path(entry *Entry) string {
+	return filepath.Join(c.Dir, entry.Algorithm, entry.Sum)
+}
+
+// Lock serializes the downloads of entries between workers: a source shared
+// by several package bases is downloaded by the first worker to lock it and
+// restored from the cache by the others. The returned function unlocks them.
+func (c *Cache) Lock(entries []Entry) func() {
+	keys := make([]string, 0, len(entries))
+	for i := range entries {
+		keys = append(keys, entries[i].Algorithm+"/"+entries[i].Sum)
+	}
+
+	// a consistent order keeps workers sharing several sources from deadlocking
+	sort.Strings(keys)
+
+	held := make([]*sync.Mutex, 0, len(keys))
+
+	for i, key := range keys {
+		if i > 0 && keys[i-1] == key {
+			continue
+		}
+
+		c.mux.Lock()
+		if c.locks == nil {
+			c.locks = make(map[string]*sync.Mutex)
+		}
+
+		lock, ok := c.locks[key]
+		if !ok {
+			lock = &sync.Mutex{}
+			c.locks[key] = lock
+		}
+		c.mux.Unlock()
+
+		lock.Lock()
+
+		held = append(held, lock)
+	}
+
+	return func() {
+		for _, lock := range held {
+			lock.Unlock()
+		}
+	}
+}
+
+// Restore places the cached files of entries missing from srcdest there,
+// returning the paths it created.
+func (c *Cache) Restore(entries []Entry, srcdest string) ([]string, error) {
+	restored := []string{}
+
+	for i := range entries {
+		dest := filepath.Join(srcdest, entries[i].File)
+		if _, err := os.Lstat(dest); err == nil {
+			continue
+		}
+
+		cached := c.path(&entries[i])
+		if _, err := os.Stat(cached); err != nil {
+			continue
+		}
+
+		if err := os.MkdirAll(srcdest, 0o755); err != nil {
+			return restored, err
+		}
+
+		if err := copyFile(cached, dest); err != nil {
+			return restored, err
+		}
+
+		restored = append(restored, dest)
+
+		touch(cached)
+	}
+
+	return restored, nil
+}
+
+// Store adds the files of entries found in srcdest to the cache, returning
+// how many were added. Files not matching their checksum are left out.
+func (c *Cache) Store(entries []Entry, srcdest string) (int, error) {
+	stored := 0
+
+	for i := range entries {
+		cached := c.path(&entries[i])
+		if _, err := os.Stat(cached); err == nil {
+			touch(cached)
+			continue
+		}
+
+		src := filepath.Join(srcdest, entries[i].File)
+		if info, err := os.Stat(src); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		sum, err := checksum(src, entries[i].Algorithm)
+		if err != nil {
+			return stored, err
+		}
+
+		if sum != entries[i].Sum {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(cached), 0o755); err != nil {
+			return stored, err
+		}
+
+		// copy under a temporary name first so readers never see a partial file
+		tmp, err := tempName(filepath.Dir(cached), entries[i].Sum)
+		if err != nil {
+			return stored, err
+		}
+
+		if err := copyFile(src, tmp); err != nil {
+			return stored, err
+		}
+
+		if err := os.Rename(tmp, cached); err != nil {
+			return stored, err
+		}
+
+		stored++
+	}
+
+	return stored, nil
+}
+
+// Evict removes the least recently used files until the cache fits MaxSize.
+func (c *Cache) Evict() (int, error) {
+	type file struct {
+		path  string
+		size  int64
+		mtime time.Time
+	}
+
+	files := []file{}
+
+	var total int64
+
+	err := filepath.WalkDir(c.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+
+		files = append(files, file{path: path, size: info.Size(), mtime: info.ModTime()})
+		total += info.Size()
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].mtime.Before(files[j].mtime) })
+
+	removed := 0
+
+	for _, f := range files {
+		if total <= c.MaxSize {
+			break
+		}
+
+		if err := os.Remove(f.path); err != nil {
+			return removed, err
+		}
+
+		total -= f.size
+		removed++
+	}
+
+	return removed, nil
+}
+
+// tempName returns an unused path in dir, as workers may store the same file
+// at the same time.
+func tempName(dir, prefix string) (string, error) {
+	f, err := os.CreateTemp(dir, prefix+".*.part")
+	if err != nil {
+		return "", err
+	}
+
+	name := f.Name()
+	_ = f.Close()
+
+	return name, os.Remove(name)
+}
+
+func touch(path string) {
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+}
+
+func checksum(path, algorithmName string) (string, error) {
+	for _, algo := range algorithms {
+		if algo.name != algorithmName {
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+
+		h := algo.new()
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	return "", nil
+}
+
+// copyFile copies src to the new file dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dst)
+
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,178 @@
+package srccache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gosrc "github.com/Morganamilo/go-srcinfo"
+	"github.com/stretchr/testify/assert"
+)
+
+func sha256sum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestEntries(t *testing.T) {
+	t.Parallel()
+
+	srcinfo := &gosrc.Srcinfo{}
+	srcinfo.Source = []gosrc.ArchString{
+		{Value: "https://example.com/foo-1.0.tar.gz"},
+		{Value: "bar.tar.gz::https://example.com/download?id=1"},
+		{Value: "git+https://example.com/foo.git"},
+		{Value: "foo.patch"},
+		{Value: "ftp://example.com/skipped.tar.gz"},
+		{Arch: "x86_64", Value: "https://example.com/foo-x86_64.bin"},
+		{Arch: "aarch64", Value: "https://example.com/foo-aarch64.bin"},
+	}
+	srcinfo.SHA256Sums = []gosrc.ArchString{
+		{Value: "AAAA"}, {Value: "bbbb"}, {Value: "SKIP"}, {Value: "cccc"}, {Value: "SKIP"},
+		{Arch: "x86_64", Value: "dddd"},
+		{Arch: "aarch64", Value: "eeee"},
+	}
+	srcinfo.B2Sums = []gosrc.ArchString{{Value: "ffff"}}
+	srcinfo.SHA512Sums = []gosrc.ArchString{{Value: "1111"}}
+
+	assert.Equal(t, []Entry{
+		{File: "foo-1.0.tar.gz", Algorithm: "sha512", Sum: "1111"},
+		{File: "bar.tar.gz", Algorithm: "sha256", Sum: "bbbb"},
+		{File: "foo-x86_64.bin", Algorithm: "sha256", Sum: "dddd"},
+	}, Entries(srcinfo, "x86_64"))
+
+	assert.Len(t, Entries(srcinfo, ""), 2)
+}
+
+func TestCacheStoreRestore(t *testing.T) {
+	t.Parallel()
+
+	cache := &Cache{Dir: filepath.Join(t.TempDir(), "cache"), MaxSize: 1 << 20}
+	first := t.TempDir()
+	second := filepath.Join(t.TempDir(), "srcdest")
+
+	entries := []Entry{
+		{File: "foo.tar.gz", Algorithm: "sha256", Sum: sha256sum("foo")},
+		{File: "bad.tar.gz", Algorithm: "sha256", Sum: sha256sum("expected")},
+		{File: "missing.tar.gz", Algorithm: "sha256", Sum: sha256sum("missing")},
+	}
+
+	assert.NoError(t, os.WriteFile(filepath.Join(first, "foo.tar.gz"), []byte("foo"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(first, "bad.tar.gz"), []byte("corrupted"), 0o644))
+
+	stored, err := cache.Store(entries, first)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stored)
+
+	stored, err = cache.Store(entries, first)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stored)
+
+	restored, err := cache.Restore(entries, second)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(second, "foo.tar.gz")}, restored)
+
+	content, err := os.ReadFile(filepath.Join(second, "foo.tar.gz"))
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", string(content))
+
+	restored, err = cache.Restore(entries, second)
+	assert.NoError(t, err)
+	assert.Empty(t, restored)
+}
+
+func TestCacheEvict(t *testing.T) {
+	t.Parallel()
+
+	cache := &Cache{Dir: t.TempDir(), MaxSize: 25}
+	old := time.Now().Add(-time.Hour)
+
+	for i, name := range []string{"old", "middle", "new"} {
+		path := filepath.Join(cache.Dir, "sha256", name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, make([]byte, 10), 0o644))
+
+		mtime := old.Add(time.Duration(i) * time.Minute)
+		assert.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+
+	removed, err := cache.Evict()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	assert.NoFileExists(t, filepath.Join(cache.Dir, "sha256", "old"))
+	assert.FileExists(t, filepath.Join(cache.Dir, "sha256", "middle"))
+	assert.FileExists(t, filepath.Join(cache.Dir, "sha256", "new"))
+
+	removed, err = (&Cache{Dir: filepath.Join(cache.Dir, "missing")}).Evict()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, removed)
+}
+
+func TestCacheCopies(t *testing.T) {
+	t.Parallel()
+
+	cache := &Cache{Dir: filepath.Join(t.TempDir(), "cache"), MaxSize: 0}
+	srcdest := t.TempDir()
+	entries := []Entry{{File: "foo.tar.gz", Algorithm: "sha256", Sum: sha256sum("foo")}}
+
+	assert.NoError(t, os.WriteFile(filepath.Join(srcdest, "foo.tar.gz"), []byte("foo"), 0o644))
+
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	assert.NoError(t, os.Chtimes(filepath.Join(srcdest, "foo.tar.gz"), old, old))
+
+	stored, err := cache.Store(entries, srcdest)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stored)
+
+	// the build directory keeps its own file and mtime
+	info, err := os.Stat(filepath.Join(srcdest, "foo.tar.gz"))
+	assert.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(old))
+
+	_, err = cache.Store(entries, srcdest)
+	assert.NoError(t, err)
+
+	info, err = os.Stat(filepath.Join(srcdest, "foo.tar.gz"))
+	assert.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(old))
+
+	// evicting a cached file does not touch the copy in use
+	removed, err := cache.Evict()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.FileExists(t, filepath.Join(srcdest, "foo.tar.gz"))
+}
+
+func TestCacheLock(t *testing.T) {
+	t.Parallel()
+
+	cache := &Cache{Dir: t.TempDir()}
+	shared := Entry{File: "shared.tar.gz", Algorithm: "sha256", Sum: sha256sum("shared")}
+	first := []Entry{{File: "a.tar.gz", Algorithm: "sha256", Sum: sha256sum("a")}, shared}
+	second := []Entry{shared, shared}
+
+	unlock := cache.Lock(first)
+	locked := make(chan struct{})
+
+	go func() {
+		defer close(locked)
+
+		cache.Lock(second)()
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("a shared source was locked twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// unrelated sources are not serialized
+	cache.Lock([]Entry{{File: "b.tar.gz", Algorithm: "sha256", Sum: sha256sum("b")}})()
+
+	unlock()
+	<-locked
+}
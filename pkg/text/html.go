@@ -0,0 +1,105 @@
+package text
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// isUnsafeControl reports whether r is a C0 or C1 control character other than
+// a newline or tab. Written to the terminal these start escape sequences able
+// to rewrite the screen, change the window title or inject hyperlinks.
+func isUnsafeControl(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\t'
+}
+
+// StripControl removes the C0 and C1 control characters of s, except newlines
+// and tabs.
+func StripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if isUnsafeControl(r) {
+			return -1
+		}
+
+		return r
+	}, s)
+}
+
+// ParseHTML is a crude html to text conversion, good enough for the arch news
+// and AUR comments. The input comes from the network, so control characters,
+// literal or escaped as entities, are stripped to keep it from sending escape
+// sequences to the terminal.
+func ParseHTML(str string) string {
+	var (
+		buffer       bytes.Buffer
+		tagBuffer    bytes.Buffer
+		escapeBuffer bytes.Buffer
+		inTag        = false
+		inEscape     = false
+	)
+
+	for _, char := range str {
+		if inTag {
+			if char == '>' {
+				inTag = false
+
+				switch tagBuffer.String() {
+				case "code":
+					buffer.WriteString(CyanCode)
+				case "/code":
+					buffer.WriteString(ResetCode)
+				case "/p":
+					buffer.WriteRune('\n')
+				}
+
+				continue
+			}
+
+			tagBuffer.WriteRune(char)
+
+			continue
+		}
+
+		if inEscape {
+			if char == ';' {
+				inEscape = false
+
+				escapeBuffer.WriteRune(char)
+				s := html.UnescapeString(escapeBuffer.String())
+				buffer.WriteString(StripControl(s))
+
+				continue
+			}
+
+			escapeBuffer.WriteRune(char)
+
+			continue
+		}
+
+		if char == '<' {
+			inTag = true
+
+			tagBuffer.Reset()
+
+			continue
+		}
+
+		if char == '&' {
+			inEscape = true
+
+			escapeBuffer.Reset()
+			escapeBuffer.WriteRune(char)
+
+			continue
+		}
+
+		if !isUnsafeControl(char) {
+			buffer.WriteRune(char)
+		}
+	}
+
+	buffer.WriteString(ResetCode)
+
+	return buffer.String()
+}
@@ -0,0 +1,35 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHTML(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{name: "paragraphs", html: "<p>one</p><p>two</p>", want: "one\ntwo\n"},
+		{name: "code", html: "run <code>yay</code>", want: "run " + CyanCode + "yay" + ResetCode},
+		{name: "entities", html: "&quot;go&quot; &amp; more", want: `"go" & more`},
+		{name: "tabs kept", html: "a\tb\nc", want: "a\tb\nc"},
+		{name: "literal csi", html: "\x1b[2Jcleared", want: "[2Jcleared"},
+		{name: "literal osc", html: "\x1b]8;;https://evil\x07link\x1b]8;;\x07", want: "]8;;https://evillink]8;;"},
+		{name: "escaped csi", html: "&#27;[31mred&#x1b;[0m", want: "[31mred[0m"},
+		{name: "escaped osc", html: "&#x1b;]0;title&#7;", want: "]0;title"},
+		{name: "c1 csi", html: "\u009b31mred", want: "31mred"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want+ResetCode, ParseHTML(tt.html))
+		})
+	}
+}
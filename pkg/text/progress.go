@@ -0,0 +1,291 @@
+package text
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/multierror"
+)
+
+// DownloadState is the step a concurrent download is at.
+type DownloadState int
+
+const (
+	Queued DownloadState = iota
+	Cloning
+	Pulling
+	FetchingSources
+	Done
+	Failed
+)
+
+func (s DownloadState) String() string {
+	switch s {
+	case Queued:
+		return gotext.Get("queued")
+	case Cloning:
+		return gotext.Get("cloning")
+	case Pulling:
+		return gotext.Get("pulling")
+	case FetchingSources:
+		return gotext.Get("fetching sources")
+	case Done:
+		return gotext.Get("done")
+	case Failed:
+		return gotext.Get("failed")
+	}
+
+	return ""
+}
+
+// ErrDownloadFailed is the failure of the download of Name.
+type ErrDownloadFailed struct {
+	Name string
+	Err  error
+}
+
+func (e ErrDownloadFailed) Error() string {
+	return Cyan(e.Name) + ": " + strings.TrimSpace(e.Err.Error())
+}
+
+func (e ErrDownloadFailed) Unwrap() error {
+	return e.Err
+}
+
+// Progress displays the state of concurrent downloads. When live, a compact
+// summary is redrawn in place on every change, otherwise a line is printed
+// when each download ends. A nil Progress prints messages like the package
+// level functions and ignores updates.
+type Progress struct {
+	mux    sync.Mutex
+	out    io.Writer
+	live   bool
+	title  string
+	names  []string
+	states map[string]DownloadState
+	errs   map[string]error
+	ended  int
+	drawn  int
+}
+
+// NewProgress tracks the downloads of names, all queued.
+func NewProgress(out io.Writer, title string, live bool, names []string) *Progress {
+	p := &Progress{
+		out:    out,
+		live:   live,
+		title:  title,
+		names:  names,
+		states: make(map[string]DownloadState, len(names)),
+		errs:   make(map[string]error),
+	}
+
+	for _, name := range names {
+		p.states[name] = Queued
+	}
+
+	p.mux.Lock()
+	p.draw()
+	p.mux.Unlock()
+
+	return p
+}
+
+// Set moves name to state.
+func (p *Progress) Set(name string, state DownloadState) {
+	if p == nil {
+		return
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.states[name] = state
+	p.draw()
+}
+
+// Done marks the download of name as successful, described by message when
+// not live.
+func (p *Progress) Done(name, message string) {
+	if p == nil {
+		return
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.states[name] = Done
+	p.ended++
+
+	if !p.live {
+		fmt.Fprintln(p.out, SprintOperationInfo(
+			fmt.Sprintf("(%d/%d) %s: %s", p.ended, len(p.names), message, Cyan(name))))
+	}
+
+	p.draw()
+}
+
+// Fail marks the download of name as failed with err.
+func (p *Progress) Fail(name string, err error) {
+	if p == nil {
+		return
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.states[name] = Failed
+	p.errs[name] = err
+	p.ended++
+
+	if !p.live {
+		fmt.Fprintln(p.out, SprintWarn(
+			fmt.Sprintf("(%d/%d) %s: %s", p.ended, len(p.names), gotext.Get("failed"), Cyan(name))))
+	}
+
+	p.draw()
+}
+
+// Warnln prints a warning without breaking the live display.
+func (p *Progress) Warnln(a ...interface{}) {
+	if p == nil {
+		Warnln(a...)
+		return
+	}
+
+	p.println(SprintWarn(fmt.Sprint(a...)))
+}
+
+// OperationInfoln prints an information without breaking the live display.
+func (p *Progress) OperationInfoln(a ...interface{}) {
+	if p == nil {
+		OperationInfoln(a...)
+		return
+	}
+
+	p.println(SprintOperationInfo(a...))
+}
+
+func (p *Progress) println(line string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.clear()
+	fmt.Fprintln(p.out, line)
+	p.draw()
+}
+
+// Live reports whether the progress is redrawn in place, in which case the
+// output of commands should be captured rather than shown.
+func (p *Progress) Live() bool {
+	return p != nil && p.live
+}
+
+// Close ends the display and returns the failures, one per download in the
+// order they were given.
+func (p *Progress) Close() error {
+	if p == nil {
+		return nil
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.live && p.drawn > 0 {
+		fmt.Fprintln(p.out)
+		p.drawn = 0
+	}
+
+	var errs multierror.MultiError
+
+	for _, name := range p.names {
+		if err, ok := p.errs[name]; ok {
+			errs.Add(ErrDownloadFailed{Name: name, Err: err})
+		}
+	}
+
+	return errs.Return()
+}
+
+// clear moves back to the start of the live display and erases it.
+func (p *Progress) clear() {
+	if p.drawn == 0 {
+		return
+	}
+
+	fmt.Fprint(p.out, "\r")
+
+	if p.drawn > 1 {
+		fmt.Fprintf(p.out, "\x1b[%dA", p.drawn-1)
+	}
+
+	fmt.Fprint(p.out, "\x1b[J")
+
+	p.drawn = 0
+}
+
+func (p *Progress) draw() {
+	if !p.live {
+		return
+	}
+
+	lines := p.summary()
+
+	p.clear()
+	fmt.Fprint(p.out, strings.Join(lines, "\n"))
+
+	p.drawn = len(lines)
+}
+
+// summary returns the lines of the live display: the count of downloads per
+// state, then the downloads in progress.
+func (p *Progress) summary() []string {
+	counts := make(map[DownloadState]int)
+	active := []string{}
+
+	for _, name := range p.names {
+		state := p.states[name]
+		counts[state]++
+
+		if state != Queued && state != Done && state != Failed {
+			active = append(active, name+" ("+state.String()+")")
+		}
+	}
+
+	parts := []string{}
+
+	for _, state := range []DownloadState{Cloning, Pulling, FetchingSources, Queued, Failed} {
+		if counts[state] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[state], state))
+		}
+	}
+
+	status := fmt.Sprintf("(%d/%d) %s", counts[Done]+counts[Failed], len(p.names), p.title)
+	if len(parts) > 0 {
+		status += ": " + strings.Join(parts, ", ")
+	}
+
+	// leave room for the operation symbol and the indentation
+	lines := []string{SprintOperationInfo(truncate(status, getColumnCount()-len(opSymbol)-2))}
+
+	if len(active) > 0 {
+		lines = append(lines, "   "+truncate(strings.Join(active, ", "), getColumnCount()-4))
+	}
+
+	return lines
+}
+
+// truncate shortens s to width characters, ending it with an ellipsis.
+func truncate(s string, width int) string {
+	const ellipsis = "..."
+
+	runes := []rune(s)
+	if len(runes) <= width || width <= len(ellipsis) {
+		return s
+	}
+
+	return string(runes[:width-len(ellipsis)]) + ellipsis
+}
@@ -0,0 +1,94 @@
+package text
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v11/pkg/multierror"
+)
+
+func TestProgressPlain(t *testing.T) {
+	t.Parallel()
+
+	out := &bytes.Buffer{}
+	progress := NewProgress(out, "Downloading", false, []string{"foo", "bar", "baz"})
+
+	progress.Set("foo", Cloning)
+	assert.Empty(t, out.String())
+
+	progress.Done("foo", "Downloaded")
+	progress.Fail("baz", errors.New("unreachable\n"))
+	progress.Fail("bar", errors.New("not found"))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "(1/3) Downloaded: ")
+	assert.Contains(t, lines[0], "foo")
+	assert.Contains(t, lines[1], "(2/3) failed: ")
+	assert.Contains(t, lines[1], "baz")
+
+	err := progress.Close()
+
+	var errs *multierror.MultiError
+
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs.Errors, 2)
+
+	var failed ErrDownloadFailed
+
+	// failures follow the order of the names, not the order they happened in
+	assert.True(t, errors.As(errs.Errors[0], &failed))
+	assert.Equal(t, "bar", failed.Name)
+	assert.True(t, errors.As(errs.Errors[1], &failed))
+	assert.Equal(t, "baz", failed.Name)
+	assert.True(t, strings.HasSuffix(failed.Error(), ": unreachable"))
+}
+
+func TestProgressLive(t *testing.T) {
+	t.Parallel()
+
+	out := &bytes.Buffer{}
+	progress := NewProgress(out, "Downloading", true, []string{"foo", "bar", "baz"})
+
+	assert.True(t, progress.Live())
+	assert.Contains(t, out.String(), "(0/3) Downloading: 3 queued")
+
+	progress.Set("foo", Cloning)
+	progress.Set("bar", Pulling)
+	assert.Contains(t, out.String(), "1 cloning, 1 pulling, 1 queued")
+	assert.Contains(t, out.String(), "foo (cloning), bar (pulling)")
+
+	progress.Done("foo", "Downloaded")
+	progress.Fail("bar", errors.New("not found"))
+	progress.Done("baz", "Downloaded")
+	assert.Contains(t, out.String(), "(3/3) Downloading: 1 failed")
+	assert.NotContains(t, out.String(), "Downloaded")
+
+	assert.Error(t, progress.Close())
+	assert.True(t, strings.HasSuffix(out.String(), "\n"))
+}
+
+func TestProgressNil(t *testing.T) {
+	t.Parallel()
+
+	var progress *Progress
+
+	progress.Set("foo", Cloning)
+	progress.Done("foo", "Downloaded")
+	progress.Fail("foo", errors.New("not found"))
+
+	assert.False(t, progress.Live())
+	assert.NoError(t, progress.Close())
+}
+
+func TestTruncate(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "foo", truncate("foo", 10))
+	assert.Equal(t, "föo bä...", truncate("föo bär baz", 9))
+	assert.Equal(t, "foo bar", truncate("foo bar", 2))
+}
@@ -0,0 +1,102 @@
+package upgrade
+
+import (
+	alpm "github.com/Jguer/go-alpm/v2"
+
+	"github.com/Jguer/yay/v11/pkg/db"
+	"github.com/Jguer/yay/v11/pkg/query"
+)
+
+// JSONEntry is the machine readable description of an available upgrade.
+type JSONEntry struct {
+	Name          string   `json:"name"`
+	Repository    string   `json:"repository"`
+	LocalVersion  string   `json:"localversion"`
+	RemoteVersion string   `json:"remoteversion"`
+	Reason        string   `json:"reason"`
+	Ignored       bool     `json:"ignored"`
+	AUR           *JSONAUR `json:"aur,omitempty"`
+}
+
+// JSONAUR holds the AUR specific details of an upgrade.
+type JSONAUR struct {
+	Maintainer string `json:"maintainer"`
+	OutOfDate  bool   `json:"outofdate"`
+}
+
+// JSONCount is the machine readable number of available upgrades.
+type JSONCount struct {
+	Total int `json:"total"`
+	Repo  int `json:"repo"`
+	AUR   int `json:"aur"`
+}
+
+func reasonString(reason alpm.PkgReason) string {
+	if reason == alpm.PkgReasonDepend {
+		return "dependency"
+	}
+
+	return "explicit"
+}
+
+// NewJSONEntry converts an upgrade to a JSONEntry.
+// aurdata is used to fill the AUR details of AUR and devel upgrades.
+func NewJSONEntry(up Upgrade, ignored bool, aurdata map[string]*query.Pkg) JSONEntry {
+	entry := JSONEntry{
+		Name:          up.Name,
+		Repository:    up.Repository,
+		LocalVersion:  up.LocalVersion,
+		RemoteVersion: up.RemoteVersion,
+		Reason:        reasonString(up.Reason),
+		Ignored:       ignored,
+	}
+
+	if aurPkg, ok := aurdata[up.Name]; ok && (up.Repository == "aur" || up.Repository == "devel") {
+		entry.AUR = &JSONAUR{
+			Maintainer: aurPkg.Maintainer,
+			OutOfDate:  aurPkg.OutOfDate != 0,
+		}
+	}
+
+	return entry
+}
+
+// UpIgnored gathers the upgrades held back by IgnorePkg and IgnoreGroup.
+// Repository packages are looked up with syncPackage, foreign packages in aurdata.
+func UpIgnored(local []db.IPackage, syncPackage func(string) db.IPackage,
+	aurdata map[string]*query.Pkg) (repoUp, aurUp UpSlice) {
+	repoUp = UpSlice{Up: make([]Upgrade, 0)}
+	aurUp = UpSlice{Up: make([]Upgrade, 0), Repos: []string{"aur"}}
+
+	for _, pkg := range local {
+		if !pkg.ShouldIgnore() {
+			continue
+		}
+
+		if syncPkg := syncPackage(pkg.Name()); syncPkg != nil {
+			if db.VerCmp(pkg.Version(), syncPkg.Version()) < 0 {
+				repoUp.Up = append(repoUp.Up, Upgrade{
+					Name:          pkg.Name(),
+					Repository:    syncPkg.DB().Name(),
+					LocalVersion:  pkg.Version(),
+					RemoteVersion: syncPkg.Version(),
+					Reason:        pkg.Reason(),
+				})
+			}
+
+			continue
+		}
+
+		if aurPkg, ok := aurdata[pkg.Name()]; ok && db.VerCmp(pkg.Version(), aurPkg.Version) < 0 {
+			aurUp.Up = append(aurUp.Up, Upgrade{
+				Name:          pkg.Name(),
+				Repository:    "aur",
+				LocalVersion:  pkg.Version(),
+				RemoteVersion: aurPkg.Version,
+				Reason:        pkg.Reason(),
+			})
+		}
+	}
+
+	return repoUp, aurUp
+}
@@ -0,0 +1,101 @@
+package upgrade
+
+import (
+	"testing"
+
+	aur "github.com/Jguer/aur"
+	alpm "github.com/Jguer/go-alpm/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jguer/yay/v11/pkg/db"
+	"github.com/Jguer/yay/v11/pkg/db/mock"
+)
+
+func TestNewJSONEntry(t *testing.T) {
+	t.Parallel()
+
+	aurdata := map[string]*aur.Pkg{
+		"yay": {Name: "yay", Version: "11.0.0-1", Maintainer: "jguer", OutOfDate: 1640000000},
+	}
+
+	tests := []struct {
+		name    string
+		up      Upgrade
+		ignored bool
+		want    JSONEntry
+	}{
+		{
+			name: "repo",
+			up: Upgrade{
+				Name: "linux", Repository: "core", LocalVersion: "5.15-1",
+				RemoteVersion: "5.16-1", Reason: alpm.PkgReasonExplicit,
+			},
+			want: JSONEntry{
+				Name: "linux", Repository: "core", LocalVersion: "5.15-1",
+				RemoteVersion: "5.16-1", Reason: "explicit",
+			},
+		},
+		{
+			name: "aur ignored",
+			up: Upgrade{
+				Name: "yay", Repository: "aur", LocalVersion: "10.0.0-1",
+				RemoteVersion: "11.0.0-1", Reason: alpm.PkgReasonDepend,
+			},
+			ignored: true,
+			want: JSONEntry{
+				Name: "yay", Repository: "aur", LocalVersion: "10.0.0-1",
+				RemoteVersion: "11.0.0-1", Reason: "dependency", Ignored: true,
+				AUR: &JSONAUR{Maintainer: "jguer", OutOfDate: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, NewJSONEntry(tt.up, tt.ignored, aurdata))
+		})
+	}
+}
+
+func TestUpIgnored(t *testing.T) {
+	t.Parallel()
+
+	local := []db.IPackage{
+		&mock.Package{PName: "linux", PVersion: "5.15-1", PShouldIgnore: true},
+		&mock.Package{PName: "glibc", PVersion: "2.33-1"},
+		&mock.Package{PName: "bash", PVersion: "5.1-1", PShouldIgnore: true},
+		&mock.Package{PName: "yay", PVersion: "10.0.0-1", PShouldIgnore: true, PReason: alpm.PkgReasonDepend},
+		&mock.Package{PName: "paru", PVersion: "1.0.0-1", PShouldIgnore: true},
+	}
+
+	sync := map[string]db.IPackage{
+		"linux": &mock.Package{PName: "linux", PVersion: "5.16-1", PDB: mock.NewDB("core")},
+		"glibc": &mock.Package{PName: "glibc", PVersion: "2.34-1", PDB: mock.NewDB("core")},
+		"bash":  &mock.Package{PName: "bash", PVersion: "5.1-1", PDB: mock.NewDB("core")},
+	}
+
+	syncPackage := func(name string) db.IPackage {
+		if pkg, ok := sync[name]; ok {
+			return pkg
+		}
+
+		return nil
+	}
+
+	aurdata := map[string]*aur.Pkg{
+		"yay":  {Name: "yay", Version: "11.0.0-1"},
+		"paru": {Name: "paru", Version: "1.0.0-1"},
+	}
+
+	repoUp, aurUp := UpIgnored(local, syncPackage, aurdata)
+
+	assert.Equal(t, []Upgrade{{
+		Name: "linux", Repository: "core", LocalVersion: "5.15-1", RemoteVersion: "5.16-1",
+	}}, repoUp.Up)
+	assert.Equal(t, []Upgrade{{
+		Name: "yay", Repository: "aur", LocalVersion: "10.0.0-1", RemoteVersion: "11.0.0-1",
+		Reason: alpm.PkgReasonDepend,
+	}}, aurUp.Up)
+}
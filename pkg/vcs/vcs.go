@@ -37,10 +37,17 @@ type OriginInfoByURL map[string]OriginInfo
 // 	"branch": "next",
 // 	"sha": "c1171d41467c68ffd3c46748182a16366aaaf87b"
 // }.
+//
+// Sources pinned with #tag= record the tag instead of a branch, sources
+// pinned with #commit= record the commit and are never outdated.
+// Unpinned sources also record the remote default branch (HEAD symref).
 type OriginInfo struct {
-	Protocols []string `json:"protocols"`
-	Branch    string   `json:"branch"`
-	SHA       string   `json:"sha"`
+	Protocols     []string `json:"protocols"`
+	Branch        string   `json:"branch"`
+	SHA           string   `json:"sha"`
+	Tag           string   `json:"tag,omitempty"`
+	Commit        string   `json:"commit,omitempty"`
+	DefaultBranch string   `json:"defaultbranch,omitempty"`
 }
 
 func NewInfoStore(filePath string, cmdBuilder exe.GitCmdBuilder) *InfoStore {
@@ -53,40 +60,136 @@ func NewInfoStore(filePath string, cmdBuilder exe.GitCmdBuilder) *InfoStore {
 	return infoStore
 }
 
+// lsRemote runs git ls-remote against url with the given arguments.
+func (v *InfoStore) lsRemote(ctx context.Context, url string, protocols []string, args ...string) (string, bool) {
+	if len(protocols) == 0 {
+		return "", false
+	}
+
+	protocol := protocols[len(protocols)-1]
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	lsArgs := make([]string, 0, len(args)+2)
+	lsArgs = append(lsArgs, "ls-remote")
+
+	// keep --symref and friends in front of the repository
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		lsArgs = append(lsArgs, args[0])
+		args = args[1:]
+	}
+
+	lsArgs = append(lsArgs, protocol+"://"+url)
+	lsArgs = append(lsArgs, args...)
+
+	cmd := v.CmdBuilder.BuildGitCmd(ctxTimeout, "", lsArgs...)
+
+	stdout, _, err := v.CmdBuilder.Capture(cmd)
+	if err != nil {
+		if exiterr, ok := err.(*exec.ExitError); ok && exiterr.ExitCode() == 128 {
+			text.Warnln(gotext.Get("devel check for package failed: '%s' encountered an error", cmd.String()))
+			return "", false
+		}
+
+		text.Warnln(err)
+
+		return "", false
+	}
+
+	return stdout, true
+}
+
 // GetCommit parses HEAD commit from url and branch.
 func (v *InfoStore) getCommit(ctx context.Context, url, branch string, protocols []string) string {
-	if len(protocols) > 0 {
-		protocol := protocols[len(protocols)-1]
+	stdout, ok := v.lsRemote(ctx, url, protocols, branch)
+	if !ok {
+		return ""
+	}
 
-		ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
+	split := strings.Fields(stdout)
 
-		cmd := v.CmdBuilder.BuildGitCmd(ctxTimeout, "", "ls-remote", protocol+"://"+url, branch)
+	if len(split) < 2 {
+		return ""
+	}
 
-		stdout, _, err := v.CmdBuilder.Capture(cmd)
-		if err != nil {
-			if exiterr, ok := err.(*exec.ExitError); ok && exiterr.ExitCode() == 128 {
-				text.Warnln(gotext.Get("devel check for package failed: '%s' encountered an error", cmd.String()))
-				return ""
-			}
+	commit := split[0]
+
+	return commit
+}
+
+// getTagCommit returns the commit a tag points to.
+// Annotated tags are peeled so the commit is returned instead of the tag object.
+func (v *InfoStore) getTagCommit(ctx context.Context, url, tag string, protocols []string) string {
+	ref := "refs/tags/" + tag
+
+	stdout, ok := v.lsRemote(ctx, url, protocols, ref, ref+"^{}")
+	if !ok {
+		return ""
+	}
+
+	commit := ""
 
-			text.Warnln(err)
+	for _, line := range strings.Split(stdout, "\n") {
+		split := strings.Fields(line)
+		if len(split) < 2 {
+			continue
+		}
 
-			return ""
+		switch split[1] {
+		case ref + "^{}":
+			return split[0]
+		case ref:
+			commit = split[0]
 		}
+	}
 
-		split := strings.Fields(stdout)
+	return commit
+}
 
+// getHead returns the commit HEAD points to and the branch HEAD is a
+// symbolic ref to in the remote.
+func (v *InfoStore) getHead(ctx context.Context, url string, protocols []string) (commit, defaultBranch string) {
+	stdout, ok := v.lsRemote(ctx, url, protocols, "--symref", "HEAD")
+	if !ok {
+		return "", ""
+	}
+
+	for _, line := range strings.Split(stdout, "\n") {
+		split := strings.Fields(line)
 		if len(split) < 2 {
-			return ""
+			continue
 		}
 
-		commit := split[0]
+		if split[0] == "ref:" {
+			if len(split) >= 3 {
+				defaultBranch = strings.TrimPrefix(split[1], "refs/heads/")
+			}
 
-		return commit
+			continue
+		}
+
+		if commit == "" {
+			commit = split[0]
+		}
 	}
 
-	return ""
+	return commit, defaultBranch
+}
+
+// getRemote returns the current upstream commit for the origin and
+// the remote default branch when the origin follows HEAD.
+func (v *InfoStore) getRemote(ctx context.Context, url string, info OriginInfo) (commit, defaultBranch string) {
+	switch {
+	case info.Commit != "":
+		return info.Commit, ""
+	case info.Tag != "":
+		return v.getTagCommit(ctx, url, info.Tag, info.Protocols), ""
+	case info.Branch == "HEAD":
+		return v.getHead(ctx, url, info.Protocols)
+	default:
+		return v.getCommit(ctx, url, info.Branch, info.Protocols), ""
+	}
 }
 
 func (v *InfoStore) Update(ctx context.Context, pkgName string,
@@ -97,22 +200,19 @@ func (v *InfoStore) Update(ctx context.Context, pkgName string,
 	checkSource := func(source gosrc.ArchString) {
 		defer wg.Done()
 
-		url, branch, protocols := parseSource(source.Value)
-		if url == "" || branch == "" {
+		url, origin := parseSource(source.Value)
+		if url == "" || origin.Commit != "" {
+			// pinned commits never move, nothing to track
 			return
 		}
 
-		commit := v.getCommit(ctx, url, branch, protocols)
-		if commit == "" {
+		origin.SHA, origin.DefaultBranch = v.getRemote(ctx, url, origin)
+		if origin.SHA == "" {
 			return
 		}
 
 		mux.Lock()
-		info[url] = OriginInfo{
-			protocols,
-			branch,
-			commit,
-		}
+		info[url] = origin
 
 		v.OriginsByPackage[pkgName] = info
 
@@ -131,17 +231,18 @@ func (v *InfoStore) Update(ctx context.Context, pkgName string,
 	}
 }
 
-// parseSource returns the git url, default branch and protocols it supports.
-func parseSource(source string) (url, branch string, protocols []string) {
+// parseSource returns the git url and the origin info describing which
+// ref the source follows (branch, tag or commit) and the protocols it supports.
+func parseSource(source string) (url string, origin OriginInfo) {
 	split := strings.Split(source, "::")
 	source = split[len(split)-1]
 	split = strings.SplitN(source, "://", 2)
 
 	if len(split) != 2 {
-		return "", "", nil
+		return "", OriginInfo{}
 	}
 
-	protocols = strings.SplitN(split[0], "+", 2)
+	protocols := strings.SplitN(split[0], "+", 2)
 
 	git := false
 
@@ -155,31 +256,39 @@ func parseSource(source string) (url, branch string, protocols []string) {
 	protocols = protocols[len(protocols)-1:]
 
 	if !git {
-		return "", "", nil
+		return "", OriginInfo{}
 	}
 
+	origin.Protocols = protocols
+
 	split = strings.SplitN(split[1], "#", 2)
-	if len(split) == 2 {
-		secondSplit := strings.SplitN(split[1], "=", 2)
-		if secondSplit[0] != "branch" {
-			// source has #commit= or #tag= which makes them not vcs
-			// packages because they reference a specific point
-			return "", "", nil
-		}
+	url = strings.Split(split[0], "?")[0]
 
-		if len(secondSplit) == 2 {
-			url = split[0]
-			branch = secondSplit[1]
-		}
-	} else {
-		url = split[0]
-		branch = "HEAD"
+	if len(split) == 1 {
+		origin.Branch = "HEAD"
+
+		return url, origin
 	}
 
-	url = strings.Split(url, "?")[0]
-	branch = strings.Split(branch, "?")[0]
+	fragment := strings.SplitN(split[1], "=", 2)
+	if len(fragment) != 2 {
+		return "", OriginInfo{}
+	}
+
+	value := strings.Split(fragment[1], "?")[0]
+
+	switch fragment[0] {
+	case "branch":
+		origin.Branch = value
+	case "tag":
+		origin.Tag = value
+	case "commit":
+		origin.Commit = value
+	default:
+		return "", OriginInfo{}
+	}
 
-	return url, branch, protocols
+	return url, origin
 }
 
 func (v *InfoStore) NeedsUpdate(ctx context.Context, infos OriginInfoByURL) bool {
@@ -194,13 +303,21 @@ func (v *InfoStore) NeedsUpdate(ctx context.Context, infos OriginInfoByURL) bool
 	defer close(closed)
 
 	checkHash := func(url string, info OriginInfo) {
-		hash := v.getCommit(ctx, url, info.Branch, info.Protocols)
+		var sendTo chan<- struct{} = finished
 
-		var sendTo chan<- struct{}
-		if hash != "" && hash != info.SHA {
-			sendTo = hasUpdate
-		} else {
-			sendTo = finished
+		// commit pinned sources never move
+		if info.Commit == "" {
+			hash, defaultBranch := v.getRemote(ctx, url, info)
+
+			switch {
+			case hash != "" && hash != info.SHA:
+				sendTo = hasUpdate
+			case defaultBranch != "" && info.DefaultBranch != "" && defaultBranch != info.DefaultBranch:
+				text.Warnln(gotext.Get("%s: default branch changed from %s to %s",
+					text.Cyan(url), info.DefaultBranch, defaultBranch))
+
+				sendTo = hasUpdate
+			}
 		}
 
 		select {
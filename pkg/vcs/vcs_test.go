@@ -20,9 +20,8 @@ import (
 func TestParsing(t *testing.T) {
 	t.Parallel()
 	type source struct {
-		URL       string
-		Branch    string
-		Protocols []string
+		URL    string
+		Origin OriginInfo
 	}
 
 	urls := []string{
@@ -32,24 +31,32 @@ func TestParsing(t *testing.T) {
 		"git://github.com/jguer/yay.git#tag=v3.440",
 		"git://github.com/jguer/yay.git#commit=e5470c88c6e2f9e0f97deb4728659ffa70ef5d0c",
 		"a+b+c+d+e+f://github.com/jguer/yay.git#branch=foo",
+		"yay::git+https://github.com/jguer/yay.git#tag=v10.0.0?signed",
+		"git+https://github.com/jguer/yay.git#revision=1",
+		"https://github.com/jguer/yay/archive/v10.0.0.tar.gz",
 	}
 
 	sources := []source{
-		{"github.com/neovim/neovim.git", "HEAD", []string{"https"}},
-		{"github.com/jguer/yay.git", "master", []string{"git"}},
-		{"github.com/davidgiven/ack", "HEAD", []string{"git"}},
-		{"", "", nil},
-		{"", "", nil},
-		{"", "", nil},
+		{"github.com/neovim/neovim.git", OriginInfo{Protocols: []string{"https"}, Branch: "HEAD"}},
+		{"github.com/jguer/yay.git", OriginInfo{Protocols: []string{"git"}, Branch: "master"}},
+		{"github.com/davidgiven/ack", OriginInfo{Protocols: []string{"git"}, Branch: "HEAD"}},
+		{"github.com/jguer/yay.git", OriginInfo{Protocols: []string{"git"}, Tag: "v3.440"}},
+		{"github.com/jguer/yay.git", OriginInfo{
+			Protocols: []string{"git"},
+			Commit:    "e5470c88c6e2f9e0f97deb4728659ffa70ef5d0c",
+		}},
+		{"", OriginInfo{}},
+		{"github.com/jguer/yay.git", OriginInfo{Protocols: []string{"https"}, Tag: "v10.0.0"}},
+		{"", OriginInfo{}},
+		{"", OriginInfo{}},
 	}
 
 	for n, url := range urls {
-		url, branch, protocols := parseSource(url)
+		url, origin := parseSource(url)
 		compare := sources[n]
 
 		assert.Equal(t, compare.URL, url)
-		assert.Equal(t, compare.Branch, branch)
-		assert.Equal(t, compare.Protocols, protocols)
+		assert.Equal(t, compare.Origin, origin)
 	}
 }
 
@@ -201,6 +208,86 @@ func TestInfoStore_NeedsUpdate(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "commit-never_outdated",
+			args: args{infos: OriginInfoByURL{
+				"github.com/Jguer/z.git": OriginInfo{
+					Protocols: []string{"https"},
+					Commit:    "991c5b4146fd27f4aacf4e3111258a848934aaa1",
+					SHA:       "991c5b4146fd27f4aacf4e3111258a848934aaa1",
+				},
+			}}, fields: fields{
+				CmdBuilder: &exe.CmdBuilder{GitBin: "git", GitFlags: []string{""}, Runner: &MockRunner{
+					Returned: []string{"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa	HEAD"},
+				}},
+			},
+			want: false,
+		},
+		{
+			name: "tag-peeled_no_update",
+			args: args{infos: OriginInfoByURL{
+				"github.com/Jguer/z.git": OriginInfo{
+					Protocols: []string{"https"},
+					Tag:       "v1.0",
+					SHA:       "991c5b4146fd27f4aacf4e3111258a848934aaa1",
+				},
+			}}, fields: fields{
+				CmdBuilder: &exe.CmdBuilder{GitBin: "git", GitFlags: []string{""}, Runner: &MockRunner{
+					Returned: []string{"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa	refs/tags/v1.0\n" +
+						"991c5b4146fd27f4aacf4e3111258a848934aaa1	refs/tags/v1.0^{}"},
+				}},
+			},
+			want: false,
+		},
+		{
+			name: "tag-moved",
+			args: args{infos: OriginInfoByURL{
+				"github.com/Jguer/z.git": OriginInfo{
+					Protocols: []string{"https"},
+					Tag:       "v1.0",
+					SHA:       "991c5b4146fd27f4aacf4e3111258a848934aaa1",
+				},
+			}}, fields: fields{
+				CmdBuilder: &exe.CmdBuilder{GitBin: "git", GitFlags: []string{""}, Runner: &MockRunner{
+					Returned: []string{"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa	refs/tags/v1.0"},
+				}},
+			},
+			want: true,
+		},
+		{
+			name: "head-default_branch_changed",
+			args: args{infos: OriginInfoByURL{
+				"github.com/Jguer/z.git": OriginInfo{
+					Protocols:     []string{"https"},
+					Branch:        "HEAD",
+					DefaultBranch: "master",
+					SHA:           "991c5b4146fd27f4aacf4e3111258a848934aaa1",
+				},
+			}}, fields: fields{
+				CmdBuilder: &exe.CmdBuilder{GitBin: "git", GitFlags: []string{""}, Runner: &MockRunner{
+					Returned: []string{"ref: refs/heads/main	HEAD\n" +
+						"991c5b4146fd27f4aacf4e3111258a848934aaa1	HEAD"},
+				}},
+			},
+			want: true,
+		},
+		{
+			name: "head-no_update",
+			args: args{infos: OriginInfoByURL{
+				"github.com/Jguer/z.git": OriginInfo{
+					Protocols:     []string{"https"},
+					Branch:        "HEAD",
+					DefaultBranch: "main",
+					SHA:           "991c5b4146fd27f4aacf4e3111258a848934aaa1",
+				},
+			}}, fields: fields{
+				CmdBuilder: &exe.CmdBuilder{GitBin: "git", GitFlags: []string{""}, Runner: &MockRunner{
+					Returned: []string{"ref: refs/heads/main	HEAD\n" +
+						"991c5b4146fd27f4aacf4e3111258a848934aaa1	HEAD"},
+				}},
+			},
+			want: false,
+		},
 		{
 			name: "simple-no protocol",
 			args: args{infos: OriginInfoByURL{
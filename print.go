@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
@@ -37,30 +38,36 @@ func (q aurQuery) printSearch(start int, dbExecutor db.Executor) {
 			continue
 		}
 
-		toprint += text.Bold(text.ColorHash("aur")) + "/" + text.Bold(q[i].Name) +
-			" " + text.Cyan(q[i].Version) +
-			text.Bold(" (+"+strconv.Itoa(q[i].NumVotes)) +
-			" " + text.Bold(strconv.FormatFloat(q[i].Popularity, 'f', 2, 64)+") ")
+		toprint += aurSearchLine(&q[i], dbExecutor)
+		fmt.Println(toprint)
+	}
+}
 
-		if q[i].Maintainer == "" {
-			toprint += text.Bold(text.Red(gotext.Get("(Orphaned)"))) + " "
-		}
+// aurSearchLine formats an AUR package search result.
+func aurSearchLine(a *aur.Pkg, dbExecutor db.Executor) string {
+	repo := aurRepository(a.PackageBase)
+	toprint := text.Bold(text.ColorHash(repo)) + "/" + text.Bold(a.Name) +
+		" " + text.Cyan(a.Version) +
+		text.Bold(" (+"+strconv.Itoa(a.NumVotes)) +
+		" " + text.Bold(strconv.FormatFloat(a.Popularity, 'f', 2, 64)+") ")
 
-		if q[i].OutOfDate != 0 {
-			toprint += text.Bold(text.Red(gotext.Get("(Out-of-date: %s)", text.FormatTime(q[i].OutOfDate)))) + " "
-		}
+	if a.Maintainer == "" {
+		toprint += text.Bold(text.Red(gotext.Get("(Orphaned)"))) + " "
+	}
 
-		if pkg := dbExecutor.LocalPackage(q[i].Name); pkg != nil {
-			if pkg.Version() != q[i].Version {
-				toprint += text.Bold(text.Green(gotext.Get("(Installed: %s)", pkg.Version())))
-			} else {
-				toprint += text.Bold(text.Green(gotext.Get("(Installed)")))
-			}
-		}
+	if a.OutOfDate != 0 {
+		toprint += text.Bold(text.Red(gotext.Get("(Out-of-date: %s)", text.FormatTime(a.OutOfDate)))) + " "
+	}
 
-		toprint += "\n    " + q[i].Description
-		fmt.Println(toprint)
+	if pkg := dbExecutor.LocalPackage(a.Name); pkg != nil {
+		if pkg.Version() != a.Version {
+			toprint += text.Bold(text.Green(gotext.Get("(Installed: %s)", pkg.Version())))
+		} else {
+			toprint += text.Bold(text.Green(gotext.Get("(Installed)")))
+		}
 	}
+
+	return toprint + "\n    " + a.Description
 }
 
 // PrintSearch receives a RepoSearch type and outputs pretty text.
@@ -82,25 +89,54 @@ func (s repoQuery) printSearch(dbExecutor db.Executor) {
 			continue
 		}
 
-		toprint += text.Bold(text.ColorHash(res.DB().Name())) + "/" + text.Bold(res.Name()) +
-			" " + text.Cyan(res.Version()) +
-			text.Bold(" ("+text.Human(res.Size())+
-				" "+text.Human(res.ISize())+") ")
+		toprint += repoSearchLine(res, dbExecutor)
+		fmt.Println(toprint)
+	}
+}
 
-		packageGroups := dbExecutor.PackageGroups(res)
-		if len(packageGroups) != 0 {
-			toprint += fmt.Sprint(packageGroups, " ")
+// repoSearchLine formats a repo package search result.
+func repoSearchLine(res db.IPackage, dbExecutor db.Executor) string {
+	toprint := text.Bold(text.ColorHash(res.DB().Name())) + "/" + text.Bold(res.Name()) +
+		" " + text.Cyan(res.Version()) +
+		text.Bold(" ("+text.Human(res.Size())+
+			" "+text.Human(res.ISize())+") ")
+
+	packageGroups := dbExecutor.PackageGroups(res)
+	if len(packageGroups) != 0 {
+		toprint += fmt.Sprint(packageGroups, " ")
+	}
+
+	if pkg := dbExecutor.LocalPackage(res.Name()); pkg != nil {
+		if pkg.Version() != res.Version() {
+			toprint += text.Bold(text.Green(gotext.Get("(Installed: %s)", pkg.Version())))
+		} else {
+			toprint += text.Bold(text.Green(gotext.Get("(Installed)")))
 		}
+	}
 
-		if pkg := dbExecutor.LocalPackage(res.Name()); pkg != nil {
-			if pkg.Version() != res.Version() {
-				toprint += text.Bold(text.Green(gotext.Get("(Installed: %s)", pkg.Version())))
-			} else {
-				toprint += text.Bold(text.Green(gotext.Get("(Installed)")))
-			}
+	return toprint + "\n    " + res.Description()
+}
+
+// printSearch prints the merged results of a relevance ranked search.
+func (q rankedQuery) printSearch(dbExecutor db.Executor) {
+	for i := range q {
+		if config.SearchMode == minimal {
+			fmt.Println(q[i].name())
+			continue
+		}
+
+		var toprint string
+
+		if config.SearchMode == numberMenu {
+			toprint += text.Magenta(strconv.Itoa(q.number(i)) + " ")
+		}
+
+		if q[i].repo != nil {
+			toprint += repoSearchLine(q[i].repo, dbExecutor)
+		} else {
+			toprint += aurSearchLine(q[i].aur, dbExecutor)
 		}
 
-		toprint += "\n    " + res.Description()
 		fmt.Println(toprint)
 	}
 }
@@ -108,8 +144,8 @@ func (s repoQuery) printSearch(dbExecutor db.Executor) {
 // Pretty print a set of packages from the same package base.
 
 // PrintInfo prints package info like pacman -Si.
-func PrintInfo(a *aur.Pkg, extendedInfo bool) {
-	text.PrintInfoValue(gotext.Get("Repository"), "aur")
+func PrintInfo(a *aur.Pkg, extendedInfo bool, requiredBy []string) {
+	text.PrintInfoValue(gotext.Get("Repository"), aurRepository(a.PackageBase))
 	text.PrintInfoValue(gotext.Get("Name"), a.Name)
 	text.PrintInfoValue(gotext.Get("Keywords"), a.Keywords...)
 	text.PrintInfoValue(gotext.Get("Version"), a.Version)
@@ -124,6 +160,11 @@ func PrintInfo(a *aur.Pkg, extendedInfo bool) {
 	text.PrintInfoValue(gotext.Get("Check Deps"), a.CheckDepends...)
 	text.PrintInfoValue(gotext.Get("Optional Deps"), a.OptDepends...)
 	text.PrintInfoValue(gotext.Get("Conflicts With"), a.Conflicts...)
+
+	if requiredBy != nil {
+		text.PrintInfoValue(gotext.Get("Required By"), requiredBy...)
+	}
+
 	text.PrintInfoValue(gotext.Get("Maintainer"), a.Maintainer)
 	text.PrintInfoValue(gotext.Get("Votes"), fmt.Sprintf("%d", a.NumVotes))
 	text.PrintInfoValue(gotext.Get("Popularity"), fmt.Sprintf("%f", a.Popularity))
@@ -179,22 +220,31 @@ func localStatistics(ctx context.Context, dbExecutor db.Executor) error {
 	biggestPackages(dbExecutor)
 	fmt.Println(text.Bold(text.Cyan("===========================================")))
 
-	query.AURInfoPrint(ctx, config.Runtime.AURClient, remoteNames, config.RequestSplitN)
+	query.AURInfoPrint(ctx, config.Runtime.QueryClient, remoteNames, config.RequestSplitN)
 
 	return nil
 }
 
-func printNumberOfUpdates(ctx context.Context, dbExecutor db.Executor, enableDowngrade bool, filter upgrade.Filter) error {
+func printNumberOfUpdates(ctx context.Context, dbExecutor db.Executor, enableDowngrade, asJSON bool,
+	filter upgrade.Filter) error {
 	warnings := query.NewWarnings()
 	old := os.Stdout // keep backup of the real stdout
 	os.Stdout = nil
-	aurUp, repoUp, err := upList(ctx, warnings, dbExecutor, enableDowngrade, filter)
+	aurUp, repoUp, _, err := upList(ctx, warnings, dbExecutor, enableDowngrade, filter)
 	os.Stdout = old // restoring the real stdout
 
 	if err != nil {
 		return err
 	}
 
+	if asJSON {
+		return printJSON(upgrade.JSONCount{
+			Total: len(aurUp.Up) + len(repoUp.Up),
+			Repo:  len(repoUp.Up),
+			AUR:   len(aurUp.Up),
+		})
+	}
+
 	fmt.Println(len(aurUp.Up) + len(repoUp.Up))
 
 	return nil
@@ -213,7 +263,17 @@ func printUpdateList(ctx context.Context, cmdArgs *parser.Arguments,
 		return err
 	}
 
-	aurUp, repoUp, err := upList(ctx, warnings, dbExecutor, enableDowngrade, filter)
+	aurUp, repoUp, aurdata, err := upList(ctx, warnings, dbExecutor, enableDowngrade, filter)
+
+	var (
+		asJSON              = cmdArgs.ExistsArg("json")
+		ignRepoUp, ignAURUp upgrade.UpSlice
+	)
+
+	if err == nil && asJSON {
+		ignRepoUp, ignAURUp = ignoredUpList(dbExecutor, aurdata, filter)
+	}
+
 	os.Stdout = old // restoring the real stdout
 
 	if err != nil {
@@ -221,13 +281,17 @@ func printUpdateList(ctx context.Context, cmdArgs *parser.Arguments,
 	}
 
 	noTargets := len(targets) == 0
+	entries := make([]upgrade.JSONEntry, 0)
 
 	if !cmdArgs.ExistsArg("m", "foreign") {
 		for _, pkg := range repoUp.Up {
 			if noTargets || targets.Get(pkg.Name) {
-				if cmdArgs.ExistsArg("q", "quiet") {
+				switch {
+				case asJSON:
+					entries = append(entries, upgrade.NewJSONEntry(pkg, false, aurdata))
+				case cmdArgs.ExistsArg("q", "quiet"):
 					fmt.Printf("%s\n", pkg.Name)
-				} else {
+				default:
 					fmt.Printf("%s %s -> %s\n", text.Bold(pkg.Name), text.Green(pkg.LocalVersion), text.Green(pkg.RemoteVersion))
 				}
 
@@ -239,9 +303,12 @@ func printUpdateList(ctx context.Context, cmdArgs *parser.Arguments,
 	if !cmdArgs.ExistsArg("n", "native") {
 		for _, pkg := range aurUp.Up {
 			if noTargets || targets.Get(pkg.Name) {
-				if cmdArgs.ExistsArg("q", "quiet") {
+				switch {
+				case asJSON:
+					entries = append(entries, upgrade.NewJSONEntry(pkg, false, aurdata))
+				case cmdArgs.ExistsArg("q", "quiet"):
 					fmt.Printf("%s\n", pkg.Name)
-				} else {
+				default:
 					fmt.Printf("%s %s -> %s\n", text.Bold(pkg.Name), text.Green(pkg.LocalVersion), text.Green(pkg.RemoteVersion))
 				}
 
@@ -250,6 +317,30 @@ func printUpdateList(ctx context.Context, cmdArgs *parser.Arguments,
 		}
 	}
 
+	if asJSON {
+		ignored := make([]upgrade.Upgrade, 0, len(ignRepoUp.Up)+len(ignAURUp.Up))
+
+		if !cmdArgs.ExistsArg("m", "foreign") {
+			ignored = append(ignored, ignRepoUp.Up...)
+		}
+
+		if !cmdArgs.ExistsArg("n", "native") {
+			ignored = append(ignored, ignAURUp.Up...)
+		}
+
+		for _, pkg := range ignored {
+			if noTargets || targets.Get(pkg.Name) {
+				entries = append(entries, upgrade.NewJSONEntry(pkg, true, aurdata))
+
+				delete(targets, pkg.Name)
+			}
+		}
+
+		if errJSON := printJSON(entries); errJSON != nil {
+			return errJSON
+		}
+	}
+
 	missing := false
 
 outer:
@@ -276,3 +367,31 @@ outer:
 
 	return nil
 }
+
+// ignoredUpList returns the upgrades held back by IgnorePkg and IgnoreGroup,
+// foreign packages are looked up in the aurdata upList fetched.
+func ignoredUpList(dbExecutor db.Executor, aurdata map[string]*aur.Pkg,
+	filter upgrade.Filter) (repoUp, aurUp upgrade.UpSlice) {
+	repoUp, aurUp = upgrade.UpIgnored(dbExecutor.LocalPackages(), dbExecutor.SyncPackage, aurdata)
+
+	if !config.Runtime.Mode.AtLeastRepo() {
+		repoUp.Up = nil
+	}
+
+	repoUp.Up = filterUpdateList(repoUp.Up, filter)
+	aurUp.Up = filterUpdateList(aurUp.Up, filter)
+
+	return repoUp, aurUp
+}
+
+// printJSON prints v as indented JSON to stdout.
+func printJSON(v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(out))
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/Jguer/yay/v11/pkg/multierror"
+	"github.com/Jguer/yay/v11/pkg/publish"
+)
+
+// yay -Y --publish.
+func publishPkgbuilds(ctx context.Context, dirs []string, dryRun bool) error {
+	if len(dirs) == 0 {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+
+		dirs = []string{wd}
+	}
+
+	publisher := &publish.Publisher{
+		CmdBuilder: config.Runtime.CmdBuilder,
+		AURURL:     config.AURURL,
+		DryRun:     dryRun,
+	}
+
+	var errs multierror.MultiError
+
+	for _, dir := range dirs {
+		errs.Add(publisher.Publish(ctx, dir))
+	}
+
+	return errs.Return()
+}
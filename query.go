@@ -12,6 +12,7 @@ import (
 	alpm "github.com/Jguer/go-alpm/v2"
 	"github.com/leonelquinteros/gotext"
 
+	"github.com/Jguer/yay/v11/pkg/comments"
 	"github.com/Jguer/yay/v11/pkg/db"
 	"github.com/Jguer/yay/v11/pkg/query"
 	"github.com/Jguer/yay/v11/pkg/settings"
@@ -89,7 +90,7 @@ func getSearchBy(value string) aur.By {
 }
 
 // NarrowSearch searches AUR and narrows based on subarguments.
-func narrowSearch(ctx context.Context, aurClient *aur.Client, pkgS []string, sortS bool) (aurQuery, error) {
+func narrowSearch(ctx context.Context, aurClient aur.ClientInterface, pkgS []string, sortS bool) (aurQuery, error) {
 	var (
 		r         []aur.Pkg
 		err       error
@@ -161,8 +162,152 @@ func narrowSearch(ctx context.Context, aurClient *aur.Client, pkgS []string, sor
 	return aq, err
 }
 
+// sortByRelevance merges repo and AUR search results ranked by relevance.
+const sortByRelevance = "relevance"
+
+// rankedResult is a repo or AUR package found by a relevance ranked search.
+type rankedResult struct {
+	score float64
+	repo  db.IPackage
+	aur   *aur.Pkg
+}
+
+// rankedQuery holds the results of a relevance ranked search.
+type rankedQuery []rankedResult
+
+func (r *rankedResult) name() string {
+	if r.repo != nil {
+		return r.repo.Name()
+	}
+
+	return r.aur.Name
+}
+
+// target returns the install target of the result, prefixed by its db.
+func (r *rankedResult) target() string {
+	if r.repo != nil {
+		return r.repo.DB().Name() + "/" + r.repo.Name()
+	}
+
+	return "aur/" + r.aur.Name
+}
+
+// number returns the menu number of the i-th result.
+func (q rankedQuery) number(i int) int {
+	if config.SortMode == settings.BottomUp {
+		return len(q) - i
+	}
+
+	return i + 1
+}
+
+// rankedSearch searches the repos and the AUR for the words of pkgS and ranks
+// the packages matching all of them by relevance, the most relevant first, or
+// last with bottomup. Repo packages are matched with typo tolerance against
+// the whole sync database.
+func rankedSearch(ctx context.Context, aurClient aur.ClientInterface,
+	pkgS []string, dbExecutor db.Executor) (rankedQuery, error) {
+	var (
+		rq     rankedQuery
+		aurErr error
+	)
+
+	if config.Runtime.Mode.AtLeastAUR() {
+		by := getSearchBy(config.SearchBy)
+
+		var (
+			results []aur.Pkg
+			used    string
+		)
+
+		// a single search for the most selective term, the others are matched
+		// by the scorer like for repo packages
+		for _, word := range bySelectivity(pkgS) {
+			results, aurErr = aurClient.Search(ctx, word, by)
+			if aurErr == nil {
+				used = word
+
+				break
+			}
+		}
+
+		terms := pkgS
+		if by != aur.Name && by != aur.NameDesc {
+			// the searched field matched the used term, the others must
+			// match the name or description
+			terms = withoutTerm(pkgS, used)
+		}
+
+		for i := range results {
+			score := query.Score(terms, results[i].Name, results[i].Description, results[i].Popularity)
+			if score > 0 || len(terms) == 0 {
+				rq = append(rq, rankedResult{score: score, aur: &results[i]})
+			}
+		}
+	}
+
+	if config.Runtime.Mode.AtLeastRepo() {
+		for _, pkg := range dbExecutor.SyncPackages() {
+			// official packages rank like popular AUR packages
+			if score := query.Score(pkgS, pkg.Name(), pkg.Description(), repoPopularity); score > 0 {
+				rq = append(rq, rankedResult{score: score, repo: pkg})
+			}
+		}
+	}
+
+	sort.SliceStable(rq, func(i, j int) bool {
+		if rq[i].score != rq[j].score {
+			return rq[i].score > rq[j].score
+		}
+
+		return text.LessRunes([]rune(rq[i].name()), []rune(rq[j].name()))
+	})
+
+	if config.SortMode == settings.BottomUp {
+		for i, j := 0, len(rq)-1; i < j; i, j = i+1, j-1 {
+			rq[i], rq[j] = rq[j], rq[i]
+		}
+	}
+
+	return rq, aurErr
+}
+
+// bySelectivity returns the search terms from longest to shortest, longer
+// terms matching fewer AUR packages.
+func bySelectivity(pkgS []string) []string {
+	terms := make([]string, len(pkgS))
+	copy(terms, pkgS)
+
+	sort.SliceStable(terms, func(i, j int) bool {
+		return len([]rune(terms[i])) > len([]rune(terms[j]))
+	})
+
+	return terms
+}
+
+// withoutTerm returns pkgS without the first occurrence of term.
+func withoutTerm(pkgS []string, term string) []string {
+	terms := make([]string, 0, len(pkgS))
+	removed := false
+
+	for _, word := range pkgS {
+		if word == term && !removed {
+			removed = true
+
+			continue
+		}
+
+		terms = append(terms, word)
+	}
+
+	return terms
+}
+
+// repoPopularity is the popularity given to repo packages when ranking.
+const repoPopularity = 10
+
 // SyncSearch presents a query to the local repos and to the AUR.
-func syncSearch(ctx context.Context, pkgS []string, aurClient *aur.Client, dbExecutor db.Executor) (err error) {
+func syncSearch(ctx context.Context, pkgS []string, aurClient aur.ClientInterface, dbExecutor db.Executor) (err error) {
 	pkgS = query.RemoveInvalidTargets(pkgS, config.Runtime.Mode)
 
 	var (
@@ -171,12 +316,19 @@ func syncSearch(ctx context.Context, pkgS []string, aurClient *aur.Client, dbExe
 		pq     repoQuery
 	)
 
-	if config.Runtime.Mode.AtLeastAUR() {
-		aq, aurErr = narrowSearch(ctx, aurClient, pkgS, true)
-	}
+	if config.SortBy == sortByRelevance {
+		var rq rankedQuery
 
-	if config.Runtime.Mode.AtLeastRepo() {
-		pq = queryRepo(pkgS, dbExecutor)
+		rq, aurErr = rankedSearch(ctx, aurClient, pkgS, dbExecutor)
+		rq.printSearch(dbExecutor)
+	} else {
+		if config.Runtime.Mode.AtLeastAUR() {
+			aq, aurErr = narrowSearch(ctx, aurClient, pkgS, true)
+		}
+
+		if config.Runtime.Mode.AtLeastRepo() {
+			pq = queryRepo(pkgS, dbExecutor)
+		}
 	}
 
 	switch config.SortMode {
@@ -216,6 +368,11 @@ func syncInfo(ctx context.Context, cmdArgs *parser.Arguments, pkgS []string, dbE
 		missing = false
 	)
 
+	showComments := cmdArgs.ExistsArg("comments")
+	showRequiredBy := cmdArgs.ExistsArg("required-by")
+	cmdArgs.DelArg("comments")
+	cmdArgs.DelArg("required-by")
+
 	pkgS = query.RemoveInvalidTargets(pkgS, config.Runtime.Mode)
 	aurS, repoS := packageSlices(pkgS, dbExecutor)
 
@@ -227,7 +384,7 @@ func syncInfo(ctx context.Context, cmdArgs *parser.Arguments, pkgS []string, dbE
 			noDB = append(noDB, name)
 		}
 
-		info, err = query.AURInfoPrint(ctx, config.Runtime.AURClient, noDB, config.RequestSplitN)
+		info, err = query.AURInfoPrint(ctx, config.Runtime.QueryClient, noDB, config.RequestSplitN)
 		if err != nil {
 			missing = true
 
@@ -254,7 +411,17 @@ func syncInfo(ctx context.Context, cmdArgs *parser.Arguments, pkgS []string, dbE
 
 	if len(info) != 0 {
 		for _, pkg := range info {
-			PrintInfo(pkg, cmdArgs.ExistsDouble("i"))
+			var requiredBy []string
+
+			if showRequiredBy {
+				requiredBy = printableRequiredBy(ctx, pkg, dbExecutor)
+			}
+
+			PrintInfo(pkg, cmdArgs.ExistsDouble("i"), requiredBy)
+
+			if showComments {
+				printComments(ctx, pkg.PackageBase)
+			}
 		}
 	}
 
@@ -265,6 +432,80 @@ func syncInfo(ctx context.Context, cmdArgs *parser.Arguments, pkgS []string, dbE
 	return err
 }
 
+// requiredByFields are the search fields of the AUR packages listed by
+// -Si --required-by.
+var requiredByFields = []string{"depends", "makedepends", "optdepends"}
+
+// aurRequiredBy returns the sorted names of the AUR packages whose depends,
+// makedepends or optdepends reference pkg or one of its provides.
+func aurRequiredBy(ctx context.Context, aurClient aur.ClientInterface, pkg *aur.Pkg) ([]string, error) {
+	names := []string{pkg.Name}
+
+	for _, provide := range pkg.Provides {
+		if i := strings.IndexAny(provide, "<>="); i != -1 {
+			provide = provide[:i]
+		}
+
+		if provide != pkg.Name {
+			names = append(names, provide)
+		}
+	}
+
+	requiredBy := make(stringset.StringSet)
+
+	for _, name := range names {
+		for _, field := range requiredByFields {
+			results, err := aurClient.Search(ctx, name, getSearchBy(field))
+			if err != nil {
+				return nil, err
+			}
+
+			for i := range results {
+				if results[i].Name != pkg.Name {
+					requiredBy.Set(results[i].Name)
+				}
+			}
+		}
+	}
+
+	sorted := requiredBy.ToSlice()
+	sort.Strings(sorted)
+
+	return sorted, nil
+}
+
+// printableRequiredBy returns the AUR packages requiring pkg with the
+// installed ones highlighted. Failing to search them is not an error.
+func printableRequiredBy(ctx context.Context, pkg *aur.Pkg, dbExecutor db.Executor) []string {
+	requiredBy, err := aurRequiredBy(ctx, config.Runtime.QueryClient, pkg)
+	if err != nil {
+		text.Warnln(gotext.Get("unable to find the packages requiring %s: %s", pkg.Name, err))
+	}
+
+	for i, name := range requiredBy {
+		if dbExecutor.LocalPackage(name) != nil {
+			requiredBy[i] = text.Bold(text.Green(name))
+		}
+	}
+
+	return requiredBy
+}
+
+// aurCommentsLimit is the number of latest comments shown by -Si --comments.
+const aurCommentsLimit = 10
+
+// printComments prints the pinned and latest AUR comments of pkgbase.
+// Comments are informative only, failing to fetch them is not an error.
+func printComments(ctx context.Context, pkgbase string) {
+	pkgComments, err := comments.Fetch(ctx, config.Runtime.HTTPClient, config.AURURL, pkgbase)
+	if err != nil {
+		text.Warnln(gotext.Get("unable to fetch comments for %s: %s", pkgbase, err))
+		return
+	}
+
+	comments.Print(pkgComments, aurCommentsLimit)
+}
+
 // Search handles repo searches. Creates a RepoSearch struct.
 func queryRepo(pkgInputN []string, dbExecutor db.Executor) repoQuery {
 	s := repoQuery(dbExecutor.SyncPackages(pkgInputN...))
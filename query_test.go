@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Jguer/aur"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockSearchClient struct {
+	aur.ClientInterface
+	results map[aur.By]map[string][]aur.Pkg
+}
+
+func (m *mockSearchClient) Search(ctx context.Context, query string, by aur.By,
+	reqEditors ...aur.RequestEditorFn) ([]aur.Pkg, error) {
+	return m.results[by][query], nil
+}
+
+func TestAURRequiredBy(t *testing.T) {
+	t.Parallel()
+
+	client := &mockSearchClient{results: map[aur.By]map[string][]aur.Pkg{
+		aur.Depends: {
+			"libfoo":      {{Name: "bar"}, {Name: "libfoo"}},
+			"libfoo-impl": {{Name: "baz"}},
+		},
+		aur.MakeDepends: {
+			"libfoo": {{Name: "bar"}, {Name: "qux"}},
+		},
+		aur.OptDepends: {
+			"libfoo-impl": {{Name: "alpha"}},
+		},
+	}}
+
+	got, err := aurRequiredBy(context.Background(), client,
+		&aur.Pkg{Name: "libfoo", Provides: []string{"libfoo-impl=2.0", "libfoo"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alpha", "bar", "baz", "qux"}, got)
+
+	got, err = aurRequiredBy(context.Background(), client, &aur.Pkg{Name: "unused"})
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+	assert.NotNil(t, got)
+}
+
+func TestBySelectivity(t *testing.T) {
+	t.Parallel()
+
+	pkgS := []string{"qt", "browser", "web", "engine"}
+
+	assert.Equal(t, []string{"browser", "engine", "web", "qt"}, bySelectivity(pkgS))
+	assert.Equal(t, []string{"qt", "browser", "web", "engine"}, pkgS)
+	assert.Equal(t, []string{"qt", "web", "engine"}, withoutTerm(pkgS, "browser"))
+}
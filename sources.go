@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+
+	"github.com/Jguer/aur"
+	"github.com/leonelquinteros/gotext"
+
+	"github.com/Jguer/yay/v11/pkg/db"
+	"github.com/Jguer/yay/v11/pkg/download"
+	"github.com/Jguer/yay/v11/pkg/multierror"
+	"github.com/Jguer/yay/v11/pkg/text"
+)
+
+// aurRepository returns the name of the PKGBUILD source pkgbase was resolved
+// from, or aur.
+func aurRepository(pkgbase string) string {
+	if config.Runtime.Sources != nil {
+		if name, ok := config.Runtime.SourceBases.Repository(pkgbase); ok {
+			return name
+		}
+	}
+
+	return "aur"
+}
+
+// sourceNames returns the names of the configured PKGBUILD sources.
+func sourceNames() []string {
+	if config.Runtime.Sources == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(config.Runtime.Sources.Sources))
+	for _, source := range config.Runtime.Sources.Sources {
+		names = append(names, source.Name)
+	}
+
+	return names
+}
+
+// labelSourceUpgrades sets the repository of the upgrades coming from a
+// PKGBUILD source instead of the AUR.
+func labelSourceUpgrades(ups []db.Upgrade, aurdata map[string]*aur.Pkg) {
+	for i := range ups {
+		if pkg, ok := aurdata[ups[i].Name]; ok && ups[i].Repository == "aur" {
+			ups[i].Repository = aurRepository(pkg.PackageBase)
+		}
+	}
+}
+
+// downloadPKGBUILDRepos clones or pulls the PKGBUILD repositories of bases to
+// dest, from their PKGBUILD source or from the AUR. Checkouts that can not be
+// fast-forwarded are recovered as chosen by the user.
+func downloadPKGBUILDRepos(ctx context.Context, bases []string, dest string, force bool) (map[string]bool, error) {
+	if !force {
+		if errReset := resetPatched(ctx, bases, dest); errReset != nil {
+			text.Errorln(errReset)
+		}
+	}
+
+	cloned, err := fetchPKGBUILDRepos(ctx, bases, dest, force)
+	if err == nil || force {
+		return cloned, err
+	}
+
+	return cloned, recoverCheckouts(ctx, cloned, dest, err)
+}
+
+func fetchPKGBUILDRepos(ctx context.Context, bases []string, dest string, force bool) (map[string]bool, error) {
+	if config.Runtime.Sources == nil {
+		return download.AURPKGBUILDRepos(ctx, config.Runtime.CmdBuilder, bases, config.AURURL, config.FetchOptions(), dest, force)
+	}
+
+	var errs multierror.MultiError
+
+	cloned := make(map[string]bool, len(bases))
+	aurBases := make([]string, 0, len(bases))
+
+	for _, base := range bases {
+		repo, ok := config.Runtime.SourceBases.Repository(base)
+		if !ok {
+			aurBases = append(aurBases, base)
+			continue
+		}
+
+		newClone, err := config.Runtime.Sources.Download(ctx, base, dest, force)
+		if err != nil {
+			errs.Add(err)
+			continue
+		}
+
+		cloned[base] = newClone
+
+		text.OperationInfoln(gotext.Get("Downloaded PKGBUILD from %s: %s", repo, text.Cyan(base)))
+	}
+
+	aurCloned, err := download.AURPKGBUILDRepos(ctx, config.Runtime.CmdBuilder, aurBases,
+		config.AURURL, config.FetchOptions(), dest, force)
+	errs.Add(err)
+
+	for base, newClone := range aurCloned {
+		cloned[base] = newClone
+	}
+
+	return cloned, errs.Return()
+}
+
+// sourceTargets splits targets between the ones provided by a PKGBUILD source,
+// mapped to their package base, and the others.
+func sourceTargets(ctx context.Context, dbExecutor download.DBSearcher,
+	targets []string) (fromSources map[string]string, others []string) {
+	fromSources = make(map[string]string)
+
+	if config.Runtime.Sources == nil || !config.Runtime.Mode.AtLeastAUR() {
+		return fromSources, targets
+	}
+
+	names := make([]string, 0, len(targets))
+	byName := make(map[string]string, len(targets))
+
+	for _, target := range targets {
+		dbName, name := text.SplitDBFromName(target)
+		if dbName != "" && dbName != "aur" {
+			continue
+		}
+
+		if dbName == "" && config.Runtime.Mode.AtLeastRepo() && dbExecutor.SyncPackage(name) != nil {
+			continue
+		}
+
+		names = append(names, name)
+		byName[name] = target
+	}
+
+	if len(names) != 0 {
+		info, err := config.Runtime.QueryClient.Info(ctx, names)
+		if err != nil {
+			text.Warnln(err)
+		}
+
+		for i := range info {
+			if _, ok := config.Runtime.SourceBases.Repository(info[i].PackageBase); ok {
+				fromSources[byName[info[i].Name]] = info[i].PackageBase
+			}
+		}
+	}
+
+	others = make([]string, 0, len(targets))
+
+	for _, target := range targets {
+		if _, ok := fromSources[target]; !ok {
+			others = append(others, target)
+		}
+	}
+
+	return fromSources, others
+}
@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	aur "github.com/Jguer/aur"
 	alpm "github.com/Jguer/go-alpm/v2"
@@ -14,7 +15,10 @@ import (
 	"github.com/Jguer/yay/v11/pkg/db"
 	"github.com/Jguer/yay/v11/pkg/intrange"
 	"github.com/Jguer/yay/v11/pkg/multierror"
+	"github.com/Jguer/yay/v11/pkg/news"
 	"github.com/Jguer/yay/v11/pkg/query"
+	"github.com/Jguer/yay/v11/pkg/settings"
+	"github.com/Jguer/yay/v11/pkg/settings/parser"
 	"github.com/Jguer/yay/v11/pkg/stringset"
 	"github.com/Jguer/yay/v11/pkg/text"
 	"github.com/Jguer/yay/v11/pkg/upgrade"
@@ -32,9 +36,10 @@ func filterUpdateList(list []db.Upgrade, filter upgrade.Filter) []db.Upgrade {
 	return tmp
 }
 
-// upList returns lists of packages to upgrade from each source.
+// upList returns lists of packages to upgrade from each source and the AUR
+// data of the foreign packages.
 func upList(ctx context.Context, warnings *query.AURWarnings, dbExecutor db.Executor, enableDowngrade bool,
-	filter upgrade.Filter) (aurUp, repoUp upgrade.UpSlice, err error) {
+	filter upgrade.Filter) (aurUp, repoUp upgrade.UpSlice, aurdata map[string]*aur.Pkg, err error) {
 	remote, remoteNames := query.GetRemotePackages(dbExecutor)
 
 	var (
@@ -44,7 +49,7 @@ func upList(ctx context.Context, warnings *query.AURWarnings, dbExecutor db.Exec
 		errs      multierror.MultiError
 	)
 
-	aurdata := make(map[string]*aur.Pkg)
+	aurdata = make(map[string]*aur.Pkg)
 
 	for _, pkg := range remote {
 		if pkg.ShouldIgnore() {
@@ -67,7 +72,8 @@ func upList(ctx context.Context, warnings *query.AURWarnings, dbExecutor db.Exec
 		text.OperationInfoln(gotext.Get("Searching AUR for updates..."))
 
 		var _aurdata []*aur.Pkg
-		_aurdata, err = query.AURInfo(ctx, config.Runtime.AURClient, remoteNames, warnings, config.RequestSplitN)
+		_aurdata, err = query.AURInfo(ctx, config.Runtime.QueryClient,
+			withoutLocalOrigins(remoteNames), warnings, config.RequestSplitN)
 		errs.Add(err)
 
 		if err == nil {
@@ -113,14 +119,27 @@ func upList(ctx context.Context, warnings *query.AURWarnings, dbExecutor db.Exec
 	}
 
 	aurUp = develUp
-	aurUp.Repos = []string{"aur", "devel"}
+	aurUp.Repos = append([]string{"aur", "devel"}, sourceNames()...)
+	labelSourceUpgrades(aurUp.Up, aurdata)
+	aurUp.Up = filterUpdateList(aurUp.Up, isNotPinned)
 
 	repoUp = upgrade.UpSlice{Up: repoSlice, Repos: dbExecutor.Repos()}
 
 	aurUp.Up = filterUpdateList(aurUp.Up, filter)
 	repoUp.Up = filterUpdateList(repoUp.Up, filter)
 
-	return aurUp, repoUp, errs.Return()
+	return aurUp, repoUp, aurdata, errs.Return()
+}
+
+// isNotPinned filters out upgrades of packages pinned to a historical AUR version.
+func isNotPinned(up db.Upgrade) bool {
+	p, ok := config.Runtime.PinStore.Get(up.Name)
+	if ok {
+		text.Warnln(gotext.Get("%s: pinned to %s (%s available), skipping",
+			text.Cyan(up.Name), p.Version, up.RemoteVersion))
+	}
+
+	return !ok
 }
 
 func printLocalNewerThanAUR(
@@ -235,7 +254,7 @@ func sysupgradeTargets(ctx context.Context, dbExecutor db.Executor,
 	enableDowngrade bool) (stringset.StringSet, []string, error) {
 	warnings := query.NewWarnings()
 
-	aurUp, repoUp, err := upList(ctx, warnings, dbExecutor, enableDowngrade,
+	aurUp, repoUp, _, err := upList(ctx, warnings, dbExecutor, enableDowngrade,
 		func(upgrade.Upgrade) bool { return true })
 	if err != nil {
 		return nil, nil, err
@@ -247,3 +266,50 @@ func sysupgradeTargets(ctx context.Context, dbExecutor db.Executor,
 
 	return ignore, targets, errUp
 }
+
+// checkNews shows the unread Arch news before a system upgrade and asks for
+// confirmation. Databases are refreshed first when requested so news
+// mentioning pending repository upgrades can be flagged.
+func checkNews(ctx context.Context, cmdArgs *parser.Arguments, dbExecutor db.Executor) error {
+	if cmdArgs.ExistsArg("y", "refresh") {
+		if err := earlyRefresh(ctx, cmdArgs); err != nil {
+			return fmt.Errorf(gotext.Get("error refreshing databases"))
+		}
+
+		if err := dbExecutor.RefreshHandle(); err != nil {
+			return err
+		}
+	}
+
+	repoUp, err := dbExecutor.RepoUpgrades(cmdArgs.ExistsDouble("u", "sysupgrade"))
+	if err != nil {
+		return err
+	}
+
+	pending := make([]string, 0, len(repoUp))
+	for _, up := range repoUp {
+		pending = append(pending, up.Name)
+	}
+
+	store := news.NewReadStore(config.Runtime.NewsReadPath)
+	if err := store.Load(); err != nil {
+		text.Warnln(err)
+	}
+
+	return news.CheckUnread(ctx, config.Runtime.HTTPClient, config.NewsFeeds, store,
+		lastUpgradeTime(dbExecutor), pending, config.SortMode, config.NewsStop, settings.NoConfirm)
+}
+
+// lastUpgradeTime returns the newest install date of the local packages, news
+// published since then may concern the pending upgrade.
+func lastUpgradeTime(dbExecutor db.Executor) time.Time {
+	var lastTime time.Time
+
+	for _, pkg := range dbExecutor.LocalPackages() {
+		if installed := pkg.InstallDate(); installed.After(lastTime) {
+			lastTime = installed
+		}
+	}
+
+	return lastTime
+}
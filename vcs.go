@@ -9,7 +9,6 @@ import (
 
 	"github.com/Jguer/yay/v11/pkg/db"
 	"github.com/Jguer/yay/v11/pkg/dep"
-	"github.com/Jguer/yay/v11/pkg/download"
 	"github.com/Jguer/yay/v11/pkg/query"
 	"github.com/Jguer/yay/v11/pkg/settings"
 	"github.com/Jguer/yay/v11/pkg/stringset"
@@ -28,7 +27,7 @@ func createDevelDB(ctx context.Context, config *settings.Configuration, dbExecut
 		return err
 	}
 
-	info, err := query.AURInfoPrint(ctx, config.Runtime.AURClient, remoteNames, config.RequestSplitN)
+	info, err := query.AURInfoPrint(ctx, config.Runtime.QueryClient, remoteNames, config.RequestSplitN)
 	if err != nil {
 		return err
 	}
@@ -51,8 +50,7 @@ func createDevelDB(ctx context.Context, config *settings.Configuration, dbExecut
 				len(toSkipSlice), len(bases), text.Cyan(strings.Join(toSkipSlice, ", "))))
 	}
 
-	if _, errA := download.AURPKGBUILDRepos(ctx,
-		config.Runtime.CmdBuilder, targets, config.AURURL, config.BuildDir, false); errA != nil {
+	if _, errA := downloadPKGBUILDRepos(ctx, targets, config.BuildDir, false); errA != nil {
 		return err
 	}
 